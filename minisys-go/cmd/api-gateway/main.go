@@ -1,27 +1,47 @@
 package main
 
 import (
+	"context"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"os"
+	"os/signal"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/auth"
 	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/config"
 	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/discovery"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/health"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/logging"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/metrics"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/ratelimit"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/requestid"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/tracing"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
+// upstreams maps each routed service to its K8s DNS fallback
+var upstreams = map[string]string{
+	"product-service": "http://product-service:8081",
+	"order-service":   "http://order-service:8082",
+}
+
 type Gateway struct {
 	consul   *discovery.ConsulClient
 	proxies  map[string]*httputil.ReverseProxy
 	mutex    sync.RWMutex
 	services map[string]string
+	prober   *health.Prober
 }
 
-func NewGateway(consul *discovery.ConsulClient) *Gateway {
+func NewGateway(ctx context.Context, consul *discovery.ConsulClient) *Gateway {
 	g := &Gateway{
 		consul:   consul,
 		proxies:  make(map[string]*httputil.ReverseProxy),
@@ -31,48 +51,73 @@ func NewGateway(consul *discovery.ConsulClient) *Gateway {
 	g.discoverServices()
 	go g.watchServices()
 
+	g.prober = health.NewProber(g.probeTargets, 5*time.Second, 2*time.Second)
+	g.prober.Start(ctx)
+
 	return g
 }
 
 func (g *Gateway) discoverServices() {
-	services := []string{"product-service", "order-service"}
-
-	for _, svc := range services {
-		url, err := g.consul.GetServiceURL(svc)
-		if err != nil {
-			log.Printf("⚠️ Service %s not found: %v", svc, err)
-			// Use K8s DNS as fallback
-			switch svc {
-			case "product-service":
-				url = "http://product-service:8081"
-			case "order-service":
-				url = "http://order-service:8082"
+	for svc, fallback := range upstreams {
+		url := fallback
+		if g.consul != nil {
+			discovered, err := g.consul.GetServiceURL(svc)
+			if err != nil {
+				// Use K8s DNS as fallback
+				slog.Warn("service not found in Consul, using K8s DNS", "upstream", svc, "error", err)
+			} else {
+				url = discovered
 			}
 		}
 		g.updateProxy(svc, url)
 	}
 }
 
+// probeTargets lists every known instance of every upstream for the prober
+func (g *Gateway) probeTargets() map[string][]health.Target {
+	targets := make(map[string][]health.Target, len(upstreams))
+
+	for svc, fallback := range upstreams {
+		if g.consul != nil {
+			instances, err := g.consul.GetServiceInstances(svc)
+			if err == nil && len(instances) > 0 {
+				for _, instance := range instances {
+					targets[svc] = append(targets[svc], health.Target{
+						InstanceID: instance.ID,
+						URL:        instance.URL(),
+					})
+				}
+				continue
+			}
+		}
+
+		targets[svc] = []health.Target{{InstanceID: svc, URL: fallback}}
+	}
+
+	return targets
+}
+
 func (g *Gateway) updateProxy(serviceName, serviceURL string) {
 	g.mutex.Lock()
 	defer g.mutex.Unlock()
 
 	target, err := url.Parse(serviceURL)
 	if err != nil {
-		log.Printf("❌ Invalid URL for %s: %v", serviceName, err)
+		slog.Error("invalid upstream URL", "upstream", serviceName, "url", serviceURL, "error", err)
 		return
 	}
 
 	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.Transport = metrics.InstrumentTransport(serviceName, otelhttp.NewTransport(http.DefaultTransport))
 	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
-		log.Printf("❌ Proxy error for %s: %v", serviceName, err)
+		slog.ErrorContext(r.Context(), "proxy error", "upstream", serviceName, "error", err)
 		w.WriteHeader(http.StatusBadGateway)
 		io.WriteString(w, `{"error": "service unavailable"}`)
 	}
 
 	g.proxies[serviceName] = proxy
 	g.services[serviceName] = serviceURL
-	log.Printf("✅ Updated route: %s → %s", serviceName, serviceURL)
+	slog.Debug("updated route", "upstream", serviceName, "url", serviceURL)
 }
 
 func (g *Gateway) watchServices() {
@@ -94,7 +139,7 @@ func (g *Gateway) ProxyProducts(c *gin.Context) {
 		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "product-service unavailable"})
 		return
 	}
-	log.Printf("🔀 Routing %s %s → product-service", c.Request.Method, c.Request.URL.Path)
+	slog.DebugContext(c.Request.Context(), "routing request", "upstream", "product-service", "method", c.Request.Method, "path", c.Request.URL.Path)
 	proxy.ServeHTTP(c.Writer, c.Request)
 }
 
@@ -104,41 +149,19 @@ func (g *Gateway) ProxyOrders(c *gin.Context) {
 		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "order-service unavailable"})
 		return
 	}
-	log.Printf("🔀 Routing %s %s → order-service", c.Request.Method, c.Request.URL.Path)
+	slog.DebugContext(c.Request.Context(), "routing request", "upstream", "order-service", "method", c.Request.Method, "path", c.Request.URL.Path)
 	proxy.ServeHTTP(c.Writer, c.Request)
 }
 
+// HealthCheck answers from the prober cache, never probing inline
 func (g *Gateway) HealthCheck(c *gin.Context) {
-	g.mutex.RLock()
-	defer g.mutex.RUnlock()
+	report := g.prober.Snapshot()
 
-	statuses := make(map[string]string)
-	allHealthy := true
-
-	client := &http.Client{Timeout: 2 * time.Second}
-
-	for name, url := range g.services {
-		resp, err := client.Get(url + "/health")
-		if err != nil || resp.StatusCode != http.StatusOK {
-			statuses[name] = "unhealthy"
-			allHealthy = false
-		} else {
-			statuses[name] = "healthy"
-		}
-		if resp != nil {
-			resp.Body.Close()
-		}
-	}
-
-	status := "healthy"
-	if !allHealthy {
-		status = "degraded"
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"status":   status,
-		"service":  "api-gateway",
-		"services": statuses,
+	c.JSON(report.HTTPStatus(), gin.H{
+		"status":     report.Status,
+		"service":    "api-gateway",
+		"checked_at": report.CheckedAt,
+		"services":   report.Services,
 	})
 }
 
@@ -151,23 +174,138 @@ func (g *Gateway) ListServices(c *gin.Context) {
 func main() {
 	cfg := config.Load()
 
+	hostname, _ := os.Hostname()
+	logging.Init("api-gateway", hostname, cfg.LogLevel)
+
+	shutdownTracing, err := tracing.Init(context.Background(), "api-gateway", tracing.Config{
+		Exporter:     cfg.TraceExporter,
+		OTLPEndpoint: cfg.TraceEndpoint,
+		FilePath:     cfg.TraceFile,
+		SampleRatio:  cfg.TraceSampleRatio,
+	})
+	if err != nil {
+		logging.Fatal("failed to initialize tracing", "error", err)
+	}
+
+	// Flush pending spans on shutdown
+	go func() {
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+		<-sigChan
+		slog.Info("shutting down")
+		shutdownTracing(context.Background())
+		os.Exit(0)
+	}()
+
 	consul, err := discovery.NewConsulClient(cfg.ConsulHost, cfg.ConsulPort)
 	if err != nil {
-		log.Printf("⚠️ Failed to connect to Consul, using K8s DNS: %v", err)
+		slog.Warn("failed to connect to Consul, using K8s DNS", "error", err)
 	}
 
-	gateway := NewGateway(consul)
+	gateway := NewGateway(context.Background(), consul)
 
-	router := gin.Default()
+	router := gin.New()
+	// Only believe X-Forwarded-For from our own proxies, otherwise any
+	// client could pick the IP its rate limit counts against
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		logging.Fatal("invalid trusted proxies", "error", err)
+	}
+	router.Use(otelgin.Middleware("api-gateway"), requestid.Middleware(), logging.Middleware(), gin.Recovery(), metrics.Middleware())
 
 	router.GET("/health", gateway.HealthCheck)
 	router.GET("/services", gateway.ListServices)
+	router.GET("/metrics", metrics.Handler())
 
-	router.Any("/products", gateway.ProxyProducts)
-	router.Any("/products/*path", gateway.ProxyProducts)
-	router.Any("/orders", gateway.ProxyOrders)
-	router.Any("/orders/*path", gateway.ProxyOrders)
+	api := router.Group("/")
+	authenticator, err := newAuthenticator(cfg)
+	if err != nil {
+		logging.Fatal("failed to configure authentication", "error", err)
+	}
+
+	// Rate limit proxied traffic only; limits are shared through Redis.
+	// Pre-auth rules count every request per client address, failed logins
+	// included, and the rest run after authentication so they can key on
+	// the verified caller.
+	policy, err := ratelimit.LoadPolicy(cfg.RateLimitConfig)
+	if err != nil {
+		logging.Fatal("failed to load rate limit policy", "error", err)
+	}
+	limiter, err := ratelimit.NewRedisLimiter(cfg.RedisHost, cfg.RedisPort)
+	if err != nil {
+		slog.Warn("rate limiting disabled", "error", err)
+		api.Use(authenticator.Middleware())
+	} else {
+		defer limiter.Close()
+		api.Use(ratelimit.PreAuthMiddleware(limiter, policy), authenticator.Middleware(), ratelimit.Middleware(limiter, policy))
+	}
+
+	api.GET("/admin/log-level", logging.LevelHandler)
+	api.PUT("/admin/log-level", logging.LevelHandler)
+	api.GET("/admin/inventory/drift", gateway.ProxyProducts)
+	api.DELETE("/admin/products/:id", gateway.ProxyProducts)
 
-	log.Println("🚀 API Gateway starting on http://0.0.0.0:8080")
+	api.Any("/products", gateway.ProxyProducts)
+	api.Any("/products/*path", gateway.ProxyProducts)
+	api.Any("/categories", gateway.ProxyProducts)
+	api.Any("/categories/*path", gateway.ProxyProducts)
+	api.Any("/variants", gateway.ProxyProducts)
+	api.Any("/warehouses", gateway.ProxyProducts)
+	api.Any("/warehouses/*path", gateway.ProxyProducts)
+	api.Any("/reservations", gateway.ProxyProducts)
+	api.Any("/inventory/*path", gateway.ProxyProducts)
+	api.Any("/reservations/*path", gateway.ProxyProducts)
+	api.Any("/attributes", gateway.ProxyProducts)
+	api.Any("/attributes/*path", gateway.ProxyProducts)
+	api.Any("/orders", gateway.ProxyOrders)
+	api.Any("/orders/*path", gateway.ProxyOrders)
+
+	slog.Info("api-gateway starting", "addr", ":8080")
 	router.Run(":8080")
 }
+
+// newAuthenticator wires up whichever credential types are configured.
+// With none configured, only public routes are reachable.
+func newAuthenticator(cfg *config.Config) (*auth.Authenticator, error) {
+	policy, err := auth.LoadPolicy(cfg.AuthPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	jwtCfg := auth.JWTConfig{
+		Issuer:   cfg.JWTIssuer,
+		Audience: cfg.JWTAudience,
+	}
+	if cfg.JWTSecret != "" {
+		jwtCfg.HMACSecret = []byte(cfg.JWTSecret)
+	}
+	if cfg.JWKSSource != "" {
+		jwks, err := auth.NewJWKS(cfg.JWKSSource)
+		if err != nil {
+			return nil, err
+		}
+		jwks.Watch(context.Background(), 10*time.Minute)
+		jwtCfg.JWKS = jwks
+	}
+
+	var validator *auth.JWTValidator
+	if jwtCfg.HMACSecret != nil || jwtCfg.JWKS != nil {
+		validator, err = auth.NewJWTValidator(jwtCfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var apiKeys *auth.APIKeyStore
+	if cfg.APIKeysFile != "" {
+		apiKeys, err = auth.LoadAPIKeys(cfg.APIKeysFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if validator == nil && apiKeys == nil {
+		slog.Warn("no JWT keys or API keys configured, only public routes are reachable")
+	}
+
+	return auth.NewAuthenticator(validator, apiKeys, policy), nil
+}
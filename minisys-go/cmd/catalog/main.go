@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/catalog"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/config"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/db"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/logging"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/models"
+)
+
+const usage = `Usage: catalog <command> [flags]
+
+Commands:
+  export [-format csv|ndjson] [-o file]   stream every product that is not
+                                          deleted, to stdout by default
+
+Imports go through POST /products/import so they are validated and
+invalidate the product cache.`
+
+func main() {
+	cfg := config.Load()
+
+	// Logs go to stderr so they never mix with an export on stdout
+	logging.InitWriter(os.Stderr, "catalog", "catalog", cfg.LogLevel)
+
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "export":
+		export(cfg, os.Args[2:])
+
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func export(cfg *config.Config, args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	formatName := flags.String("format", string(catalog.CSV), "csv or ndjson")
+	output := flags.String("o", "", "file to write instead of stdout")
+	flags.Parse(args)
+
+	format, err := catalog.ParseFormat(*formatName)
+	if err != nil {
+		logging.Fatal("invalid format", "error", err)
+	}
+
+	database, err := db.NewPostgresDB(cfg.PostgresHost, cfg.PostgresPort, cfg.PostgresUser, cfg.PostgresPassword, cfg.PostgresDB)
+	if err != nil {
+		logging.Fatal("failed to connect to database", "error", err)
+	}
+	defer database.Close()
+
+	out := os.Stdout
+	if *output != "" {
+		out, err = os.Create(*output)
+		if err != nil {
+			logging.Fatal("failed to create output file", "path", *output, "error", err)
+		}
+		defer out.Close()
+	}
+
+	buffered := bufio.NewWriter(out)
+	writer := catalog.NewWriter(buffered, format)
+	rows := 0
+	err = db.NewProductRepository(database).Export(context.Background(), func(row models.CatalogRow) error {
+		rows++
+		return writer.Write(row)
+	})
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = buffered.Flush()
+	}
+	if err != nil {
+		logging.Fatal("export failed", "rows", rows, "error", err)
+	}
+
+	slog.Info("catalog exported", "format", format, "rows", rows)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/config"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/db"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/logging"
+)
+
+const usage = `Usage: migrate <command>
+
+Commands:
+  up              apply all pending migrations
+  down [steps]    revert the last migration, or the last <steps> migrations
+  status          list migrations and whether they are applied
+  to <version>    migrate up or down to <version> (0 reverts everything)`
+
+func main() {
+	cfg := config.Load()
+	logging.Init("migrate", "migrate", cfg.LogLevel)
+
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	database, err := db.NewPostgresDB(cfg.PostgresHost, cfg.PostgresPort, cfg.PostgresUser, cfg.PostgresPassword, cfg.PostgresDB)
+	if err != nil {
+		logging.Fatal("failed to connect to database", "error", err)
+	}
+	defer database.Close()
+
+	migrator, err := db.NewMigrator(database.Conn)
+	if err != nil {
+		logging.Fatal("failed to load migrations", "error", err)
+	}
+
+	ctx := context.Background()
+
+	switch os.Args[1] {
+	case "up":
+		err = migrator.Up(ctx)
+
+	case "down":
+		steps := 1
+		if len(os.Args) > 2 {
+			steps, err = strconv.Atoi(os.Args[2])
+			if err != nil {
+				logging.Fatal("invalid step count", "steps", os.Args[2])
+			}
+		}
+		err = migrator.Down(ctx, steps)
+
+	case "to":
+		if len(os.Args) < 3 {
+			fmt.Fprintln(os.Stderr, usage)
+			os.Exit(2)
+		}
+		version, convErr := strconv.Atoi(os.Args[2])
+		if convErr != nil {
+			logging.Fatal("invalid version", "version", os.Args[2])
+		}
+		err = migrator.To(ctx, version)
+
+	case "status":
+		var statuses []db.MigrationStatus
+		statuses, err = migrator.Status(ctx)
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05 MST")
+			}
+			fmt.Printf("%04d  %-30s  %s\n", status.Version, status.Name, appliedAt)
+		}
+
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		logging.Fatal("migration failed", "command", os.Args[1], "error", err)
+	}
+}
@@ -1,19 +1,27 @@
 package main
 
 import (
-	"log"
+	"context"
+	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/auth"
 	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/client"
 	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/config"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/consumer"
 	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/db"
 	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/discovery"
 	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/handlers"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/logging"
 	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/messaging"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/metrics"
 	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/publisher"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/requestid"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/tracing"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 const (
@@ -24,26 +32,50 @@ const (
 func main() {
 	// Load configuration
 	cfg := config.Load()
+	logging.Init(serviceName, serviceID, cfg.LogLevel)
 	servicePort := 8082
 
+	// Start tracing
+	shutdownTracing, err := tracing.Init(context.Background(), serviceName, tracing.Config{
+		Exporter:     cfg.TraceExporter,
+		OTLPEndpoint: cfg.TraceEndpoint,
+		FilePath:     cfg.TraceFile,
+		SampleRatio:  cfg.TraceSampleRatio,
+	})
+	if err != nil {
+		logging.Fatal("failed to initialize tracing", "error", err)
+	}
+
 	// Connect to PostgreSQL
 	database, err := db.NewPostgresDB(cfg.PostgresHost, cfg.PostgresPort, cfg.PostgresUser, cfg.PostgresPassword, cfg.PostgresDB)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		logging.Fatal("failed to connect to database", "error", err)
 	}
 	defer database.Close()
+	metrics.RegisterDBStats(database.Conn, cfg.PostgresDB)
+
+	// Apply pending schema migrations
+	if cfg.MigrateOnStart {
+		migrator, err := db.NewMigrator(database.Conn)
+		if err != nil {
+			logging.Fatal("failed to load migrations", "error", err)
+		}
+		if err := migrator.Up(context.Background()); err != nil {
+			logging.Fatal("failed to run migrations", "error", err)
+		}
+	}
 
 	// Connect to RabbitMQ
 	rabbitMQ, err := messaging.NewRabbitMQ(cfg.RabbitMQHost, cfg.RabbitMQPort, cfg.RabbitMQUser, cfg.RabbitMQPassword)
 	if err != nil {
-		log.Fatalf("Failed to connect to RabbitMQ: %v", err)
+		logging.Fatal("failed to connect to RabbitMQ", "error", err)
 	}
 	defer rabbitMQ.Close()
 
 	// Connect to Consul
 	consul, err := discovery.NewConsulClient(cfg.ConsulHost, cfg.ConsulPort)
 	if err != nil {
-		log.Fatalf("Failed to connect to Consul: %v", err)
+		logging.Fatal("failed to connect to Consul", "error", err)
 	}
 
 	// Register with Consul
@@ -54,7 +86,7 @@ func main() {
 		Tags: []string{"api", "orders"},
 	})
 	if err != nil {
-		log.Fatalf("Failed to register service: %v", err)
+		logging.Fatal("failed to register service", "error", err)
 	}
 
 	// Deregister on shutdown
@@ -62,23 +94,24 @@ func main() {
 		sigChan := make(chan os.Signal, 1)
 		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 		<-sigChan
-		log.Println("Shutting down...")
+		slog.Info("shutting down")
 		consul.Deregister(serviceID)
+		shutdownTracing(context.Background())
 		os.Exit(0)
 	}()
 
 	// Discover Product Service from Consul
 	productServiceURL, err := consul.GetServiceURL("product-service")
 	if err != nil {
-		log.Printf("⚠️ Product service not found, using default: %v", err)
+		slog.Warn("product-service not found in Consul, using default", "error", err)
 		productServiceURL = "http://product-service:8081"
 	}
-	log.Printf("📍 Discovered product-service at: %s", productServiceURL)
+	slog.Info("discovered product-service", "url", productServiceURL)
 
 	// Create publisher
 	orderPublisher, err := publisher.NewOrderPublisher(rabbitMQ)
 	if err != nil {
-		log.Fatalf("Failed to create publisher: %v", err)
+		logging.Fatal("failed to create publisher", "error", err)
 	}
 
 	// Create Product Service client
@@ -88,16 +121,47 @@ func main() {
 	orderRepo := db.NewOrderRepository(database)
 	orderHandler := handlers.NewOrderHandler(orderRepo, productClient, orderPublisher)
 
+	// Start event consumer
+	go startAllocationConsumer(rabbitMQ, orderRepo)
+
 	// Setup router
-	router := gin.Default()
+	router := gin.New()
+	router.Use(otelgin.Middleware(serviceName), requestid.Middleware(), logging.Middleware(), gin.Recovery(), metrics.Middleware())
 
 	router.GET("/health", orderHandler.HealthCheck)
-	router.GET("/orders", orderHandler.ListOrders)
-	router.GET("/orders/:id", orderHandler.GetOrder)
-	router.POST("/orders", orderHandler.CreateOrder)
-	router.PATCH("/orders/:id/status", orderHandler.UpdateOrderStatus)
+	router.GET("/metrics", metrics.Handler())
+	router.GET("/admin/log-level", logging.LevelHandler)
+	router.PUT("/admin/log-level", auth.ReadIdentity(), auth.RequireRole(auth.RoleAdmin), logging.LevelHandler)
+
+	// Every order route needs a caller; ownership is checked in the handlers
+	orders := router.Group("/orders", auth.ReadIdentity(), auth.RequireRole(auth.RoleAdmin, auth.RoleStaff, auth.RoleCustomer))
+	orders.GET("", orderHandler.ListOrders)
+	orders.GET("/:id", orderHandler.GetOrder)
+	orders.POST("", orderHandler.CreateOrder)
+	orders.PATCH("/:id/status", orderHandler.UpdateOrderStatus)
 
 	// Start server
-	log.Printf("🚀 %s starting on http://0.0.0.0:%d", serviceName, servicePort)
+	slog.Info("service starting", "port", servicePort)
 	router.Run(":8082")
 }
+
+func startAllocationConsumer(mq *messaging.RabbitMQ, repo *db.OrderRepository) {
+	for _, queue := range []string{publisher.InventoryAllocatedQueue, publisher.OrderAllocationFailedQueue} {
+		if err := mq.DeclareQueue(queue); err != nil {
+			logging.Fatal("failed to declare queue", "queue", queue, "error", err)
+		}
+	}
+
+	allocated, err := mq.Consume(publisher.InventoryAllocatedQueue)
+	if err != nil {
+		logging.Fatal("failed to consume messages", "error", err)
+	}
+	failed, err := mq.Consume(publisher.OrderAllocationFailedQueue)
+	if err != nil {
+		logging.Fatal("failed to consume messages", "error", err)
+	}
+
+	allocationConsumer := consumer.NewAllocationConsumer(repo)
+	go allocationConsumer.ProcessAllocationFailed(failed)
+	allocationConsumer.ProcessInventoryAllocated(allocated)
+}
@@ -1,21 +1,32 @@
 package main
 
 import (
-	"log"
+	"context"
+	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/allocation"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/auth"
 	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/cache"
 	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/config"
 	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/consumer"
 	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/db"
 	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/discovery"
 	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/handlers"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/inventory"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/logging"
 	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/messaging"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/metrics"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/pricing"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/publisher"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/requestid"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/tracing"
 )
 
 const (
@@ -26,33 +37,62 @@ const (
 func main() {
 	// Load configuration
 	cfg := config.Load()
+	logging.Init(serviceName, serviceID, cfg.LogLevel)
 	servicePort := 8081
 
+	// Start tracing
+	shutdownTracing, err := tracing.Init(context.Background(), serviceName, tracing.Config{
+		Exporter:     cfg.TraceExporter,
+		OTLPEndpoint: cfg.TraceEndpoint,
+		FilePath:     cfg.TraceFile,
+		SampleRatio:  cfg.TraceSampleRatio,
+	})
+	if err != nil {
+		logging.Fatal("failed to initialize tracing", "error", err)
+	}
+
 	// Connect to PostgreSQL
 	database, err := db.NewPostgresDB(cfg.PostgresHost, cfg.PostgresPort, cfg.PostgresUser, cfg.PostgresPassword, cfg.PostgresDB)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		logging.Fatal("failed to connect to database", "error", err)
 	}
 	defer database.Close()
+	metrics.RegisterDBStats(database.Conn, cfg.PostgresDB)
+
+	// Apply pending schema migrations
+	if cfg.MigrateOnStart {
+		migrator, err := db.NewMigrator(database.Conn)
+		if err != nil {
+			logging.Fatal("failed to load migrations", "error", err)
+		}
+		if err := migrator.Up(context.Background()); err != nil {
+			logging.Fatal("failed to run migrations", "error", err)
+		}
+	}
 
 	// Connect to Redis
 	redisCache, err := cache.NewRedisCache(cfg.RedisHost, cfg.RedisPort, 5*time.Minute)
 	if err != nil {
-		log.Fatalf("Failed to connect to Redis: %v", err)
+		logging.Fatal("failed to connect to Redis", "error", err)
 	}
 	defer redisCache.Close()
 
+	// Serve hot keys from memory; deletions reach every replica over pub/sub
+	if cfg.LocalCacheSize > 0 {
+		redisCache.UseLocal(context.Background(), cache.NewLocalCache(cfg.LocalCacheSize, cfg.LocalCacheTTL))
+	}
+
 	// Connect to RabbitMQ
 	rabbitMQ, err := messaging.NewRabbitMQ(cfg.RabbitMQHost, cfg.RabbitMQPort, cfg.RabbitMQUser, cfg.RabbitMQPassword)
 	if err != nil {
-		log.Fatalf("Failed to connect to RabbitMQ: %v", err)
+		logging.Fatal("failed to connect to RabbitMQ", "error", err)
 	}
 	defer rabbitMQ.Close()
 
 	// Connect to Consul
 	consul, err := discovery.NewConsulClient(cfg.ConsulHost, cfg.ConsulPort)
 	if err != nil {
-		log.Fatalf("Failed to connect to Consul: %v", err)
+		logging.Fatal("failed to connect to Consul", "error", err)
 	}
 
 	// Register with Consul
@@ -63,7 +103,7 @@ func main() {
 		Tags: []string{"api", "products"},
 	})
 	if err != nil {
-		log.Fatalf("Failed to register service: %v", err)
+		logging.Fatal("failed to register service", "error", err)
 	}
 
 	// Deregister on shutdown
@@ -71,45 +111,154 @@ func main() {
 		sigChan := make(chan os.Signal, 1)
 		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 		<-sigChan
-		log.Println("Shutting down...")
+		slog.Info("shutting down")
 		consul.Deregister(serviceID)
+		shutdownTracing(context.Background())
 		os.Exit(0)
 	}()
 
 	// Create repositories
 	productRepo := db.NewProductRepository(database)
 	cachedRepo := db.NewCachedProductRepository(productRepo, redisCache)
+	categoryRepo := db.NewCategoryRepository(database)
+	attributeRepo := db.NewAttributeRepository(database)
+	variantRepo := db.NewVariantRepository(database)
+	warehouseRepo := db.NewWarehouseRepository(database)
+	inventoryRepo := db.NewInventoryRepository(database)
+	reservationRepo := db.NewReservationRepository(database)
+	orderRepo := db.NewOrderRepository(database)
+	alertRepo := db.NewAlertRepository(database)
+	priceRepo := db.NewPriceRepository(database)
+
+	// Create publisher
+	productPublisher, err := publisher.NewProductPublisher(rabbitMQ)
+	if err != nil {
+		logging.Fatal("failed to create publisher", "error", err)
+	}
+	inventoryPublisher, err := publisher.NewInventoryPublisher(rabbitMQ)
+	if err != nil {
+		logging.Fatal("failed to create publisher", "error", err)
+	}
+
+	strategy, err := allocation.ParseStrategy(cfg.AllocationStrategy)
+	if err != nil {
+		logging.Fatal("invalid allocation strategy", "error", err)
+	}
 
 	// Create handler
-	productHandler := handlers.NewProductHandler(cachedRepo)
+	productHandler := handlers.NewProductHandler(cachedRepo, attributeRepo, productPublisher)
+	categoryHandler := handlers.NewCategoryHandler(categoryRepo, cachedRepo)
+	attributeHandler := handlers.NewAttributeHandler(attributeRepo)
+	variantHandler := handlers.NewVariantHandler(variantRepo, db.NewCachedVariantRepository(variantRepo, redisCache), cachedRepo)
+	warehouseHandler := handlers.NewWarehouseHandler(warehouseRepo, cachedRepo)
+	priceHandler := handlers.NewPriceHandler(priceRepo, cachedRepo)
+
+	// Check the stock levels against the inventory ledger
+	reconciler := inventory.NewReconciler(inventoryRepo, cfg.ReconcileInterval)
+	reconciler.Start(context.Background())
+	inventoryHandler := handlers.NewInventoryHandler(inventoryRepo, warehouseRepo, cachedRepo, reconciler)
+	reservationHandler := handlers.NewReservationHandler(reservationRepo, cachedRepo, orderRepo, cfg.ReservationTTL, cfg.MaxReservations, strategy)
+
+	alertHandler := handlers.NewAlertHandler(alertRepo)
+
+	// Give back stock held by abandoned checkouts
+	inventory.NewSweeper(reservationRepo, inventoryPublisher, redisCache, cfg.SweepInterval).Start(context.Background())
+
+	// Announce products running low
+	inventory.NewAlerter(alertRepo, inventoryPublisher, cfg.AlertInterval).Start(context.Background())
+
+	// Apply scheduled prices and announce price changes
+	pricing.NewScheduler(priceRepo, productPublisher, redisCache, cfg.PriceInterval).Start(context.Background())
+
+	// Tell order-service where allocated orders ship from
+	allocationRelay := inventory.NewAllocationRelay(warehouseRepo, inventoryPublisher, cfg.AllocationInterval)
+	allocationRelay.Start(context.Background())
 
 	// Start event consumer
-	go startEventConsumer(rabbitMQ, productRepo, redisCache)
+	go startEventConsumer(rabbitMQ, consumer.NewInventoryConsumer(warehouseRepo, reservationRepo, inventoryPublisher, allocationRelay, strategy, redisCache))
 
 	// Setup router
-	router := gin.Default()
+	router := gin.New()
+	router.Use(otelgin.Middleware(serviceName), requestid.Middleware(), logging.Middleware(), gin.Recovery(), metrics.Middleware())
+	router.Use(auth.ReadIdentity())
 
 	router.GET("/health", productHandler.HealthCheck)
+	router.GET("/metrics", metrics.Handler())
+	router.GET("/admin/log-level", logging.LevelHandler)
+	router.PUT("/admin/log-level", auth.RequireRole(auth.RoleAdmin), logging.LevelHandler)
+	router.GET("/admin/inventory/drift", auth.RequireRole(auth.RoleAdmin), inventoryHandler.GetDrift)
+	router.DELETE("/admin/products/:id", auth.RequireRole(auth.RoleAdmin), productHandler.PurgeProduct)
 	router.GET("/products", productHandler.ListProducts)
+	router.GET("/products/search", productHandler.SearchProducts)
+	router.GET("/products/export", auth.RequireRole(auth.RoleAdmin, auth.RoleStaff), productHandler.ExportProducts)
+	router.POST("/products/import", auth.RequireRole(auth.RoleAdmin, auth.RoleStaff), productHandler.ImportProducts)
 	router.GET("/products/:id", productHandler.GetProduct)
-	router.POST("/products", productHandler.CreateProduct)
-	router.DELETE("/products/:id", productHandler.DeleteProduct)
+	router.POST("/products", auth.RequireRole(auth.RoleAdmin, auth.RoleStaff), productHandler.CreateProduct)
+	router.PUT("/products/:id", auth.RequireRole(auth.RoleAdmin, auth.RoleStaff), productHandler.UpdateProduct)
+	router.PATCH("/products/:id", auth.RequireRole(auth.RoleAdmin, auth.RoleStaff), productHandler.PatchProduct)
+	router.DELETE("/products/:id", auth.RequireRole(auth.RoleAdmin), productHandler.DeleteProduct)
+	router.POST("/products/:id/restore", auth.RequireRole(auth.RoleAdmin), productHandler.RestoreProduct)
+	router.GET("/products/:id/prices", auth.RequireRole(auth.RoleAdmin, auth.RoleStaff), priceHandler.ListPrices)
+	router.POST("/products/:id/prices", auth.RequireRole(auth.RoleAdmin, auth.RoleStaff), priceHandler.SchedulePrice)
+
+	router.GET("/variants", variantHandler.LookupVariants)
+	router.GET("/products/:id/variants", variantHandler.ListVariants)
+	router.POST("/products/:id/variants", auth.RequireRole(auth.RoleAdmin, auth.RoleStaff), variantHandler.CreateVariant)
+	router.PATCH("/products/:id/variants/:variantId", auth.RequireRole(auth.RoleAdmin, auth.RoleStaff), variantHandler.UpdateVariant)
+	router.DELETE("/products/:id/variants/:variantId", auth.RequireRole(auth.RoleAdmin), variantHandler.DeleteVariant)
+	router.GET("/products/:id/stock", warehouseHandler.GetProductStock)
+	router.GET("/products/:id/inventory/history", auth.RequireRole(auth.RoleAdmin, auth.RoleStaff), inventoryHandler.GetHistory)
+	router.POST("/products/:id/inventory/adjustments", auth.RequireRole(auth.RoleAdmin, auth.RoleStaff), inventoryHandler.AdjustStock)
+	router.GET("/products/:id/reorder-policy", auth.RequireRole(auth.RoleAdmin, auth.RoleStaff), alertHandler.GetReorderPolicy)
+	router.PUT("/products/:id/reorder-policy", auth.RequireRole(auth.RoleAdmin, auth.RoleStaff), alertHandler.SetReorderPolicy)
+	router.DELETE("/products/:id/reorder-policy", auth.RequireRole(auth.RoleAdmin, auth.RoleStaff), alertHandler.DeleteReorderPolicy)
+	router.GET("/inventory/alerts", auth.RequireRole(auth.RoleAdmin, auth.RoleStaff), alertHandler.ListAlerts)
+
+	router.GET("/warehouses", warehouseHandler.ListWarehouses)
+	router.GET("/warehouses/:id", warehouseHandler.GetWarehouse)
+	router.POST("/warehouses", auth.RequireRole(auth.RoleAdmin), warehouseHandler.CreateWarehouse)
+	router.PATCH("/warehouses/:id", auth.RequireRole(auth.RoleAdmin), warehouseHandler.UpdateWarehouse)
+	router.GET("/warehouses/:id/stock", warehouseHandler.ListWarehouseStock)
+	router.PUT("/warehouses/:id/stock/:variantId", auth.RequireRole(auth.RoleAdmin, auth.RoleStaff), warehouseHandler.SetWarehouseStock)
+
+	shopper := auth.RequireRole(auth.RoleAdmin, auth.RoleStaff, auth.RoleCustomer)
+	router.POST("/reservations", shopper, reservationHandler.CreateReservation)
+	router.GET("/reservations/:id", shopper, reservationHandler.GetReservation)
+	router.POST("/reservations/:id/release", shopper, reservationHandler.ReleaseReservation)
+	router.POST("/reservations/:id/confirm", auth.RequireRole(auth.RoleAdmin, auth.RoleStaff), reservationHandler.ConfirmReservation)
+
+	router.GET("/categories", categoryHandler.ListCategories)
+	router.GET("/categories/:id", categoryHandler.GetCategory)
+	router.POST("/categories", auth.RequireRole(auth.RoleAdmin, auth.RoleStaff), categoryHandler.CreateCategory)
+	router.PATCH("/categories/:id", auth.RequireRole(auth.RoleAdmin, auth.RoleStaff), categoryHandler.UpdateCategory)
+	router.DELETE("/categories/:id", auth.RequireRole(auth.RoleAdmin), categoryHandler.DeleteCategory)
+
+	router.GET("/attributes", attributeHandler.ListAttributes)
+	router.PUT("/attributes/:name", auth.RequireRole(auth.RoleAdmin), attributeHandler.PutAttribute)
+	router.DELETE("/attributes/:name", auth.RequireRole(auth.RoleAdmin), attributeHandler.DeleteAttribute)
 
 	// Start server
-	log.Printf("🚀 %s starting on http://0.0.0.0:%d", serviceName, servicePort)
+	slog.Info("service starting", "port", servicePort)
 	router.Run(":8081")
 }
 
-func startEventConsumer(mq *messaging.RabbitMQ, repo *db.ProductRepository, cache *cache.RedisCache) {
-	if err := mq.DeclareQueue("order.created"); err != nil {
-		log.Fatalf("Failed to declare queue: %v", err)
+func startEventConsumer(mq *messaging.RabbitMQ, inventoryConsumer *consumer.InventoryConsumer) {
+	for _, queue := range []string{"order.created", "order.cancelled"} {
+		if err := mq.DeclareQueue(queue); err != nil {
+			logging.Fatal("failed to declare queue", "queue", queue, "error", err)
+		}
+	}
+
+	cancelled, err := mq.Consume("order.cancelled")
+	if err != nil {
+		logging.Fatal("failed to consume messages", "error", err)
 	}
+	go inventoryConsumer.ProcessOrderCancelled(cancelled)
 
 	messages, err := mq.Consume("order.created")
 	if err != nil {
-		log.Fatalf("Failed to consume messages: %v", err)
+		logging.Fatal("failed to consume messages", "error", err)
 	}
 
-	inventoryConsumer := consumer.NewInventoryConsumer(repo, cache)
 	inventoryConsumer.ProcessOrderCreated(messages)
 }
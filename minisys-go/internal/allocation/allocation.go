@@ -0,0 +1,139 @@
+package allocation
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/models"
+)
+
+type Strategy string
+
+const (
+	// Nearest ships from the closest warehouse that can fill the whole item
+	Nearest Strategy = "nearest"
+	// MostStock ships from the warehouse holding the most of the item
+	MostStock Strategy = "most_stock"
+	// Split fills the item from several warehouses, nearest first
+	Split Strategy = "split"
+)
+
+// DefaultStrategy is used when neither the order nor the config names one
+const DefaultStrategy = Nearest
+
+// ErrUnfulfillable is returned when the warehouses cannot supply an item
+// under the chosen strategy
+var ErrUnfulfillable = errors.New("insufficient stock to allocate item")
+
+// ParseStrategy validates a strategy name; "" yields DefaultStrategy
+func ParseStrategy(name string) (Strategy, error) {
+	switch s := Strategy(name); s {
+	case "":
+		return DefaultStrategy, nil
+	case Nearest, MostStock, Split:
+		return s, nil
+	default:
+		return "", fmt.Errorf("unknown allocation strategy %q (want nearest, most_stock or split)", name)
+	}
+}
+
+// Candidate is a warehouse holding some of the item being allocated
+type Candidate struct {
+	WarehouseID int
+	Code        string
+	Location    *models.Location
+	Priority    int
+	Available   int
+}
+
+// Pick is the quantity one warehouse ships
+type Pick struct {
+	WarehouseID int
+	Code        string
+	Quantity    int
+}
+
+// Allocate picks warehouses for quantity units under strategy. Without a
+// ship-to location, or for warehouses without one, distance is unknown and
+// warehouse priority (higher first) decides instead.
+func Allocate(strategy Strategy, quantity int, candidates []Candidate, shipTo *models.Location) ([]Pick, error) {
+	ranked := make([]Candidate, 0, len(candidates))
+	for _, c := range candidates {
+		if c.Available > 0 {
+			ranked = append(ranked, c)
+		}
+	}
+
+	if strategy == MostStock {
+		sort.SliceStable(ranked, func(i, j int) bool {
+			if ranked[i].Available != ranked[j].Available {
+				return ranked[i].Available > ranked[j].Available
+			}
+			return closer(ranked[i], ranked[j], shipTo)
+		})
+	} else {
+		sort.SliceStable(ranked, func(i, j int) bool {
+			return closer(ranked[i], ranked[j], shipTo)
+		})
+	}
+
+	if strategy != Split {
+		for _, c := range ranked {
+			if c.Available >= quantity {
+				return []Pick{{WarehouseID: c.WarehouseID, Code: c.Code, Quantity: quantity}}, nil
+			}
+		}
+		return nil, ErrUnfulfillable
+	}
+
+	var picks []Pick
+	remaining := quantity
+	for _, c := range ranked {
+		if remaining == 0 {
+			break
+		}
+		take := min(c.Available, remaining)
+		picks = append(picks, Pick{WarehouseID: c.WarehouseID, Code: c.Code, Quantity: take})
+		remaining -= take
+	}
+	if remaining > 0 {
+		return nil, ErrUnfulfillable
+	}
+
+	return picks, nil
+}
+
+// closer orders warehouses by distance to shipTo, then by priority, then
+// by ID so the result is stable
+func closer(a, b Candidate, shipTo *models.Location) bool {
+	da, db := distance(a, shipTo), distance(b, shipTo)
+	if da != db {
+		return da < db
+	}
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	return a.WarehouseID < b.WarehouseID
+}
+
+// distance is the great-circle distance in kilometres, or +Inf when
+// either end has no location
+func distance(c Candidate, shipTo *models.Location) float64 {
+	if c.Location == nil || shipTo == nil {
+		return math.Inf(1)
+	}
+	return haversineKm(*c.Location, *shipTo)
+}
+
+const earthRadiusKm = 6371.0
+
+func haversineKm(a, b models.Location) float64 {
+	lat1, lat2 := a.Latitude*math.Pi/180, b.Latitude*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (b.Longitude - a.Longitude) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
@@ -0,0 +1,211 @@
+package allocation
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/models"
+)
+
+var (
+	newYork = &models.Location{Latitude: 40.71, Longitude: -74.01}
+	chicago = &models.Location{Latitude: 41.88, Longitude: -87.63}
+	seattle = &models.Location{Latitude: 47.61, Longitude: -122.33}
+	boston  = &models.Location{Latitude: 42.36, Longitude: -71.06}
+)
+
+func TestParseStrategy(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Strategy
+		wantErr bool
+	}{
+		{"", DefaultStrategy, false},
+		{"nearest", Nearest, false},
+		{"most_stock", MostStock, false},
+		{"split", Split, false},
+		{"Nearest", "", true},
+		{"closest", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseStrategy(tt.name)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseStrategy(%q) = %q, %v; want %q, error %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	warehouses := []Candidate{
+		{WarehouseID: 1, Code: "EWR", Location: newYork, Priority: 1, Available: 5},
+		{WarehouseID: 2, Code: "ORD", Location: chicago, Priority: 2, Available: 20},
+		{WarehouseID: 3, Code: "SEA", Location: seattle, Priority: 3, Available: 8},
+	}
+	unlocated := []Candidate{
+		{WarehouseID: 1, Code: "LOW", Priority: 1, Available: 10},
+		{WarehouseID: 2, Code: "HIGH", Priority: 5, Available: 10},
+		{WarehouseID: 3, Code: "MID", Priority: 3, Available: 10},
+	}
+
+	tests := []struct {
+		name       string
+		strategy   Strategy
+		quantity   int
+		candidates []Candidate
+		shipTo     *models.Location
+		want       []Pick
+		wantErr    error
+	}{
+		{
+			name:       "nearest fills from the closest warehouse",
+			strategy:   Nearest,
+			quantity:   3,
+			candidates: warehouses,
+			shipTo:     boston,
+			want:       []Pick{{1, "EWR", 3}},
+		},
+		{
+			name:       "nearest skips a close warehouse that cannot fill the item",
+			strategy:   Nearest,
+			quantity:   10,
+			candidates: warehouses,
+			shipTo:     boston,
+			want:       []Pick{{2, "ORD", 10}},
+		},
+		{
+			name:       "nearest without ship-to falls back to priority",
+			strategy:   Nearest,
+			quantity:   3,
+			candidates: warehouses,
+			want:       []Pick{{3, "SEA", 3}},
+		},
+		{
+			name:       "priority breaks ties between unlocated warehouses",
+			strategy:   Nearest,
+			quantity:   10,
+			candidates: unlocated,
+			shipTo:     boston,
+			want:       []Pick{{2, "HIGH", 10}},
+		},
+		{
+			name:       "nearest fails when no single warehouse has enough",
+			strategy:   Nearest,
+			quantity:   25,
+			candidates: warehouses,
+			shipTo:     boston,
+			wantErr:    ErrUnfulfillable,
+		},
+		{
+			name:       "most stock ignores distance",
+			strategy:   MostStock,
+			quantity:   3,
+			candidates: warehouses,
+			shipTo:     seattle,
+			want:       []Pick{{2, "ORD", 3}},
+		},
+		{
+			name:     "most stock breaks ties by distance",
+			strategy: MostStock,
+			quantity: 3,
+			candidates: []Candidate{
+				{WarehouseID: 1, Code: "SEA", Location: seattle, Available: 9},
+				{WarehouseID: 2, Code: "EWR", Location: newYork, Available: 9},
+			},
+			shipTo: boston,
+			want:   []Pick{{2, "EWR", 3}},
+		},
+		{
+			name:       "split takes nearest first",
+			strategy:   Split,
+			quantity:   30,
+			candidates: warehouses,
+			shipTo:     boston,
+			want:       []Pick{{1, "EWR", 5}, {2, "ORD", 20}, {3, "SEA", 5}},
+		},
+		{
+			name:       "split stops once the item is filled",
+			strategy:   Split,
+			quantity:   4,
+			candidates: warehouses,
+			shipTo:     boston,
+			want:       []Pick{{1, "EWR", 4}},
+		},
+		{
+			name:       "split fails when all stock together is short",
+			strategy:   Split,
+			quantity:   34,
+			candidates: warehouses,
+			shipTo:     boston,
+			wantErr:    ErrUnfulfillable,
+		},
+		{
+			name:     "empty warehouses are ignored",
+			strategy: Split,
+			quantity: 2,
+			candidates: []Candidate{
+				{WarehouseID: 1, Code: "EWR", Location: newYork, Available: 0},
+				{WarehouseID: 2, Code: "ORD", Location: chicago, Available: 2},
+			},
+			shipTo: boston,
+			want:   []Pick{{2, "ORD", 2}},
+		},
+		{
+			name:     "no candidates",
+			strategy: Nearest,
+			quantity: 1,
+			wantErr:  ErrUnfulfillable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Allocate(tt.strategy, tt.quantity, tt.candidates, tt.shipTo)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("picks = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllocateLeavesCandidatesAlone(t *testing.T) {
+	candidates := []Candidate{
+		{WarehouseID: 2, Code: "ORD", Location: chicago, Available: 20},
+		{WarehouseID: 1, Code: "EWR", Location: newYork, Available: 5},
+	}
+	before := slices.Clone(candidates)
+
+	if _, err := Allocate(Split, 10, candidates, boston); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(candidates, before) {
+		t.Errorf("candidates reordered to %v", candidates)
+	}
+}
+
+func TestHaversineKm(t *testing.T) {
+	tests := []struct {
+		a, b *models.Location
+		want float64
+	}{
+		{newYork, newYork, 0},
+		{newYork, boston, 306},
+		{newYork, chicago, 1145},
+		{chicago, seattle, 2792},
+	}
+
+	for _, tt := range tests {
+		if got := haversineKm(*tt.a, *tt.b); math.Abs(got-tt.want) > 5 {
+			t.Errorf("haversineKm(%v, %v) = %.0f, want about %.0f", *tt.a, *tt.b, got, tt.want)
+		}
+	}
+
+	if d := distance(Candidate{}, boston); !math.IsInf(d, 1) {
+		t.Errorf("distance without a location = %v, want +Inf", d)
+	}
+}
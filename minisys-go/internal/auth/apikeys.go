@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+type apiKeyEntry struct {
+	Key       string   `json:"key"`
+	KeySHA256 string   `json:"key_sha256"`
+	Subject   string   `json:"subject"`
+	Roles     []string `json:"roles"`
+	Scopes    []string `json:"scopes"`
+}
+
+// APIKeyStore resolves API keys to identities. Keys are held only as
+// SHA-256 digests; the file may list either the plain key or its digest.
+type APIKeyStore struct {
+	keys map[string]*Identity
+}
+
+// LoadAPIKeys reads a JSON file of the form {"keys": [{"key": "...", ...}]}
+func LoadAPIKeys(path string) (*APIKeyStore, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API keys: %w", err)
+	}
+
+	var file struct {
+		Keys []apiKeyEntry `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse API keys: %w", err)
+	}
+
+	store := &APIKeyStore{keys: make(map[string]*Identity, len(file.Keys))}
+	for _, entry := range file.Keys {
+		digest := strings.ToLower(entry.KeySHA256)
+		if digest == "" {
+			if entry.Key == "" {
+				return nil, fmt.Errorf("API key for %q has neither key nor key_sha256", entry.Subject)
+			}
+			digest = hashKey(entry.Key)
+		}
+		if len(digest) != sha256.Size*2 {
+			return nil, fmt.Errorf("API key for %q has an invalid key_sha256", entry.Subject)
+		}
+		if entry.Subject == "" {
+			return nil, fmt.Errorf("API key %s… has no subject", digest[:8])
+		}
+
+		store.keys[digest] = &Identity{
+			Subject: entry.Subject,
+			Roles:   entry.Roles,
+			Scopes:  entry.Scopes,
+			Method:  MethodAPIKey,
+		}
+	}
+
+	return store, nil
+}
+
+// Lookup returns the identity for key, or nil if the key is unknown
+func (s *APIKeyStore) Lookup(key string) *Identity {
+	identity, ok := s.keys[hashKey(key)]
+	if !ok {
+		return nil
+	}
+
+	copied := *identity
+	return &copied
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeAPIKeys(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "api-keys.json")
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadAPIKeys(t *testing.T) {
+	path := writeAPIKeys(t, `{"keys": [
+		{"key": "plain-secret", "subject": "billing", "roles": ["service"], "scopes": ["orders:read"]},
+		{"key_sha256": "`+strings.ToUpper(hashKey("hashed-secret"))+`", "subject": "search", "scopes": ["products:write"]}
+	]}`)
+
+	store, err := LoadAPIKeys(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key         string
+		wantSubject string
+		wantScopes  []string
+	}{
+		{"plain-secret", "billing", []string{"orders:read"}},
+		{"hashed-secret", "search", []string{"products:write"}},
+		{"Plain-secret", "", nil},
+		{"", "", nil},
+		{hashKey("hashed-secret"), "", nil}, // the digest itself is not a key
+	}
+	for _, tt := range tests {
+		identity := store.Lookup(tt.key)
+		if tt.wantSubject == "" {
+			if identity != nil {
+				t.Errorf("Lookup(%q) = %+v, want nil", tt.key, identity)
+			}
+			continue
+		}
+		if identity == nil {
+			t.Fatalf("Lookup(%q) = nil, want %s", tt.key, tt.wantSubject)
+		}
+		if identity.Subject != tt.wantSubject || identity.Method != MethodAPIKey || !slices.Equal(identity.Scopes, tt.wantScopes) {
+			t.Errorf("Lookup(%q) = %+v, want subject %s scopes %v", tt.key, identity, tt.wantSubject, tt.wantScopes)
+		}
+	}
+
+	// Callers get a copy they cannot use to rewrite the store
+	store.Lookup("plain-secret").Subject = "admin"
+	if got := store.Lookup("plain-secret").Subject; got != "billing" {
+		t.Errorf("subject after mutating a lookup = %s, want billing", got)
+	}
+}
+
+func TestLoadAPIKeysRejectsBadEntries(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{"not json", `{"keys": [`, "failed to parse"},
+		{"no key", `{"keys": [{"subject": "billing"}]}`, "neither key nor key_sha256"},
+		{"short digest", `{"keys": [{"key_sha256": "abc", "subject": "billing"}]}`, "invalid key_sha256"},
+		{"no subject", `{"keys": [{"key": "secret"}]}`, "no subject"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadAPIKeys(writeAPIKeys(t, tt.body))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadAPIKeys error = %v, want one mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := LoadAPIKeys(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("LoadAPIKeys accepted a missing file")
+	}
+}
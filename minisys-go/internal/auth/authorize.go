@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Roles understood by the services
+const (
+	RoleAdmin    = "admin"
+	RoleStaff    = "staff"
+	RoleCustomer = "customer"
+)
+
+// HasAnyRole reports whether the identity holds at least one of roles
+func (i *Identity) HasAnyRole(roles ...string) bool {
+	for _, role := range roles {
+		if i.HasRole(role) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPrivileged reports whether the identity may act on any customer's data
+func (i *Identity) IsPrivileged() bool {
+	return i.HasAnyRole(RoleAdmin, RoleStaff)
+}
+
+// IdentityFromHeaders reads the identity propagated by the gateway,
+// returning nil if the request carries none
+func IdentityFromHeaders(h http.Header) *Identity {
+	subject := h.Get(HeaderUserID)
+	if subject == "" {
+		return nil
+	}
+
+	return &Identity{
+		Subject: subject,
+		Roles:   splitList(h.Get(HeaderUserRoles), ","),
+		Scopes:  splitList(h.Get(HeaderUserScopes), " "),
+		Method:  h.Get(HeaderAuthMethod),
+	}
+}
+
+// ReadIdentity makes the propagated identity available through FromContext.
+// Services must only be reachable through the gateway for this to be safe.
+func ReadIdentity() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if identity := IdentityFromHeaders(c.Request.Header); identity != nil {
+			c.Set(identityContextKey, identity)
+		}
+		c.Next()
+	}
+}
+
+// RequireRole rejects requests whose identity holds none of roles
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identity := FromContext(c)
+		if identity == nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+			return
+		}
+
+		if !identity.HasAnyRole(roles...) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "insufficient role", "required": roles})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func splitList(value, sep string) []string {
+	var out []string
+	for _, part := range strings.Split(value, sep) {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
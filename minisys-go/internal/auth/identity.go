@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Headers the gateway sets on proxied requests once a caller is verified.
+// Upstream services trust these, so the gateway always strips any
+// client-supplied copies first.
+const (
+	HeaderUserID     = "X-User-ID"
+	HeaderUserRoles  = "X-User-Roles"
+	HeaderUserScopes = "X-User-Scopes"
+	HeaderAuthMethod = "X-Auth-Method"
+)
+
+const (
+	MethodJWT    = "jwt"
+	MethodAPIKey = "api_key"
+)
+
+// Identity is a verified caller
+type Identity struct {
+	Subject string   `json:"subject"`
+	Roles   []string `json:"roles"`
+	Scopes  []string `json:"scopes"`
+	Method  string   `json:"method"`
+}
+
+func (i *Identity) HasScope(scope string) bool {
+	return contains(i.Scopes, scope)
+}
+
+func (i *Identity) HasRole(role string) bool {
+	return contains(i.Roles, role)
+}
+
+// SetHeaders writes the identity onto an outgoing request
+func (i *Identity) SetHeaders(h http.Header) {
+	h.Set(HeaderUserID, i.Subject)
+	h.Set(HeaderUserRoles, strings.Join(i.Roles, ","))
+	h.Set(HeaderUserScopes, strings.Join(i.Scopes, " "))
+	h.Set(HeaderAuthMethod, i.Method)
+}
+
+// StripHeaders removes identity headers so callers cannot spoof them
+func StripHeaders(h http.Header) {
+	h.Del(HeaderUserID)
+	h.Del(HeaderUserRoles)
+	h.Del(HeaderUserScopes)
+	h.Del(HeaderAuthMethod)
+}
+
+func contains(values []string, want string) bool {
+	for _, v := range values {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
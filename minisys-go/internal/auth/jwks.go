@@ -0,0 +1,188 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// jwksRefetchInterval bounds how often an unknown kid can trigger a refetch
+const jwksRefetchInterval = time.Minute
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWKS holds RSA verification keys loaded from a local file or a URL.
+// URL-backed sets are refreshed periodically and when a token
+// references a kid we have not seen yet.
+type JWKS struct {
+	source     string
+	httpClient *http.Client
+
+	mutex     sync.RWMutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+func NewJWKS(source string) (*JWKS, error) {
+	j := &JWKS{
+		source:     source,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		keys:       make(map[string]*rsa.PublicKey),
+	}
+
+	if err := j.refresh(context.Background()); err != nil {
+		return nil, err
+	}
+
+	return j, nil
+}
+
+func (j *JWKS) isRemote() bool {
+	return strings.HasPrefix(j.source, "http://") || strings.HasPrefix(j.source, "https://")
+}
+
+// Watch refreshes a URL-backed key set on an interval until ctx is done
+func (j *JWKS) Watch(ctx context.Context, interval time.Duration) {
+	if !j.isRemote() {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := j.refresh(ctx); err != nil {
+					slog.Warn("failed to refresh JWKS", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// Key returns the public key for kid, refetching once if it is unknown
+func (j *JWKS) Key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	j.mutex.RLock()
+	key, ok := j.keys[kid]
+	stale := time.Since(j.fetchedAt) > jwksRefetchInterval
+	j.mutex.RUnlock()
+
+	if ok {
+		return key, nil
+	}
+
+	if j.isRemote() && stale {
+		if err := j.refresh(ctx); err != nil {
+			return nil, err
+		}
+
+		j.mutex.RLock()
+		key, ok = j.keys[kid]
+		j.mutex.RUnlock()
+		if ok {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (j *JWKS) refresh(ctx context.Context) error {
+	data, err := j.read(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load JWKS: %w", err)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Kty != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+
+		key, err := jwk.rsaPublicKey()
+		if err != nil {
+			return fmt.Errorf("invalid key %q: %w", jwk.Kid, err)
+		}
+		keys[jwk.Kid] = key
+	}
+
+	j.mutex.Lock()
+	j.keys = keys
+	j.fetchedAt = time.Now()
+	j.mutex.Unlock()
+
+	slog.Info("loaded JWKS signing keys", "keys", len(keys))
+	return nil
+}
+
+func (j *JWKS) read(ctx context.Context) ([]byte, error) {
+	if !j.isRemote() {
+		return os.ReadFile(j.source)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.source, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := j.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("JWKS endpoint returned status %d", resp.StatusCode)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+func (k jsonWebKey) rsaPublicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus: %w", err)
+	}
+
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent: %w", err)
+	}
+
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+		return nil, fmt.Errorf("exponent too large")
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(exponent.Int64()),
+	}, nil
+}
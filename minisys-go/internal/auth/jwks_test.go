@@ -0,0 +1,204 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var (
+	testKeyOnce sync.Once
+	testKeys    [2]*rsa.PrivateKey
+)
+
+// signingKeys returns two RSA keys shared by the tests in this package
+func signingKeys(t *testing.T) [2]*rsa.PrivateKey {
+	t.Helper()
+	testKeyOnce.Do(func() {
+		for i := range testKeys {
+			key, err := rsa.GenerateKey(rand.Reader, 2048)
+			if err != nil {
+				panic(err)
+			}
+			testKeys[i] = key
+		}
+	})
+	return testKeys
+}
+
+func toJWK(kid string, key *rsa.PublicKey) jsonWebKey {
+	return jsonWebKey{
+		Kty: "RSA",
+		Kid: kid,
+		Use: "sig",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func jwksJSON(t *testing.T, keys ...jsonWebKey) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]any{"keys": keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestJWKSFromFile(t *testing.T) {
+	keys := signingKeys(t)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	data := jwksJSON(t,
+		toJWK("a", &keys[0].PublicKey),
+		jsonWebKey{Kty: "EC", Kid: "ec"},
+		jsonWebKey{Kty: "RSA", Kid: "enc", Use: "enc", N: "AQAB", E: "AQAB"},
+	)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	jwks, err := NewJWKS(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		kid     string
+		wantErr bool
+	}{
+		{"a", false},
+		{"ec", true},  // not an RSA key
+		{"enc", true}, // not a signing key
+		{"missing", true},
+	}
+	for _, tt := range tests {
+		key, err := jwks.Key(context.Background(), tt.kid)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Key(%q) error = %v, want error %v", tt.kid, err, tt.wantErr)
+		}
+		if err == nil && !key.Equal(&keys[0].PublicKey) {
+			t.Errorf("Key(%q) returned the wrong key", tt.kid)
+		}
+	}
+}
+
+func TestNewJWKSRejectsBadSets(t *testing.T) {
+	tests := map[string]string{
+		"not json":     `{"keys": [`,
+		"bad modulus":  `{"keys": [{"kty": "RSA", "kid": "a", "n": "!!", "e": "AQAB"}]}`,
+		"bad exponent": `{"keys": [{"kty": "RSA", "kid": "a", "n": "AQAB", "e": "!!"}]}`,
+		"huge exponent": `{"keys": [{"kty": "RSA", "kid": "a", "n": "AQAB", "e": "` +
+			base64.RawURLEncoding.EncodeToString([]byte{1, 0, 0, 0, 0}) + `"}]}`,
+	}
+
+	for name, body := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "jwks.json")
+			if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := NewJWKS(path); err == nil {
+				t.Error("NewJWKS accepted an invalid key set")
+			}
+		})
+	}
+
+	if _, err := NewJWKS(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("NewJWKS accepted a missing file")
+	}
+}
+
+func TestJWKSRefetchesUnknownKid(t *testing.T) {
+	keys := signingKeys(t)
+
+	var fetches atomic.Int32
+	var served atomic.Value
+	served.Store(jwksJSON(t, toJWK("old", &keys[0].PublicKey)))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Write(served.Load().([]byte))
+	}))
+	defer server.Close()
+
+	jwks, err := NewJWKS(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// The key was rotated, but the set was fetched too recently to refetch
+	served.Store(jwksJSON(t, toJWK("old", &keys[0].PublicKey), toJWK("new", &keys[1].PublicKey)))
+	if _, err := jwks.Key(ctx, "new"); err == nil || !strings.Contains(err.Error(), "unknown signing key") {
+		t.Fatalf("Key(new) error = %v, want unknown signing key", err)
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Fatalf("fetched %d times, want 1", n)
+	}
+
+	// Once the refetch interval has passed an unknown kid triggers a refresh
+	jwks.mutex.Lock()
+	jwks.fetchedAt = time.Now().Add(-2 * jwksRefetchInterval)
+	jwks.mutex.Unlock()
+
+	key, err := jwks.Key(ctx, "new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !key.Equal(&keys[1].PublicKey) {
+		t.Error("Key(new) returned the wrong key")
+	}
+	if n := fetches.Load(); n != 2 {
+		t.Fatalf("fetched %d times, want 2", n)
+	}
+
+	// Known kids never hit the endpoint, and a kid that is still unknown
+	// after a refresh does not refetch again straight away
+	if _, err := jwks.Key(ctx, "old"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jwks.Key(ctx, "other"); err == nil {
+		t.Error("Key(other) succeeded")
+	}
+	if n := fetches.Load(); n != 2 {
+		t.Errorf("fetched %d times, want 2", n)
+	}
+}
+
+func TestJWKSKeepsKeysWhenRefreshFails(t *testing.T) {
+	keys := signingKeys(t)
+
+	var failing atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(jwksJSON(t, toJWK("a", &keys[0].PublicKey)))
+	}))
+	defer server.Close()
+
+	jwks, err := NewJWKS(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failing.Store(true)
+	if err := jwks.refresh(context.Background()); err == nil {
+		t.Fatal("refresh succeeded against a failing endpoint")
+	}
+	if _, err := jwks.Key(context.Background(), "a"); err != nil {
+		t.Errorf("a failed refresh dropped the cached keys: %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWTConfig configures token verification. At least one of HMACSecret
+// (HS256) or JWKS (RS256) must be set.
+type JWTConfig struct {
+	HMACSecret []byte
+	JWKS       *JWKS
+	Issuer     string
+	Audience   string
+}
+
+// claims are the registered claims plus the ones we map onto an Identity.
+// Scopes may arrive as a space-delimited "scope" string or a "scp" array.
+type claims struct {
+	jwt.RegisteredClaims
+	Roles []string `json:"roles"`
+	Scope string   `json:"scope"`
+	Scp   []string `json:"scp"`
+}
+
+type JWTValidator struct {
+	cfg     JWTConfig
+	methods []string
+}
+
+func NewJWTValidator(cfg JWTConfig) (*JWTValidator, error) {
+	var methods []string
+	if len(cfg.HMACSecret) > 0 {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if cfg.JWKS != nil {
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+	if len(methods) == 0 {
+		return nil, fmt.Errorf("no JWT signing keys configured")
+	}
+
+	return &JWTValidator{cfg: cfg, methods: methods}, nil
+}
+
+// Validate verifies the token signature and claims and returns the caller
+func (v *JWTValidator) Validate(ctx context.Context, token string) (*Identity, error) {
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(v.methods),
+		jwt.WithExpirationRequired(),
+	}
+	if v.cfg.Issuer != "" {
+		options = append(options, jwt.WithIssuer(v.cfg.Issuer))
+	}
+	if v.cfg.Audience != "" {
+		options = append(options, jwt.WithAudience(v.cfg.Audience))
+	}
+
+	var c claims
+	_, err := jwt.ParseWithClaims(token, &c, func(t *jwt.Token) (interface{}, error) {
+		switch t.Method.Alg() {
+		case jwt.SigningMethodHS256.Alg():
+			return v.cfg.HMACSecret, nil
+		case jwt.SigningMethodRS256.Alg():
+			kid, _ := t.Header["kid"].(string)
+			return v.cfg.JWKS.Key(ctx, kid)
+		default:
+			return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+		}
+	}, options...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+
+	if c.Subject == "" {
+		return nil, errors.New("invalid token: missing subject")
+	}
+
+	scopes := c.Scp
+	if c.Scope != "" {
+		scopes = append(scopes, strings.Fields(c.Scope)...)
+	}
+
+	return &Identity{
+		Subject: c.Subject,
+		Roles:   c.Roles,
+		Scopes:  scopes,
+		Method:  MethodJWT,
+	}, nil
+}
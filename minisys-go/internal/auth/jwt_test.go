@@ -0,0 +1,190 @@
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var testSecret = []byte("test-secret-that-is-long-enough!")
+
+func sign(t *testing.T, method jwt.SigningMethod, key any, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":   "alice",
+		"iss":   "https://issuer.example",
+		"aud":   "minisys",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"roles": []string{"shopper"},
+		"scope": "orders:read orders:write",
+	}
+}
+
+func withClaim(key string, value any) jwt.MapClaims {
+	claims := validClaims()
+	if value == nil {
+		delete(claims, key)
+	} else {
+		claims[key] = value
+	}
+	return claims
+}
+
+func TestNewJWTValidatorNeedsKeys(t *testing.T) {
+	if _, err := NewJWTValidator(JWTConfig{Issuer: "https://issuer.example"}); err == nil {
+		t.Error("NewJWTValidator accepted a config without keys")
+	}
+}
+
+func TestJWTValidatorHS256(t *testing.T) {
+	validator, err := NewJWTValidator(JWTConfig{
+		HMACSecret: testSecret,
+		Issuer:     "https://issuer.example",
+		Audience:   "minisys",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hour := time.Hour
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "valid", token: sign(t, jwt.SigningMethodHS256, testSecret, "", validClaims())},
+		{name: "audience list", token: sign(t, jwt.SigningMethodHS256, testSecret, "", withClaim("aud", []string{"other", "minisys"}))},
+		{name: "wrong secret", token: sign(t, jwt.SigningMethodHS256, []byte("another-secret-entirely-wrong!!"), "", validClaims()), wantErr: true},
+		{name: "HS384 is not pinned", token: sign(t, jwt.SigningMethodHS384, testSecret, "", validClaims()), wantErr: true},
+		{name: "alg none", token: sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "", validClaims()), wantErr: true},
+		{name: "expired", token: sign(t, jwt.SigningMethodHS256, testSecret, "", withClaim("exp", time.Now().Add(-hour).Unix())), wantErr: true},
+		{name: "no expiry", token: sign(t, jwt.SigningMethodHS256, testSecret, "", withClaim("exp", nil)), wantErr: true},
+		{name: "not yet valid", token: sign(t, jwt.SigningMethodHS256, testSecret, "", withClaim("nbf", time.Now().Add(hour).Unix())), wantErr: true},
+		{name: "already valid", token: sign(t, jwt.SigningMethodHS256, testSecret, "", withClaim("nbf", time.Now().Add(-hour).Unix()))},
+		{name: "wrong issuer", token: sign(t, jwt.SigningMethodHS256, testSecret, "", withClaim("iss", "https://evil.example")), wantErr: true},
+		{name: "no issuer", token: sign(t, jwt.SigningMethodHS256, testSecret, "", withClaim("iss", nil)), wantErr: true},
+		{name: "wrong audience", token: sign(t, jwt.SigningMethodHS256, testSecret, "", withClaim("aud", "other")), wantErr: true},
+		{name: "no subject", token: sign(t, jwt.SigningMethodHS256, testSecret, "", withClaim("sub", nil)), wantErr: true},
+		{name: "garbage", token: "not.a.token", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := validator.Validate(context.Background(), tt.token)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Validate accepted the token as %+v", identity)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if identity.Subject != "alice" || identity.Method != MethodJWT {
+				t.Errorf("identity = %+v, want alice via jwt", identity)
+			}
+		})
+	}
+}
+
+func TestJWTValidatorClaims(t *testing.T) {
+	validator, err := NewJWTValidator(JWTConfig{HMACSecret: testSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := withClaim("scp", []string{"products:write"})
+	identity, err := validator.Validate(context.Background(), sign(t, jwt.SigningMethodHS256, testSecret, "", claims))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(identity.Roles, []string{"shopper"}) {
+		t.Errorf("roles = %v, want [shopper]", identity.Roles)
+	}
+	for _, scope := range []string{"products:write", "orders:read", "orders:write"} {
+		if !identity.HasScope(scope) {
+			t.Errorf("scopes %v are missing %s", identity.Scopes, scope)
+		}
+	}
+
+	// Without configured issuer and audience any value is accepted
+	claims = withClaim("iss", "https://other.example")
+	claims["aud"] = "elsewhere"
+	if _, err := validator.Validate(context.Background(), sign(t, jwt.SigningMethodHS256, testSecret, "", claims)); err != nil {
+		t.Errorf("Validate without issuer or audience checks = %v", err)
+	}
+}
+
+func TestJWTValidatorRS256(t *testing.T) {
+	keys := signingKeys(t)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwksJSON(t, toJWK("k1", &keys[0].PublicKey)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	jwks, err := NewJWKS(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	validator, err := NewJWTValidator(JWTConfig{JWKS: jwks, Audience: "minisys"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An attacker who knows the public key must not be able to sign an
+	// HS256 token with it when only RS256 is configured
+	publicDER, err := x509.MarshalPKIXPublicKey(&keys[0].PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr string
+	}{
+		{name: "valid", token: sign(t, jwt.SigningMethodRS256, keys[0], "k1", validClaims())},
+		{name: "unknown kid", token: sign(t, jwt.SigningMethodRS256, keys[0], "k2", validClaims()), wantErr: "unknown signing key"},
+		{name: "key does not match kid", token: sign(t, jwt.SigningMethodRS256, keys[1], "k1", validClaims()), wantErr: "invalid token"},
+		{name: "HS256 signed with the public key", token: sign(t, jwt.SigningMethodHS256, publicDER, "k1", validClaims()), wantErr: "signing method"},
+		{name: "RS512 is not pinned", token: sign(t, jwt.SigningMethodRS512, keys[0], "k1", validClaims()), wantErr: "signing method"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := validator.Validate(context.Background(), tt.token)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if identity.Subject != "alice" {
+					t.Errorf("subject = %s, want alice", identity.Subject)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate error = %v, want one mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
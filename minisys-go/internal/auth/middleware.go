@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHeader carries API keys for service-to-service callers
+const APIKeyHeader = "X-API-Key"
+
+var (
+	errUnsupportedCredential = errors.New("unsupported credential type")
+	errUnknownAPIKey         = errors.New("unknown API key")
+)
+
+// identityContextKey is where the verified Identity is stored on the gin context
+const identityContextKey = "auth.identity"
+
+// Authenticator verifies callers at the gateway and forwards their identity
+type Authenticator struct {
+	jwt     *JWTValidator
+	apiKeys *APIKeyStore
+	policy  *Policy
+}
+
+// NewAuthenticator builds an Authenticator; jwt and apiKeys may be nil
+// to disable that credential type
+func NewAuthenticator(jwt *JWTValidator, apiKeys *APIKeyStore, policy *Policy) *Authenticator {
+	return &Authenticator{
+		jwt:     jwt,
+		apiKeys: apiKeys,
+		policy:  policy,
+	}
+}
+
+// Middleware authenticates the request, enforces route scopes and
+// replaces caller credentials with verified identity headers
+func (a *Authenticator) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		StripHeaders(c.Request.Header)
+
+		rule := a.policy.Match(c.Request.Method, c.Request.URL.Path)
+
+		identity, present, err := a.authenticate(c)
+		if err != nil {
+			slog.InfoContext(c.Request.Context(), "authentication failed", "method", c.Request.Method, "path", c.Request.URL.Path, "error", err)
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+			return
+		}
+
+		if !present {
+			if rule != nil && rule.Public {
+				c.Next()
+				return
+			}
+			c.Header("WWW-Authenticate", "Bearer")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+			return
+		}
+
+		if rule != nil {
+			for _, scope := range rule.Scopes {
+				if !identity.HasScope(scope) {
+					slog.InfoContext(c.Request.Context(), "insufficient scope", "subject", identity.Subject, "scope", scope, "method", c.Request.Method, "path", c.Request.URL.Path)
+					c.Header("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+strings.Join(rule.Scopes, " ")+`"`)
+					c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "insufficient scope", "required": rule.Scopes})
+					return
+				}
+			}
+		}
+
+		// Upstreams see only the verified identity, never the raw credential
+		c.Request.Header.Del("Authorization")
+		c.Request.Header.Del(APIKeyHeader)
+		identity.SetHeaders(c.Request.Header)
+		c.Set(identityContextKey, identity)
+
+		c.Next()
+	}
+}
+
+// authenticate reports the caller's identity and whether any credential
+// was presented at all
+func (a *Authenticator) authenticate(c *gin.Context) (*Identity, bool, error) {
+	if header := c.GetHeader("Authorization"); header != "" {
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok || a.jwt == nil {
+			return nil, true, errUnsupportedCredential
+		}
+
+		identity, err := a.jwt.Validate(c.Request.Context(), strings.TrimSpace(token))
+		return identity, true, err
+	}
+
+	if key := c.GetHeader(APIKeyHeader); key != "" {
+		if a.apiKeys == nil {
+			return nil, true, errUnsupportedCredential
+		}
+
+		identity := a.apiKeys.Lookup(key)
+		if identity == nil {
+			return nil, true, errUnknownAPIKey
+		}
+		return identity, true, nil
+	}
+
+	return nil, false, nil
+}
+
+// FromContext returns the identity verified earlier in the request, if any
+func FromContext(c *gin.Context) *Identity {
+	value, ok := c.Get(identityContextKey)
+	if !ok {
+		return nil
+	}
+	identity, _ := value.(*Identity)
+	return identity
+}
@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// RouteRule declares who may call a set of routes.
+//
+// Paths are matched segment by segment: "*" matches exactly one segment
+// and a trailing "**" matches any remainder, including nothing.
+type RouteRule struct {
+	Methods []string `json:"methods"`
+	Path    string   `json:"path"`
+	Public  bool     `json:"public"`
+	Scopes  []string `json:"scopes"`
+}
+
+func (r RouteRule) Matches(method, path string) bool {
+	if len(r.Methods) > 0 {
+		found := false
+		for _, m := range r.Methods {
+			if strings.EqualFold(m, method) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return matchPath(r.Path, path)
+}
+
+func matchPath(pattern, path string) bool {
+	patternSegs := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathSegs := strings.Split(strings.Trim(path, "/"), "/")
+
+	for i, seg := range patternSegs {
+		if seg == "**" {
+			return true
+		}
+		if i >= len(pathSegs) {
+			return false
+		}
+		if seg != "*" && seg != pathSegs[i] {
+			return false
+		}
+	}
+
+	return len(patternSegs) == len(pathSegs)
+}
+
+// Policy is an ordered list of route rules; the first match wins.
+// Requests matching no rule only need to be authenticated.
+type Policy struct {
+	Rules []RouteRule `json:"rules"`
+}
+
+func (p *Policy) Match(method, path string) *RouteRule {
+	for i := range p.Rules {
+		if p.Rules[i].Matches(method, path) {
+			return &p.Rules[i]
+		}
+	}
+	return nil
+}
+
+// DefaultPolicy keeps the catalog readable by anyone and requires
+// scopes for every write and for all order access
+func DefaultPolicy() *Policy {
+	return &Policy{
+		Rules: []RouteRule{
+			{Methods: []string{"GET", "HEAD"}, Path: "/products/*/inventory/**", Scopes: []string{"inventory:read"}},
+			{Methods: []string{"POST", "PUT", "PATCH", "DELETE"}, Path: "/products/*/inventory/**", Scopes: []string{"inventory:write"}},
+			{Methods: []string{"GET", "HEAD"}, Path: "/products/*/reorder-policy", Scopes: []string{"inventory:read"}},
+			{Methods: []string{"POST", "PUT", "PATCH", "DELETE"}, Path: "/products/*/reorder-policy", Scopes: []string{"inventory:write"}},
+			{Methods: []string{"GET", "HEAD"}, Path: "/products/*/prices", Scopes: []string{"products:write"}},
+			{Methods: []string{"GET", "HEAD"}, Path: "/products/**", Public: true},
+			{Methods: []string{"POST", "PUT", "PATCH", "DELETE"}, Path: "/products/**", Scopes: []string{"products:write"}},
+			{Methods: []string{"GET", "HEAD"}, Path: "/categories/**", Public: true},
+			{Methods: []string{"POST", "PUT", "PATCH", "DELETE"}, Path: "/categories/**", Scopes: []string{"products:write"}},
+			{Methods: []string{"GET", "HEAD"}, Path: "/variants/**", Public: true},
+			{Methods: []string{"GET", "HEAD"}, Path: "/warehouses/**", Scopes: []string{"inventory:read"}},
+			{Methods: []string{"POST", "PUT", "PATCH", "DELETE"}, Path: "/warehouses/**", Scopes: []string{"inventory:write"}},
+			{Methods: []string{"GET", "HEAD"}, Path: "/inventory/**", Scopes: []string{"inventory:read"}},
+			{Methods: []string{"GET", "HEAD"}, Path: "/attributes/**", Public: true},
+			{Methods: []string{"POST", "PUT", "PATCH", "DELETE"}, Path: "/attributes/**", Scopes: []string{"products:write"}},
+			{Methods: []string{"GET", "HEAD"}, Path: "/reservations/**", Scopes: []string{"orders:read"}},
+			{Methods: []string{"POST", "PUT", "PATCH", "DELETE"}, Path: "/reservations/**", Scopes: []string{"orders:write"}},
+			{Methods: []string{"GET", "HEAD"}, Path: "/orders/**", Scopes: []string{"orders:read"}},
+			{Methods: []string{"POST", "PUT", "PATCH", "DELETE"}, Path: "/orders/**", Scopes: []string{"orders:write"}},
+			{Path: "/admin/**", Scopes: []string{"admin"}},
+		},
+	}
+}
+
+// LoadPolicy reads a JSON policy file, or returns DefaultPolicy if path is empty
+func LoadPolicy(path string) (*Policy, error) {
+	if path == "" {
+		return DefaultPolicy(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read auth policy: %w", err)
+	}
+
+	var policy Policy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("failed to parse auth policy: %w", err)
+	}
+
+	for _, rule := range policy.Rules {
+		if rule.Path == "" {
+			return nil, fmt.Errorf("auth policy rule is missing a path")
+		}
+	}
+
+	return &policy, nil
+}
@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"/products", "/products", true},
+		{"/products", "/products/", true},
+		{"/products", "/products/1", false},
+		{"/products", "/product", false},
+		{"/products/*", "/products/1", true},
+		{"/products/*", "/products", false},
+		{"/products/*", "/products/1/variants", false},
+		{"/products/*/prices", "/products/1/prices", true},
+		{"/products/*/prices", "/products/1/variants", false},
+		{"/products/**", "/products", true},
+		{"/products/**", "/products/1", true},
+		{"/products/**", "/products/1/inventory/ledger", true},
+		{"/products/**", "/productsx", false},
+		{"/products/*/inventory/**", "/products/1/inventory", true},
+		{"/products/*/inventory/**", "/products/1/inventory/ledger", true},
+		{"/products/*/inventory/**", "/products/1", false},
+		{"/**", "/anything/at/all", true},
+		{"/admin/**", "/orders", false},
+	}
+
+	for _, tt := range tests {
+		if got := matchPath(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("matchPath(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRouteRuleMatchesMethod(t *testing.T) {
+	rule := RouteRule{Methods: []string{"GET", "HEAD"}, Path: "/products/**"}
+
+	tests := []struct {
+		method string
+		want   bool
+	}{
+		{"GET", true},
+		{"get", true},
+		{"HEAD", true},
+		{"POST", false},
+		{"DELETE", false},
+	}
+	for _, tt := range tests {
+		if got := rule.Matches(tt.method, "/products/1"); got != tt.want {
+			t.Errorf("Matches(%q) = %v, want %v", tt.method, got, tt.want)
+		}
+	}
+
+	anyMethod := RouteRule{Path: "/admin/**"}
+	if !anyMethod.Matches("PATCH", "/admin/log-level") {
+		t.Error("a rule without methods should match every method")
+	}
+}
+
+func TestDefaultPolicy(t *testing.T) {
+	tests := []struct {
+		method     string
+		path       string
+		wantPublic bool
+		wantScopes []string
+	}{
+		{"GET", "/products", true, nil},
+		{"GET", "/products/42", true, nil},
+		{"HEAD", "/products/42/variants", true, nil},
+		{"POST", "/products", false, []string{"products:write"}},
+		{"DELETE", "/products/42", false, []string{"products:write"}},
+		{"GET", "/products/42/prices", false, []string{"products:write"}},
+		{"POST", "/products/42/prices", false, []string{"products:write"}},
+		{"GET", "/products/42/inventory/ledger", false, []string{"inventory:read"}},
+		{"POST", "/products/42/inventory/adjustments", false, []string{"inventory:write"}},
+		{"GET", "/products/42/reorder-policy", false, []string{"inventory:read"}},
+		{"PUT", "/products/42/reorder-policy", false, []string{"inventory:write"}},
+		{"GET", "/categories/3/products", true, nil},
+		{"POST", "/categories", false, []string{"products:write"}},
+		{"GET", "/variants", true, nil},
+		{"GET", "/warehouses", false, []string{"inventory:read"}},
+		{"PUT", "/warehouses/1/stock/9", false, []string{"inventory:write"}},
+		{"GET", "/inventory/drift", false, []string{"inventory:read"}},
+		{"GET", "/attributes", true, nil},
+		{"POST", "/attributes", false, []string{"products:write"}},
+		{"GET", "/reservations/5", false, []string{"orders:read"}},
+		{"POST", "/reservations", false, []string{"orders:write"}},
+		{"POST", "/reservations/5/release", false, []string{"orders:write"}},
+		{"POST", "/reservations/5/confirm", false, []string{"orders:write"}},
+		{"GET", "/orders", false, []string{"orders:read"}},
+		{"PATCH", "/orders/5/status", false, []string{"orders:write"}},
+		{"GET", "/admin/log-level", false, []string{"admin"}},
+		{"DELETE", "/admin/products/42", false, []string{"admin"}},
+	}
+
+	policy := DefaultPolicy()
+	for _, tt := range tests {
+		rule := policy.Match(tt.method, tt.path)
+		if rule == nil {
+			t.Errorf("%s %s matched no rule", tt.method, tt.path)
+			continue
+		}
+		if rule.Public != tt.wantPublic || !slices.Equal(rule.Scopes, tt.wantScopes) {
+			t.Errorf("%s %s: public %v scopes %v, want public %v scopes %v",
+				tt.method, tt.path, rule.Public, rule.Scopes, tt.wantPublic, tt.wantScopes)
+		}
+	}
+
+	if rule := policy.Match("GET", "/health"); rule != nil {
+		t.Errorf("GET /health matched %+v, want no rule", rule)
+	}
+}
@@ -0,0 +1,166 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/metrics"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRefreshEarly(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		entry  entry
+		wantLo float64 // bounds on the share of calls that refresh
+		wantHi float64
+	}{
+		{name: "no recorded load time", entry: entry{Delta: 0, Expiry: now.Add(time.Millisecond).UnixMilli()}, wantLo: 0, wantHi: 0},
+		{name: "already expired", entry: entry{Delta: 10, Expiry: now.Add(-time.Second).UnixMilli()}, wantLo: 1, wantHi: 1},
+		{name: "fast load far from expiry", entry: entry{Delta: 1, Expiry: now.Add(time.Hour).UnixMilli()}, wantLo: 0, wantHi: 0},
+		// P(refresh) = exp(-remaining/delta) = exp(-1) ~ 0.37
+		{name: "one load time from expiry", entry: entry{Delta: 1000, Expiry: now.Add(time.Second).UnixMilli()}, wantLo: 0.25, wantHi: 0.5},
+		// exp(-0.1) ~ 0.9
+		{name: "slow load close to expiry", entry: entry{Delta: 10000, Expiry: now.Add(time.Second).UnixMilli()}, wantLo: 0.8, wantHi: 0.97},
+	}
+
+	const runs = 4000
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refreshed := 0
+			for range runs {
+				if refreshEarly(tt.entry) {
+					refreshed++
+				}
+			}
+			share := float64(refreshed) / runs
+			if share < tt.wantLo || share > tt.wantHi {
+				t.Errorf("refreshed %.2f of the time, want between %.2f and %.2f", share, tt.wantLo, tt.wantHi)
+			}
+		})
+	}
+}
+
+func TestJitter(t *testing.T) {
+	tests := []time.Duration{time.Minute, 5 * time.Minute, negativeTTL, 5 * time.Nanosecond}
+
+	for _, ttl := range tests {
+		spread := time.Duration(float64(ttl) * ttlJitter)
+		lo, hi := ttl-spread, ttl+spread
+		seen := make(map[time.Duration]bool)
+		for range 200 {
+			got := jitter(ttl)
+			if got < lo || got > hi {
+				t.Fatalf("jitter(%v) = %v, want within [%v, %v]", ttl, got, lo, hi)
+			}
+			seen[got] = true
+		}
+		if spread > 0 && len(seen) < 2 {
+			t.Errorf("jitter(%v) never varied", ttl)
+		}
+	}
+}
+
+func TestEncodeEntry(t *testing.T) {
+	c := &RedisCache{ttl: time.Minute}
+
+	tests := []struct {
+		name        string
+		value       any
+		wantMissing bool
+		wantTTL     time.Duration
+	}{
+		{name: "value", value: map[string]int{"id": 1}, wantTTL: time.Minute},
+		{name: "nil is negative", value: nil, wantMissing: true, wantTTL: negativeTTL},
+		{name: "typed nil is negative", value: (*struct{})(nil), wantMissing: true, wantTTL: negativeTTL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, data, ttl, err := c.encodeEntry(tt.value, 250*time.Millisecond)
+			if err != nil {
+				t.Fatal(err)
+			}
+			spread := time.Duration(float64(tt.wantTTL) * ttlJitter)
+			if ttl < tt.wantTTL-spread || ttl > tt.wantTTL+spread {
+				t.Errorf("ttl = %v, want about %v", ttl, tt.wantTTL)
+			}
+
+			decoded, ok := decodeEntry(data)
+			if !ok {
+				t.Fatalf("decodeEntry(%s) failed", data)
+			}
+			if decoded.Missing != tt.wantMissing || e.Missing != tt.wantMissing {
+				t.Errorf("missing = %v, want %v", decoded.Missing, tt.wantMissing)
+			}
+			if tt.wantMissing {
+				if !IsNegative(decoded.result()) {
+					t.Errorf("result = %s, want Negative", decoded.result())
+				}
+			} else if decoded.Delta != 250 {
+				t.Errorf("delta = %d, want 250", decoded.Delta)
+			}
+		})
+	}
+
+	if _, ok := decodeEntry([]byte(`{"id":1}`)); ok {
+		t.Error("a value stored before entries existed decoded as an entry")
+	}
+}
+
+// offlineCache has no reachable Redis, so only its local tier answers
+func offlineCache() *RedisCache {
+	return &RedisCache{
+		client: redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: 50 * time.Millisecond, MaxRetries: -1}),
+		ttl:    time.Minute,
+		local:  NewLocalCache(10, time.Hour),
+	}
+}
+
+func TestFetchEarlyRefreshFailure(t *testing.T) {
+	failing := func(ctx context.Context) (interface{}, error) { return nil, errors.New("database down") }
+	// A load that took far longer than the time left makes an early
+	// refresh all but certain
+	dueSoon := func(value []byte, missing bool) entry {
+		return entry{Value: value, Missing: missing, Delta: 1e9, Expiry: time.Now().Add(time.Second).UnixMilli()}
+	}
+
+	tests := []struct {
+		name      string
+		entry     *entry
+		wantFound bool
+		wantValue int
+		wantErr   bool
+	}{
+		{name: "cached value is served", entry: ptr(dueSoon([]byte(`42`), false)), wantFound: true, wantValue: 42},
+		{name: "cached negative is served", entry: ptr(dueSoon(Negative, true))},
+		{name: "nothing cached", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := offlineCache()
+			if tt.entry != nil {
+				c.local.set("k", *tt.entry)
+			}
+
+			var got int
+			found, lookup, err := c.Fetch(context.Background(), "k", &got, failing)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Fetch error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if found != tt.wantFound || got != tt.wantValue || lookup != metrics.CacheHit {
+				t.Errorf("Fetch = %v, %d, %s; want %v, %d, hit", found, got, lookup, tt.wantFound, tt.wantValue)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
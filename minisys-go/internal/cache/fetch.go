@@ -0,0 +1,110 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/metrics"
+)
+
+// Loader produces the value for a key on a cache miss. A nil value means
+// there is nothing there and is cached as a short-lived negative entry.
+type Loader func(ctx context.Context) (interface{}, error)
+
+// Fetch reads key into dest, calling load on a miss and caching what it
+// returns. It reports whether a value was found, cached or loaded, and the
+// cache lookup result (metrics.CacheHit, CacheMiss or CacheError).
+//
+// Concurrent misses for the same key within this process share one load,
+// so an expired hot key does not send every request to the database at
+// once. A value is also refreshed early, with a probability that rises as
+// expiry nears and with how long it took to load (XFetch), so hot keys
+// are usually reloaded by a single request before they expire at all. If
+// such an early reload fails, the cached value is still served.
+func (c *RedisCache) Fetch(ctx context.Context, key string, dest interface{}, load Loader) (bool, string, error) {
+	lookup := metrics.CacheMiss
+	e, ok, err := c.read(ctx, key)
+	if err != nil {
+		lookup = metrics.CacheError
+		slog.WarnContext(ctx, "cache error", "key", key, "error", err)
+	}
+	fresh := ok && time.Now().UnixMilli() < e.Expiry
+	if ok && !refreshEarly(e) {
+		if found, err := e.decode(dest); err == nil {
+			return found, metrics.CacheHit, nil
+		}
+		fresh = false
+	}
+
+	// Waiters get the value as JSON and decode their own copy. The load
+	// outlives a caller that gives up, since others may be waiting on it.
+	loaded := c.loads.DoChan(key, func() (interface{}, error) {
+		return c.loadEntry(context.WithoutCancel(ctx), key, load)
+	})
+	select {
+	case <-ctx.Done():
+		return false, lookup, ctx.Err()
+	case res := <-loaded:
+		if res.Err != nil && fresh {
+			slog.WarnContext(ctx, "early refresh failed, serving cached value", "key", key, "error", res.Err)
+			if found, err := e.decode(dest); err == nil {
+				return found, metrics.CacheHit, nil
+			}
+		}
+		if res.Err != nil {
+			return false, lookup, res.Err
+		}
+		data, _ := res.Val.(json.RawMessage)
+		if data == nil {
+			return false, lookup, nil
+		}
+		return true, lookup, json.Unmarshal(data, dest)
+	}
+}
+
+// decode reads a cached value into dest and reports whether there was one
+func (e entry) decode(dest interface{}) (bool, error) {
+	if e.Missing {
+		return false, nil
+	}
+	return true, json.Unmarshal(e.Value, dest)
+}
+
+// loadEntry calls load and caches the result, returning the value's JSON,
+// or nil if there is no value
+func (c *RedisCache) loadEntry(ctx context.Context, key string, load Loader) (json.RawMessage, error) {
+	start := time.Now()
+	value, err := load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	e, data, ttl, err := c.encodeEntry(value, time.Since(start))
+	if err != nil {
+		return nil, err
+	}
+	if err := c.write(ctx, key, e, data, ttl); err != nil {
+		slog.WarnContext(ctx, "failed to cache value", "key", key, "error", err)
+	}
+
+	if e.Missing {
+		return nil, nil
+	}
+	return e.Value, nil
+}
+
+// refreshEarly decides whether to reload a value before it expires: the
+// closer the expiry and the slower the load, the likelier (XFetch, from
+// "Optimal Probabilistic Cache Stampede Prevention", Vattani et al.)
+func refreshEarly(e entry) bool {
+	if e.Delta <= 0 {
+		return false
+	}
+	// -ln(u) for u in (0, 1] is exponentially distributed with mean 1
+	gap := float64(e.Delta) * earlyRefreshBeta * -math.Log(1-rand.Float64())
+	return float64(time.Now().UnixMilli())+gap >= float64(e.Expiry)
+}
@@ -0,0 +1,201 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// invalidationChannel carries deletions to every replica's local cache
+const invalidationChannel = "cache:invalidate"
+
+// invalidation names the keys, or the pattern of keys, a replica deleted
+type invalidation struct {
+	Keys    []string `json:"keys,omitempty"`
+	Pattern string   `json:"pattern,omitempty"`
+}
+
+// LocalCache is a size-bounded, in-process LRU of cache entries that sits
+// in front of Redis. Entries also expire after a TTL, which bounds how
+// stale a replica can get if it misses an invalidation.
+type LocalCache struct {
+	mu    sync.Mutex
+	size  int
+	ttl   time.Duration
+	order *list.List // most recently used first
+	items map[string]*list.Element
+}
+
+type localItem struct {
+	key     string
+	entry   entry
+	expires time.Time
+}
+
+func NewLocalCache(size int, ttl time.Duration) *LocalCache {
+	return &LocalCache{
+		size:  size,
+		ttl:   ttl,
+		order: list.New(),
+		items: make(map[string]*list.Element, size),
+	}
+}
+
+func (l *LocalCache) get(key string) (entry, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	el, ok := l.items[key]
+	if !ok {
+		return entry{}, false
+	}
+	item := el.Value.(*localItem)
+	if time.Now().After(item.expires) {
+		l.remove(el)
+		return entry{}, false
+	}
+
+	l.order.MoveToFront(el)
+	return item.entry, true
+}
+
+// set stores e until it expires in Redis, or for the local TTL if that is
+// sooner, evicting the least recently used entry when full
+func (l *LocalCache) set(key string, e entry) {
+	expires := time.Now().Add(l.ttl)
+	if redisExpiry := time.UnixMilli(e.Expiry); redisExpiry.Before(expires) {
+		expires = redisExpiry
+	}
+	if !expires.After(time.Now()) {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if el, ok := l.items[key]; ok {
+		el.Value = &localItem{key: key, entry: e, expires: expires}
+		l.order.MoveToFront(el)
+		return
+	}
+
+	l.items[key] = l.order.PushFront(&localItem{key: key, entry: e, expires: expires})
+	for l.order.Len() > l.size {
+		l.remove(l.order.Back())
+	}
+}
+
+func (l *LocalCache) delete(keys ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, key := range keys {
+		if el, ok := l.items[key]; ok {
+			l.remove(el)
+		}
+	}
+}
+
+// deletePattern drops every key matching a Redis glob pattern. The
+// patterns in use (prefixes ending in *) mean the same to path.Match.
+func (l *LocalCache) deletePattern(pattern string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for key, el := range l.items {
+		if ok, _ := path.Match(pattern, key); ok {
+			l.remove(el)
+		}
+	}
+}
+
+func (l *LocalCache) clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.order.Init()
+	clear(l.items)
+}
+
+// remove unlinks el; the caller holds mu
+func (l *LocalCache) remove(el *list.Element) {
+	l.order.Remove(el)
+	delete(l.items, el.Value.(*localItem).key)
+}
+
+// UseLocal puts local in front of Redis and evicts from it whatever any
+// replica deletes, until ctx is done. Deletions arrive over Redis pub/sub,
+// which does not queue messages for a disconnected subscriber, so the
+// local cache is cleared whenever the subscription is (re)established.
+func (c *RedisCache) UseLocal(ctx context.Context, local *LocalCache) {
+	c.local = local
+
+	pubsub := c.client.Subscribe(ctx, invalidationChannel)
+	go func() {
+		defer pubsub.Close()
+
+		messages := pubsub.ChannelWithSubscriptions()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				c.receive(ctx, msg)
+			}
+		}
+	}()
+}
+
+func (c *RedisCache) receive(ctx context.Context, msg interface{}) {
+	switch msg := msg.(type) {
+	case *redis.Subscription:
+		if msg.Kind == "subscribe" {
+			c.local.clear()
+			slog.DebugContext(ctx, "subscribed to cache invalidations, local cache cleared")
+		}
+
+	case *redis.Message:
+		var inv invalidation
+		if err := json.Unmarshal([]byte(msg.Payload), &inv); err != nil {
+			slog.WarnContext(ctx, "invalid cache invalidation, clearing local cache", "error", err)
+			c.local.clear()
+			return
+		}
+		c.evict(inv)
+	}
+}
+
+// evict drops an invalidation's keys from the local cache, if there is one
+func (c *RedisCache) evict(inv invalidation) {
+	if c.local == nil {
+		return
+	}
+	c.local.delete(inv.Keys...)
+	if inv.Pattern != "" {
+		c.local.deletePattern(inv.Pattern)
+	}
+}
+
+// invalidate evicts locally right away and tells the other replicas to do
+// the same. Replicas that miss the message serve stale entries until they
+// expire locally.
+func (c *RedisCache) invalidate(ctx context.Context, inv invalidation) {
+	c.evict(inv)
+
+	data, err := json.Marshal(inv)
+	if err != nil {
+		return
+	}
+	if err := c.client.Publish(ctx, invalidationChannel, data).Err(); err != nil {
+		slog.WarnContext(ctx, "failed to broadcast cache invalidation", "error", err)
+	}
+}
@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func localEntry(v int) entry {
+	data, _ := json.Marshal(v)
+	return entry{Value: data, Expiry: time.Now().Add(time.Hour).UnixMilli()}
+}
+
+func TestLocalCacheEviction(t *testing.T) {
+	local := NewLocalCache(3, time.Hour)
+	for i := 1; i <= 3; i++ {
+		local.set(fmt.Sprintf("k%d", i), localEntry(i))
+	}
+
+	// Touch k1 so k2 is now the least recently used
+	if _, ok := local.get("k1"); !ok {
+		t.Fatal("k1 missing")
+	}
+	local.set("k4", localEntry(4))
+
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"k1", true},
+		{"k2", false},
+		{"k3", true},
+		{"k4", true},
+	}
+	for _, tt := range tests {
+		if _, ok := local.get(tt.key); ok != tt.want {
+			t.Errorf("get(%s) found = %v, want %v", tt.key, ok, tt.want)
+		}
+	}
+
+	// Overwriting refreshes recency without growing the cache
+	local.set("k3", localEntry(33))
+	local.set("k5", localEntry(5))
+	if _, ok := local.get("k1"); ok {
+		t.Error("k1 survived although it was least recently used")
+	}
+	if e, ok := local.get("k3"); !ok || string(e.Value) != "33" {
+		t.Errorf("k3 = %s, %v; want 33", e.Value, ok)
+	}
+	if n := local.order.Len(); n != 3 || len(local.items) != 3 {
+		t.Errorf("cache holds %d/%d items, want 3", n, len(local.items))
+	}
+}
+
+func TestLocalCacheExpiry(t *testing.T) {
+	local := NewLocalCache(10, 20*time.Millisecond)
+
+	local.set("short", localEntry(1))
+	expired := localEntry(2)
+	expired.Expiry = time.Now().Add(-time.Second).UnixMilli()
+	local.set("expired", expired)
+
+	if _, ok := local.get("expired"); ok {
+		t.Error("an entry already expired in Redis was kept")
+	}
+	if _, ok := local.get("short"); !ok {
+		t.Fatal("fresh entry missing")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if _, ok := local.get("short"); ok {
+		t.Error("entry outlived the local TTL")
+	}
+}
+
+func TestLocalCacheDelete(t *testing.T) {
+	local := NewLocalCache(10, time.Hour)
+	for _, key := range []string{"product:1", "product:1:variants", "product:2", "products:list:a", "products:list:b"} {
+		local.set(key, localEntry(1))
+	}
+
+	local.delete("product:2", "unknown")
+	local.deletePattern("products:list:*")
+
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"product:1", true},
+		{"product:1:variants", true},
+		{"product:2", false},
+		{"products:list:a", false},
+		{"products:list:b", false},
+	}
+	for _, tt := range tests {
+		if _, ok := local.get(tt.key); ok != tt.want {
+			t.Errorf("get(%s) found = %v, want %v", tt.key, ok, tt.want)
+		}
+	}
+
+	local.deletePattern("product:*")
+	local.clear()
+	if local.order.Len() != 0 || len(local.items) != 0 {
+		t.Error("clear left items behind")
+	}
+}
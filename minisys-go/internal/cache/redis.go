@@ -4,15 +4,135 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
+	"math/rand/v2"
+	"strconv"
 	"time"
 
+	"github.com/redis/go-redis/extra/redisotel/v9"
 	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// negativeTTL is how long a negative entry lasts, short so that
+	// something created meanwhile shows up soon even if no one
+	// invalidates it
+	negativeTTL = 30 * time.Second
+	// ttlJitter is the fraction a TTL is randomly lengthened or shortened
+	// by
+	ttlJitter = 0.1
+	// earlyRefreshBeta scales how eagerly Fetch refreshes a value before
+	// it expires; 1 is the usual choice, higher refreshes sooner
+	earlyRefreshBeta = 1.0
 )
 
 type RedisCache struct {
 	client *redis.Client
 	ttl    time.Duration
+	loads  singleflight.Group
+	// local, when set, answers reads before Redis; see UseLocal
+	local *LocalCache
+}
+
+// entry is how every value is stored. Missing marks a negative entry,
+// cached for something that does not exist. Delta is how long the value
+// took to load and Expiry when it expires, in Unix milliseconds; Fetch
+// uses both to refresh hot keys early.
+type entry struct {
+	Value   json.RawMessage `json:"v,omitempty"`
+	Missing bool            `json:"m,omitempty"`
+	Delta   int64           `json:"d,omitempty"`
+	Expiry  int64           `json:"e"`
+}
+
+// decodeEntry unwraps a stored entry. Values written before entries
+// existed do not decode and are treated as misses.
+func decodeEntry(data []byte) (entry, bool) {
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil || (e.Value == nil && !e.Missing) {
+		return e, false
+	}
+	return e, true
+}
+
+// result is what MGet returns for the entry
+func (e entry) result() []byte {
+	if e.Missing {
+		return Negative
+	}
+	return e.Value
+}
+
+// encodeEntry wraps value, or marks a negative entry when value is nil,
+// and returns it encoded with the jittered TTL it should be stored with
+func (c *RedisCache) encodeEntry(value interface{}, delta time.Duration) (entry, []byte, time.Duration, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return entry{}, nil, 0, fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	e := entry{Value: data, Delta: delta.Milliseconds()}
+	ttl := c.ttl
+	if string(data) == "null" {
+		e = entry{Missing: true}
+		ttl = negativeTTL
+	}
+	ttl = jitter(ttl)
+	e.Expiry = time.Now().Add(ttl).UnixMilli()
+
+	data, err = json.Marshal(e)
+	if err != nil {
+		return entry{}, nil, 0, fmt.Errorf("failed to marshal value: %w", err)
+	}
+	return e, data, ttl, nil
+}
+
+// read looks key up in the local cache, then in Redis, keeping what Redis
+// returns locally. It reports false, with a nil error, for a key that is
+// not cached.
+func (c *RedisCache) read(ctx context.Context, key string) (entry, bool, error) {
+	if c.local != nil {
+		if e, ok := c.local.get(key); ok {
+			return e, true, nil
+		}
+	}
+
+	val, err := c.client.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return entry{}, false, nil
+	}
+	if err != nil {
+		return entry{}, false, err
+	}
+
+	e, ok := decodeEntry(val)
+	if ok && c.local != nil {
+		c.local.set(key, e)
+	}
+	return e, ok, nil
+}
+
+// write stores an encoded entry in Redis and the local cache
+func (c *RedisCache) write(ctx context.Context, key string, e entry, data []byte, ttl time.Duration) error {
+	if err := c.client.Set(ctx, key, data, ttl).Err(); err != nil {
+		return err
+	}
+
+	if c.local != nil {
+		c.local.set(key, e)
+	}
+	return nil
+}
+
+// jitter spreads a TTL by up to ttlJitter either way, so keys written
+// together do not all expire together
+func jitter(ttl time.Duration) time.Duration {
+	spread := int64(float64(ttl) * ttlJitter)
+	if spread <= 0 {
+		return ttl
+	}
+	return ttl + time.Duration(rand.Int64N(2*spread+1)-spread)
 }
 
 func NewRedisCache(host string, port int, ttl time.Duration) (*RedisCache, error) {
@@ -20,13 +140,17 @@ func NewRedisCache(host string, port int, ttl time.Duration) (*RedisCache, error
 		Addr: fmt.Sprintf("%s:%d", host, port),
 	})
 
+	if err := redisotel.InstrumentTracing(client); err != nil {
+		return nil, fmt.Errorf("failed to instrument Redis: %w", err)
+	}
+
 	// Test connection
 	ctx := context.Background()
 	if err := client.Ping(ctx).Err(); err != nil {
 		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
-	log.Println("✅ Connected to Redis")
+	slog.Info("connected to Redis", "addr", client.Options().Addr)
 
 	return &RedisCache{
 		client: client,
@@ -34,35 +158,180 @@ func NewRedisCache(host string, port int, ttl time.Duration) (*RedisCache, error
 	}, nil
 }
 
-// Get retrieves value from cache
+// Get retrieves value from cache. It returns redis.Nil if the key does not
+// exist or holds a negative entry.
 func (c *RedisCache) Get(ctx context.Context, key string, dest interface{}) error {
-	val, err := c.client.Get(ctx, key).Result()
+	e, ok, err := c.read(ctx, key)
 	if err != nil {
-		return err // Returns redis.Nil if key doesn't exist
+		return err
 	}
-
-	return json.Unmarshal([]byte(val), dest)
+	if !ok || e.Missing {
+		return redis.Nil
+	}
+	return json.Unmarshal(e.Value, dest)
 }
 
-// Set stores value in cache
+// Set stores value in cache; a nil value is stored as a negative entry
 func (c *RedisCache) Set(ctx context.Context, key string, value interface{}) error {
-	data, err := json.Marshal(value)
+	e, data, ttl, err := c.encodeEntry(value, 0)
 	if err != nil {
-		return fmt.Errorf("failed to marshal value: %w", err)
+		return err
 	}
 
-	return c.client.Set(ctx, key, data, c.ttl).Err()
+	return c.write(ctx, key, e, data, ttl)
+}
+
+// Negative is what MGet returns for a key holding a negative entry: the
+// JSON null it was stored from
+var Negative = []byte("null")
+
+// IsNegative reports whether an MGet result is a negative entry, cached
+// for something known not to exist
+func IsNegative(value []byte) bool {
+	return string(value) == string(Negative)
 }
 
-// Delete removes key from cache
+// MGet fetches several keys, those not cached locally in one round trip.
+// The result has one entry per key: nil where the key is not cached and
+// Negative where it holds a negative entry.
+func (c *RedisCache) MGet(ctx context.Context, keys []string) ([][]byte, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	results := make([][]byte, len(keys))
+	var remote []string
+	var positions []int
+	for i, key := range keys {
+		if c.local != nil {
+			if e, ok := c.local.get(key); ok {
+				results[i] = e.result()
+				continue
+			}
+		}
+		remote = append(remote, key)
+		positions = append(positions, i)
+	}
+	if len(remote) == 0 {
+		return results, nil
+	}
+
+	vals, err := c.client.MGet(ctx, remote...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	for j, val := range vals {
+		s, ok := val.(string)
+		if !ok {
+			continue
+		}
+		e, ok := decodeEntry([]byte(s))
+		if !ok {
+			continue
+		}
+		if c.local != nil {
+			c.local.set(remote[j], e)
+		}
+		results[positions[j]] = e.result()
+	}
+
+	return results, nil
+}
+
+// SetMany stores several values in one pipelined round trip
+func (c *RedisCache) SetMany(ctx context.Context, values map[string]interface{}) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	pipe := c.client.Pipeline()
+	entries := make(map[string]entry, len(values))
+	for key, value := range values {
+		e, data, ttl, err := c.encodeEntry(value, 0)
+		if err != nil {
+			return err
+		}
+		pipe.Set(ctx, key, data, ttl)
+		entries[key] = e
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+	if c.local != nil {
+		for key, e := range entries {
+			c.local.set(key, e)
+		}
+	}
+	return nil
+}
+
+// Versions reads the counters at keys, in one round trip; a counter never
+// bumped reads as 0. Building keys from a version lets a whole family of
+// entries be dropped by bumping it, without finding them first.
+func (c *RedisCache) Versions(ctx context.Context, keys ...string) ([]int64, error) {
+	vals, err := c.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]int64, len(keys))
+	for i, val := range vals {
+		s, ok := val.(string)
+		if !ok {
+			continue
+		}
+		if versions[i], err = strconv.ParseInt(s, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid version at %s: %w", keys[i], err)
+		}
+	}
+	return versions, nil
+}
+
+// Bump increments the version counters at keys, abandoning every entry
+// keyed on their old values; those expire on their own
+func (c *RedisCache) Bump(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	pipe := c.client.Pipeline()
+	for _, key := range keys {
+		pipe.Incr(ctx, key)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Delete removes key from cache, on every replica
 func (c *RedisCache) Delete(ctx context.Context, key string) error {
-	return c.client.Del(ctx, key).Err()
+	return c.DeleteMany(ctx, []string{key})
 }
 
-// DeleteByPattern removes all keys matching pattern
+// DeleteMany removes several keys in one round trip, on every replica
+func (c *RedisCache) DeleteMany(ctx context.Context, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	defer c.invalidate(ctx, invalidation{Keys: keys})
+
+	return c.client.Del(ctx, keys...).Err()
+}
+
+// DeleteByPattern removes all keys matching pattern, on every replica. It
+// walks the keyspace with SCAN so large databases are not blocked the way
+// KEYS would.
 func (c *RedisCache) DeleteByPattern(ctx context.Context, pattern string) error {
-	keys, err := c.client.Keys(ctx, pattern).Result()
-	if err != nil {
+	defer c.invalidate(ctx, invalidation{Pattern: pattern})
+
+	iter := c.client.Scan(ctx, 0, pattern, 100).Iterator()
+
+	var keys []string
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
 		return err
 	}
 
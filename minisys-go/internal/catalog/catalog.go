@@ -0,0 +1,313 @@
+package catalog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/models"
+)
+
+type Format string
+
+const (
+	// CSV has a header row naming the columns; see Columns
+	CSV Format = "csv"
+	// NDJSON has one JSON-encoded models.CatalogRow per line
+	NDJSON Format = "ndjson"
+)
+
+// Columns are the CSV columns, in the order they are exported. sku, name
+// and price are required on import; tags are comma-separated and
+// attributes are a JSON object.
+var Columns = []string{"sku", "name", "price", "currency", "quantity", "category_id", "tags", "attributes"}
+
+var requiredColumns = []string{"sku", "name", "price"}
+
+// maxLineLength caps one NDJSON line
+const maxLineLength = 1 << 20
+
+// ParseFormat validates a format name
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(strings.ToLower(name)); f {
+	case CSV, NDJSON:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown catalog format %q (want csv or ndjson)", name)
+	}
+}
+
+// ContentType is the media type of a file in format f
+func (f Format) ContentType() string {
+	if f == CSV {
+		return "text/csv"
+	}
+	return "application/x-ndjson"
+}
+
+// RowError is a row that could not be decoded. Reading can carry on with
+// the next row.
+type RowError struct {
+	Line int
+	Err  error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// Reader decodes catalog rows one at a time. Read returns io.EOF after the
+// last row and a *RowError for a row that is malformed; any other error
+// means the input cannot be read further. Line is the line the last row
+// started on.
+type Reader interface {
+	Read() (models.CatalogRow, error)
+	Line() int
+}
+
+// NewReader returns a Reader for format f. A CSV header is read and
+// checked straight away.
+func NewReader(r io.Reader, f Format) (Reader, error) {
+	if f == NDJSON {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxLineLength)
+		return &ndjsonReader{scanner: scanner}, nil
+	}
+
+	cr := csv.NewReader(r)
+	cr.ReuseRecord = true
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("csv header is missing")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !known(name) {
+			return nil, fmt.Errorf("unknown csv column %q", name)
+		}
+		if _, dup := index[name]; dup {
+			return nil, fmt.Errorf("duplicate csv column %q", name)
+		}
+		index[name] = i
+	}
+	for _, name := range requiredColumns {
+		if _, ok := index[name]; !ok {
+			return nil, fmt.Errorf("csv column %q is required", name)
+		}
+	}
+	cr.FieldsPerRecord = len(header)
+
+	return &csvReader{reader: cr, index: index}, nil
+}
+
+func known(column string) bool {
+	for _, c := range Columns {
+		if c == column {
+			return true
+		}
+	}
+	return false
+}
+
+type csvReader struct {
+	reader *csv.Reader
+	index  map[string]int
+	line   int
+}
+
+func (r *csvReader) Line() int {
+	return r.line
+}
+
+func (r *csvReader) Read() (models.CatalogRow, error) {
+	record, err := r.reader.Read()
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		r.line = parseErr.StartLine
+		return models.CatalogRow{}, &RowError{Line: r.line, Err: parseErr.Err}
+	}
+	if err != nil {
+		return models.CatalogRow{}, err
+	}
+	r.line, _ = r.reader.FieldPos(0)
+
+	row, err := r.decode(record)
+	if err != nil {
+		return models.CatalogRow{}, &RowError{Line: r.line, Err: err}
+	}
+	return row, nil
+}
+
+// field returns a column's trimmed value, "" if the file has no such column
+func (r *csvReader) field(record []string, column string) string {
+	i, ok := r.index[column]
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+func (r *csvReader) decode(record []string) (models.CatalogRow, error) {
+	row := models.CatalogRow{
+		SKU:  r.field(record, "sku"),
+		Name: r.field(record, "name"),
+	}
+
+	price, err := models.ParseMoney(r.field(record, "price"), strings.ToUpper(r.field(record, "currency")))
+	if err != nil {
+		return row, fmt.Errorf("invalid price: %w", err)
+	}
+	row.Price = price
+
+	if raw := r.field(record, "quantity"); raw != "" {
+		quantity, err := strconv.Atoi(raw)
+		if err != nil {
+			return row, fmt.Errorf("invalid quantity %q", raw)
+		}
+		row.Quantity = &quantity
+	}
+	if raw := r.field(record, "category_id"); raw != "" {
+		categoryID, err := strconv.Atoi(raw)
+		if err != nil {
+			return row, fmt.Errorf("invalid category_id %q", raw)
+		}
+		row.CategoryID = &categoryID
+	}
+	if raw := r.field(record, "tags"); raw != "" {
+		row.Tags = strings.Split(raw, ",")
+	}
+	if raw := r.field(record, "attributes"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &row.Attributes); err != nil {
+			return row, fmt.Errorf("attributes must be a JSON object: %w", err)
+		}
+	}
+
+	return row, nil
+}
+
+type ndjsonReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func (r *ndjsonReader) Line() int {
+	return r.line
+}
+
+func (r *ndjsonReader) Read() (models.CatalogRow, error) {
+	for r.scanner.Scan() {
+		r.line++
+		data := bytes.TrimSpace(r.scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		var row models.CatalogRow
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&row); err != nil {
+			return models.CatalogRow{}, &RowError{Line: r.line, Err: err}
+		}
+		return row, nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return models.CatalogRow{}, err
+	}
+	return models.CatalogRow{}, io.EOF
+}
+
+// Writer encodes catalog rows. Call Flush once done, and whenever rows
+// written so far should reach the underlying writer.
+type Writer interface {
+	Write(row models.CatalogRow) error
+	Flush() error
+}
+
+// NewWriter returns a Writer for format f. A CSV header is written before
+// the first row.
+func NewWriter(w io.Writer, f Format) Writer {
+	if f == NDJSON {
+		buffered := bufio.NewWriter(w)
+		return &ndjsonWriter{buffered: buffered, encoder: json.NewEncoder(buffered)}
+	}
+	return &csvWriter{writer: csv.NewWriter(w)}
+}
+
+type csvWriter struct {
+	writer *csv.Writer
+	header bool
+}
+
+// writeHeader writes the header once, so even an empty export has one
+func (w *csvWriter) writeHeader() error {
+	if w.header {
+		return nil
+	}
+	w.header = true
+	return w.writer.Write(Columns)
+}
+
+func (w *csvWriter) Write(row models.CatalogRow) error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+
+	var quantity, categoryID, attributes string
+	if row.Quantity != nil {
+		quantity = strconv.Itoa(*row.Quantity)
+	}
+	if row.CategoryID != nil {
+		categoryID = strconv.Itoa(*row.CategoryID)
+	}
+	if len(row.Attributes) > 0 {
+		data, err := json.Marshal(row.Attributes)
+		if err != nil {
+			return err
+		}
+		attributes = string(data)
+	}
+
+	return w.writer.Write([]string{
+		row.SKU, row.Name, row.Price.Decimal(), row.Price.Currency,
+		quantity, categoryID, strings.Join(row.Tags, ","), attributes,
+	})
+}
+
+func (w *csvWriter) Flush() error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+type ndjsonWriter struct {
+	buffered *bufio.Writer
+	encoder  *json.Encoder
+}
+
+func (w *ndjsonWriter) Write(row models.CatalogRow) error {
+	return w.encoder.Encode(row)
+}
+
+func (w *ndjsonWriter) Flush() error {
+	return w.buffered.Flush()
+}
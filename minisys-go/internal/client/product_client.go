@@ -1,14 +1,24 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/models"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/requestid"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
+// batchSize matches the product-service limit on /variants lookups
+const batchSize = 100
+
 type ProductClient struct {
 	baseURL    string
 	httpClient *http.Client
@@ -18,33 +28,108 @@ func NewProductClient(baseURL string) *ProductClient {
 	return &ProductClient{
 		baseURL: baseURL,
 		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout:   10 * time.Second,
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
 		},
 	}
 }
 
-// GetProduct fetches a product from Product Service
-func (c *ProductClient) GetProduct(productID int) (*models.Product, error) {
-	url := fmt.Sprintf("%s/products/%d", c.baseURL, productID)
+// GetProductAt fetches a product priced as it was at at. The product is
+// nil if it did not exist, or had no price, at that time.
+func (c *ProductClient) GetProductAt(ctx context.Context, productID int, at time.Time) (*models.Product, error) {
+	u := fmt.Sprintf("%s/products/%d?at=%s", c.baseURL, productID, url.QueryEscape(at.UTC().Format(time.RFC3339Nano)))
 
-	resp, err := c.httpClient.Get(url)
+	req, err := newRequest(ctx, u)
 	if err != nil {
-		return nil, fmt.Errorf("failed to call product service: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
+	slog.DebugContext(ctx, "fetching product price from product-service", "product_id", productID, "at", at)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call product service: %w", err)
+	}
 	if resp.StatusCode == http.StatusNotFound {
-		return nil, fmt.Errorf("product %d not found", productID)
+		resp.Body.Close()
+		return nil, nil
+	}
+
+	var product models.Product
+	if err := decodeResponse(resp, &product); err != nil {
+		return nil, err
 	}
 
+	return &product, nil
+}
+
+// GetVariants resolves SKUs, and product IDs for clients that predate
+// SKUs, to variants. A product ID resolves to the product's default
+// variant. Unknown SKUs and products are absent from the result.
+func (c *ProductClient) GetVariants(ctx context.Context, skus []string, productIDs []int) ([]models.Variant, error) {
+	var variants []models.Variant
+
+	for start := 0; start < len(skus)+len(productIDs); start += batchSize {
+		end := min(start+batchSize, len(skus)+len(productIDs))
+
+		params := url.Values{}
+		var batchSKUs, batchIDs []string
+		for i := start; i < end; i++ {
+			if i < len(skus) {
+				batchSKUs = append(batchSKUs, skus[i])
+			} else {
+				batchIDs = append(batchIDs, strconv.Itoa(productIDs[i-len(skus)]))
+			}
+		}
+		if len(batchSKUs) > 0 {
+			params.Set("skus", strings.Join(batchSKUs, ","))
+		}
+		if len(batchIDs) > 0 {
+			params.Set("product_ids", strings.Join(batchIDs, ","))
+		}
+
+		req, err := newRequest(ctx, c.baseURL+"/variants?"+params.Encode())
+		if err != nil {
+			return nil, err
+		}
+
+		slog.DebugContext(ctx, "fetching variants from product-service", "count", end-start)
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to call product service: %w", err)
+		}
+
+		var batch []models.Variant
+		if err := decodeResponse(resp, &batch); err != nil {
+			return nil, err
+		}
+		variants = append(variants, batch...)
+	}
+
+	return variants, nil
+}
+
+func newRequest(ctx context.Context, url string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	if id := requestid.FromContext(ctx); id != "" {
+		req.Header.Set(requestid.Header, id)
+	}
+
+	return req, nil
+}
+
+func decodeResponse(resp *http.Response, dest interface{}) error {
+	defer resp.Body.Close()
+
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("product service returned status %d", resp.StatusCode)
+		return fmt.Errorf("product service returned status %d", resp.StatusCode)
 	}
 
-	var product models.Product
-	if err := json.NewDecoder(resp.Body).Decode(&product); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if err := json.NewDecoder(resp.Body).Decode(dest); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	return &product, nil
+	return nil
 }
@@ -0,0 +1,189 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	// PostgreSQL
+	PostgresHost     string
+	PostgresPort     int
+	PostgresUser     string
+	PostgresPassword string
+	PostgresDB       string
+	MigrateOnStart   bool
+
+	// Redis
+	RedisHost string
+	RedisPort int
+	// LocalCacheSize caps the in-process cache in front of Redis; zero
+	// disables it
+	LocalCacheSize int
+	LocalCacheTTL  time.Duration
+
+	// RabbitMQ
+	RabbitMQHost     string
+	RabbitMQPort     int
+	RabbitMQUser     string
+	RabbitMQPassword string
+
+	// Consul
+	ConsulHost string
+	ConsulPort int
+
+	// Inventory
+	AllocationStrategy string
+	ReconcileInterval  time.Duration
+	ReservationTTL     time.Duration
+	MaxReservations    int
+	SweepInterval      time.Duration
+	AlertInterval      time.Duration
+	AllocationInterval time.Duration
+
+	// Pricing
+	PriceInterval time.Duration
+
+	// Logging
+	LogLevel string
+
+	// Tracing
+	TraceExporter    string
+	TraceEndpoint    string
+	TraceFile        string
+	TraceSampleRatio float64
+
+	// API Gateway
+	RateLimitConfig string
+	AuthPolicy      string
+	JWTSecret       string
+	JWKSSource      string
+	JWTIssuer       string
+	JWTAudience     string
+	APIKeysFile     string
+	TrustedProxies  []string
+}
+
+// Load reads configuration from environment variables with local defaults
+func Load() *Config {
+	return &Config{
+		PostgresHost:     getEnv("POSTGRES_HOST", "localhost"),
+		PostgresPort:     getEnvInt("POSTGRES_PORT", 5432),
+		PostgresUser:     getEnv("POSTGRES_USER", "minisys"),
+		PostgresPassword: getEnv("POSTGRES_PASSWORD", "minisys123"),
+		PostgresDB:       getEnv("POSTGRES_DB", "minisys"),
+		MigrateOnStart:   getEnvBool("MIGRATE_ON_START", false),
+
+		RedisHost: getEnv("REDIS_HOST", "localhost"),
+		RedisPort: getEnvInt("REDIS_PORT", 6379),
+
+		LocalCacheSize: getEnvInt("LOCAL_CACHE_SIZE", 10000),
+		LocalCacheTTL:  getEnvDuration("LOCAL_CACHE_TTL", 30*time.Second),
+
+		RabbitMQHost:     getEnv("RABBITMQ_HOST", "localhost"),
+		RabbitMQPort:     getEnvInt("RABBITMQ_PORT", 5672),
+		RabbitMQUser:     getEnv("RABBITMQ_USER", "guest"),
+		RabbitMQPassword: getEnv("RABBITMQ_PASSWORD", "guest"),
+
+		ConsulHost: getEnv("CONSUL_HOST", "localhost"),
+		ConsulPort: getEnvInt("CONSUL_PORT", 8500),
+
+		AllocationStrategy: getEnv("ALLOCATION_STRATEGY", "nearest"),
+		ReconcileInterval:  getEnvDuration("RECONCILE_INTERVAL", 15*time.Minute),
+		ReservationTTL:     getEnvDuration("RESERVATION_TTL", 15*time.Minute),
+		MaxReservations:    getEnvInt("MAX_ACTIVE_RESERVATIONS", 3),
+		SweepInterval:      getEnvDuration("RESERVATION_SWEEP_INTERVAL", time.Minute),
+		AlertInterval:      getEnvDuration("STOCK_ALERT_INTERVAL", 10*time.Second),
+		AllocationInterval: getEnvDuration("ALLOCATION_PUBLISH_INTERVAL", 10*time.Second),
+
+		PriceInterval: getEnvDuration("PRICE_SCHEDULER_INTERVAL", 10*time.Second),
+
+		LogLevel: getEnv("LOG_LEVEL", "info"),
+
+		TraceExporter:    getEnv("TRACE_EXPORTER", "none"),
+		TraceEndpoint:    getEnv("TRACE_OTLP_ENDPOINT", ""),
+		TraceFile:        getEnv("TRACE_FILE", "traces.json"),
+		TraceSampleRatio: getEnvFloat("TRACE_SAMPLE_RATIO", 1),
+
+		RateLimitConfig: getEnv("RATE_LIMIT_CONFIG", ""),
+		AuthPolicy:      getEnv("AUTH_POLICY", ""),
+		JWTSecret:       getEnv("JWT_HMAC_SECRET", ""),
+		JWKSSource:      getEnv("JWT_JWKS", ""),
+		JWTIssuer:       getEnv("JWT_ISSUER", ""),
+		JWTAudience:     getEnv("JWT_AUDIENCE", ""),
+		APIKeysFile:     getEnv("API_KEYS_FILE", ""),
+		TrustedProxies:  getEnvList("TRUSTED_PROXIES"),
+	}
+}
+
+func getEnv(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return fallback
+}
+
+// getEnvList splits a comma-separated variable, returning nil if unset
+func getEnvList(key string) []string {
+	var out []string
+	for _, part := range strings.Split(os.Getenv(key), ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+func getEnvInt(key string, fallback int) int {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return fallback
+	}
+	return n
+}
+
+func getEnvFloat(key string, fallback float64) float64 {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fallback
+	}
+	return f
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return fallback
+	}
+	return b
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return fallback
+	}
+	return d
+}
@@ -0,0 +1,96 @@
+package consumer
+
+import (
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/db"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/metrics"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/models"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/tracing"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// AllocationConsumer records on each order which warehouses fulfil it, or
+// that none could
+type AllocationConsumer struct {
+	repo *db.OrderRepository
+}
+
+func NewAllocationConsumer(repo *db.OrderRepository) *AllocationConsumer {
+	return &AllocationConsumer{repo: repo}
+}
+
+// ProcessInventoryAllocated handles inventory.allocated events
+func (c *AllocationConsumer) ProcessInventoryAllocated(messages <-chan amqp.Delivery) {
+	for msg := range messages {
+		started := time.Now()
+		ctx, span := tracing.StartConsume(messageContext(msg), "inventory.allocated", msg)
+
+		var event models.InventoryAllocatedEvent
+		if err := json.Unmarshal(msg.Body, &event); err != nil {
+			slog.ErrorContext(ctx, "failed to parse inventory.allocated event", "error", err)
+			tracing.RecordError(span, err)
+			span.End()
+			msg.Nack(false, false)
+			metrics.RecordConsumed("inventory.allocated", metrics.OutcomeRejected, started)
+			continue
+		}
+
+		found, err := c.repo.SetAllocations(ctx, event.OrderID, event.Allocations)
+		switch {
+		case err != nil:
+			slog.WarnContext(ctx, "failed to record allocations, requeued", "order_id", event.OrderID, "error", err)
+			tracing.RecordError(span, err)
+			msg.Nack(false, true)
+			metrics.RecordConsumed("inventory.allocated", metrics.OutcomeRequeued, started)
+		case !found:
+			slog.ErrorContext(ctx, "allocations for unknown order", "order_id", event.OrderID)
+			msg.Nack(false, false)
+			metrics.RecordConsumed("inventory.allocated", metrics.OutcomeRejected, started)
+		default:
+			slog.InfoContext(ctx, "order allocated", "order_id", event.OrderID, "strategy", event.Strategy, "allocations", len(event.Allocations))
+			msg.Ack(false)
+			metrics.RecordConsumed("inventory.allocated", metrics.OutcomeAcked, started)
+		}
+		span.End()
+	}
+}
+
+// ProcessAllocationFailed handles order.allocation_failed events by
+// failing the order
+func (c *AllocationConsumer) ProcessAllocationFailed(messages <-chan amqp.Delivery) {
+	for msg := range messages {
+		started := time.Now()
+		ctx, span := tracing.StartConsume(messageContext(msg), "order.allocation_failed", msg)
+
+		var event models.OrderAllocationFailedEvent
+		if err := json.Unmarshal(msg.Body, &event); err != nil {
+			slog.ErrorContext(ctx, "failed to parse order.allocation_failed event", "error", err)
+			tracing.RecordError(span, err)
+			span.End()
+			msg.Nack(false, false)
+			metrics.RecordConsumed("order.allocation_failed", metrics.OutcomeRejected, started)
+			continue
+		}
+
+		found, err := c.repo.MarkFailed(ctx, event.OrderID)
+		switch {
+		case err != nil:
+			slog.WarnContext(ctx, "failed to fail order, requeued", "order_id", event.OrderID, "error", err)
+			tracing.RecordError(span, err)
+			msg.Nack(false, true)
+			metrics.RecordConsumed("order.allocation_failed", metrics.OutcomeRequeued, started)
+		case !found:
+			slog.ErrorContext(ctx, "allocation failure for unknown order", "order_id", event.OrderID)
+			msg.Nack(false, false)
+			metrics.RecordConsumed("order.allocation_failed", metrics.OutcomeRejected, started)
+		default:
+			slog.InfoContext(ctx, "order failed", "order_id", event.OrderID, "reason", event.Reason)
+			msg.Ack(false)
+			metrics.RecordConsumed("order.allocation_failed", metrics.OutcomeAcked, started)
+		}
+		span.End()
+	}
+}
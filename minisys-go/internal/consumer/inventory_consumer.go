@@ -3,66 +3,198 @@ package consumer
 import (
 	"context"
 	"encoding/json"
-	"fmt"
-	"log"
+	"errors"
+	"log/slog"
+	"time"
 
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/allocation"
 	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/cache"
 	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/db"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/inventory"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/metrics"
 	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/models"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/publisher"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/requestid"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/tracing"
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
 type InventoryConsumer struct {
-	repo  *db.ProductRepository
-	cache *cache.RedisCache
+	warehouses   *db.WarehouseRepository
+	reservations *db.ReservationRepository
+	publisher    *publisher.InventoryPublisher
+	relay        *inventory.AllocationRelay
+	strategy     allocation.Strategy
+	cache        *cache.RedisCache
 }
 
-func NewInventoryConsumer(repo *db.ProductRepository, cache *cache.RedisCache) *InventoryConsumer {
+// NewInventoryConsumer allocates orders with strategy unless an order
+// names its own, and sends the allocations queued meanwhile through relay
+func NewInventoryConsumer(warehouses *db.WarehouseRepository, reservations *db.ReservationRepository, pub *publisher.InventoryPublisher, relay *inventory.AllocationRelay, strategy allocation.Strategy, cache *cache.RedisCache) *InventoryConsumer {
 	return &InventoryConsumer{
-		repo:  repo,
-		cache: cache,
+		warehouses:   warehouses,
+		reservations: reservations,
+		publisher:    pub,
+		relay:        relay,
+		strategy:     strategy,
+		cache:        cache,
 	}
 }
 
-// ProcessOrderCreated handles order.created events
+// ProcessOrderCreated handles order.created events by allocating each
+// item to warehouses and publishing where it ships from
 func (c *InventoryConsumer) ProcessOrderCreated(messages <-chan amqp.Delivery) {
 	for msg := range messages {
-		log.Printf("📥 Received order.created event")
+		started := time.Now()
+		ctx, span := tracing.StartConsume(messageContext(msg), "order.created", msg)
+		slog.DebugContext(ctx, "received order.created event")
 
 		var event models.OrderCreatedEvent
 		if err := json.Unmarshal(msg.Body, &event); err != nil {
-			log.Printf("❌ Failed to parse event: %v", err)
+			slog.ErrorContext(ctx, "failed to parse order.created event", "error", err)
+			tracing.RecordError(span, err)
+			span.End()
 			msg.Nack(false, false)
+			metrics.RecordConsumed("order.created", metrics.OutcomeRejected, started)
 			continue
 		}
 
-		log.Printf("📦 Processing Order #%d for %s", event.OrderID, event.CustomerName)
+		slog.InfoContext(ctx, "processing order", "order_id", event.OrderID, "items", len(event.Items))
 
-		success := true
-		ctx := context.Background()
-
-		for _, item := range event.Items {
-			err := c.repo.UpdateQuantity(item.ProductID, -item.Quantity)
-			if err != nil {
-				log.Printf("❌ Failed to update inventory for product %d: %v", item.ProductID, err)
-				success = false
+		strategy := c.strategy
+		if event.AllocationStrategy != "" {
+			if s, err := allocation.ParseStrategy(event.AllocationStrategy); err == nil {
+				strategy = s
 			} else {
-				log.Printf("✅ Reduced inventory: Product %d by %d", item.ProductID, item.Quantity)
-
-				// Invalidate cache
-				productKey := fmt.Sprintf("product:%d", item.ProductID)
-				c.cache.Delete(ctx, productKey)
-				c.cache.Delete(ctx, "products:all")
-				log.Printf("🗑️ Cache invalidated: %s", productKey)
+				slog.WarnContext(ctx, "ignoring order allocation strategy", "order_id", event.OrderID, "error", err)
 			}
 		}
 
-		if success {
+		allocations, err := c.allocate(ctx, event, strategy)
+		switch {
+		case err == nil:
+			c.allocated(ctx, event.OrderID, allocations)
+			msg.Ack(false)
+			metrics.RecordConsumed("order.created", metrics.OutcomeAcked, started)
+			slog.InfoContext(ctx, "order processed", "order_id", event.OrderID, "strategy", strategy, "allocations", len(allocations))
+		case errors.Is(err, db.ErrOrderCancelled):
+			// The cancellation was handled first; there is nothing to ship
+			slog.InfoContext(ctx, "skipped allocation of cancelled order", "order_id", event.OrderID)
 			msg.Ack(false)
-			log.Printf("✅ Order #%d processed successfully", event.OrderID)
-		} else {
+			metrics.RecordConsumed("order.created", metrics.OutcomeAcked, started)
+		case errors.Is(err, allocation.ErrUnfulfillable), errors.Is(err, db.ErrInsufficientStock):
+			// Retrying will not conjure up stock, so the order is failed
+			slog.ErrorContext(ctx, "failed to allocate order", "order_id", event.OrderID, "strategy", strategy, "error", err)
+			tracing.RecordError(span, err)
+			failed := models.OrderAllocationFailedEvent{
+				OrderID:  event.OrderID,
+				Reason:   err.Error(),
+				FailedAt: time.Now().UTC(),
+			}
+			if err := c.publisher.PublishAllocationFailed(ctx, failed); err != nil {
+				// Try again rather than leave the order pending for good
+				slog.WarnContext(ctx, "failed to publish order.allocation_failed event, requeued", "order_id", event.OrderID, "error", err)
+				msg.Nack(false, true)
+				metrics.RecordConsumed("order.created", metrics.OutcomeRequeued, started)
+				break
+			}
+			msg.Nack(false, false)
+			metrics.RecordConsumed("order.created", metrics.OutcomeRejected, started)
+		default:
+			slog.WarnContext(ctx, "failed to allocate order, requeued", "order_id", event.OrderID, "error", err)
+			tracing.RecordError(span, err)
+			msg.Nack(false, true)
+			metrics.RecordConsumed("order.created", metrics.OutcomeRequeued, started)
+		}
+		span.End()
+	}
+}
+
+// allocate takes the order's stock, drawing on its reservation when it
+// has one that still holds stock
+func (c *InventoryConsumer) allocate(ctx context.Context, event models.OrderCreatedEvent, strategy allocation.Strategy) ([]models.Allocation, error) {
+	if event.ReservationID != 0 {
+		reservation, allocations, err := c.reservations.Confirm(ctx, event.ReservationID, event.OrderID, event.CustomerID, event.Items, strategy, event.ShipTo)
+		switch {
+		case err == nil && reservation != nil:
+			return allocations, nil
+		case err == nil, errors.Is(err, db.ErrReservationClosed), errors.Is(err, db.ErrReservationNotOwned):
+			// Lapsed, unknown or someone else's; the order competes for
+			// stock like any other and the hold stays with its owner
+			slog.WarnContext(ctx, "order reservation not usable", "order_id", event.OrderID, "reservation_id", event.ReservationID, "error", err)
+		default:
+			return nil, err
+		}
+	}
+
+	return c.warehouses.Allocate(ctx, event.OrderID, event.Items, strategy, event.ShipTo)
+}
+
+// ProcessOrderCancelled handles order.cancelled events by returning the
+// order's allocated stock to the warehouses it came from
+func (c *InventoryConsumer) ProcessOrderCancelled(messages <-chan amqp.Delivery) {
+	for msg := range messages {
+		started := time.Now()
+		ctx, span := tracing.StartConsume(messageContext(msg), "order.cancelled", msg)
+		slog.DebugContext(ctx, "received order.cancelled event")
+
+		var event models.OrderCancelledEvent
+		if err := json.Unmarshal(msg.Body, &event); err != nil {
+			slog.ErrorContext(ctx, "failed to parse order.cancelled event", "error", err)
+			tracing.RecordError(span, err)
+			span.End()
+			msg.Nack(false, false)
+			metrics.RecordConsumed("order.cancelled", metrics.OutcomeRejected, started)
+			continue
+		}
+
+		released, err := c.warehouses.Release(ctx, event.OrderID)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to release order stock, requeued", "order_id", event.OrderID, "error", err)
+			tracing.RecordError(span, err)
+			span.End()
 			msg.Nack(false, true)
-			log.Printf("⚠️ Order #%d partially failed, requeued", event.OrderID)
+			metrics.RecordConsumed("order.cancelled", metrics.OutcomeRequeued, started)
+			continue
+		}
+
+		seen := make(map[int]bool)
+		for _, a := range released {
+			slog.InfoContext(ctx, "restored inventory", "order_id", event.OrderID, "product_id", a.ProductID, "sku", a.SKU, "warehouse", a.WarehouseCode, "quantity", a.Quantity)
+			if !seen[a.ProductID] {
+				seen[a.ProductID] = true
+				db.InvalidateProduct(ctx, c.cache, a.ProductID)
+			}
 		}
+
+		msg.Ack(false)
+		metrics.RecordConsumed("order.cancelled", metrics.OutcomeAcked, started)
+		span.End()
+	}
+}
+
+// allocated invalidates the affected products and tells order-service
+// where each item ships from. The event was queued with the allocation,
+// so one that cannot be sent now is retried by the relay.
+func (c *InventoryConsumer) allocated(ctx context.Context, orderID int, allocations []models.Allocation) {
+	seen := make(map[int]bool)
+	for _, a := range allocations {
+		slog.InfoContext(ctx, "reduced inventory", "order_id", orderID, "product_id", a.ProductID, "sku", a.SKU, "warehouse", a.WarehouseCode, "quantity", a.Quantity)
+		if !seen[a.ProductID] {
+			seen[a.ProductID] = true
+			db.InvalidateProduct(ctx, c.cache, a.ProductID)
+		}
+	}
+
+	c.relay.Publish(ctx)
+}
+
+// messageContext restores the request ID the publisher attached, or starts
+// a new one so the consumer's own log lines still correlate
+func messageContext(msg amqp.Delivery) context.Context {
+	id, _ := msg.Headers[requestid.Header].(string)
+	if !requestid.Valid(id) {
+		id = requestid.New()
 	}
+	return requestid.NewContext(context.Background(), id)
 }
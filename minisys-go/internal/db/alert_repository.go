@@ -0,0 +1,219 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/models"
+)
+
+// alertColumns lists the columns scanAlert expects, in order; they are
+// selected from alertFrom
+const alertColumns = `id, product_id, product_name, kind, quantity, threshold,
+	draft_id, draft_quantity, draft_created_at, created_at, resolved_at`
+
+// alertFrom joins each alert with its product and draft. Keyset pagination
+// expects an unqualified id column, hence the subquery.
+const alertFrom = ` FROM (
+		SELECT a.id, a.product_id, p.name AS product_name, a.kind, a.quantity, a.threshold,
+			d.id AS draft_id, d.quantity AS draft_quantity, d.created_at AS draft_created_at,
+			a.created_at, a.resolved_at, a.published_at
+		FROM stock_alerts a
+		JOIN products p ON p.id = a.product_id
+		LEFT JOIN purchase_order_drafts d ON d.alert_id = a.id
+	) a`
+
+func scanAlert(row rowScanner) (models.StockAlert, error) {
+	var a models.StockAlert
+	var draftID, draftQuantity sql.NullInt64
+	var draftCreatedAt, resolvedAt sql.NullTime
+	err := row.Scan(&a.ID, &a.ProductID, &a.ProductName, &a.Kind, &a.Quantity, &a.Threshold,
+		&draftID, &draftQuantity, &draftCreatedAt, &a.CreatedAt, &resolvedAt)
+	if draftID.Valid {
+		a.PurchaseOrder = &models.PurchaseOrderDraft{
+			ID:        int(draftID.Int64),
+			ProductID: a.ProductID,
+			Quantity:  int(draftQuantity.Int64),
+			CreatedAt: draftCreatedAt.Time,
+		}
+	}
+	if resolvedAt.Valid {
+		a.ResolvedAt = &resolvedAt.Time
+	}
+	return a, err
+}
+
+type AlertRepository struct {
+	db *sql.DB
+}
+
+func NewAlertRepository(database *PostgresDB) *AlertRepository {
+	return &AlertRepository{db: database.Conn}
+}
+
+// alertSorts whitelists the fields alert listings may be sorted by
+var alertSorts = map[string]sortField[models.StockAlert]{
+	"id":         {column: "id", cast: "::int", value: func(a models.StockAlert) string { return strconv.Itoa(a.ID) }},
+	"created_at": {column: "created_at", cast: "::timestamp", value: func(a models.StockAlert) string { return a.CreatedAt.Format(time.RFC3339Nano) }},
+}
+
+// List returns one page of alerts matching filter, plus the cursor for the
+// next page ("" on the last page)
+func (r *AlertRepository) List(ctx context.Context, filter models.AlertFilter, page models.PageRequest) ([]models.StockAlert, string, error) {
+	var where whereBuilder
+	if filter.Open != nil {
+		if *filter.Open {
+			where.add("resolved_at IS NULL")
+		} else {
+			where.add("resolved_at IS NOT NULL")
+		}
+	}
+	if filter.Kind != "" {
+		where.add("kind = ?", filter.Kind)
+	}
+	if filter.ProductID != 0 {
+		where.add("product_id = ?", filter.ProductID)
+	}
+
+	orderBy, field, err := keyset(&where, alertSorts, page)
+	if err != nil {
+		return nil, "", err
+	}
+
+	query := "SELECT " + alertColumns + alertFrom + where.sql() + orderBy
+
+	rows, err := r.db.QueryContext(ctx, query, where.args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to query stock alerts: %w", err)
+	}
+	defer rows.Close()
+
+	var alerts []models.StockAlert
+	for rows.Next() {
+		a, err := scanAlert(rows)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to scan stock alert: %w", err)
+		}
+		alerts = append(alerts, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	alerts, next := trimPage(alerts, field, page, func(a models.StockAlert) int { return a.ID })
+	return alerts, next, nil
+}
+
+// PublishPending hands up to limit unpublished alerts, oldest first, to
+// publish and marks each one published once publish succeeds. It stops at
+// the first failure so the rest are retried on the next call, and returns
+// how many were published.
+func (r *AlertRepository) PublishPending(ctx context.Context, limit int, publish func(models.StockAlert) error) (int, error) {
+	published := 0
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		query := "SELECT " + alertColumns + alertFrom + ` WHERE id IN (
+				SELECT id FROM stock_alerts WHERE published_at IS NULL
+				ORDER BY id
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED
+			)
+			ORDER BY id`
+
+		rows, err := tx.QueryContext(ctx, query, limit)
+		if err != nil {
+			return err
+		}
+		var pending []models.StockAlert
+		for rows.Next() {
+			a, err := scanAlert(rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			pending = append(pending, a)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		var ids []int
+		for _, a := range pending {
+			if err := publish(a); err != nil {
+				break
+			}
+			ids = append(ids, a.ID)
+		}
+		published = len(ids)
+
+		_, err = tx.ExecContext(ctx,
+			"UPDATE stock_alerts SET published_at = CURRENT_TIMESTAMP WHERE id = ANY($1)",
+			pq.Array(ids),
+		)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to publish stock alerts: %w", err)
+	}
+
+	return published, nil
+}
+
+// GetPolicy returns a product's reorder policy, or nil if it has none
+func (r *AlertRepository) GetPolicy(ctx context.Context, productID int) (*models.ReorderPolicy, error) {
+	query := "SELECT product_id, threshold, reorder_quantity, updated_at FROM reorder_policies WHERE product_id = $1"
+
+	var p models.ReorderPolicy
+	err := r.db.QueryRowContext(ctx, query, productID).Scan(&p.ProductID, &p.Threshold, &p.ReorderQuantity, &p.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get reorder policy: %w", err)
+	}
+
+	return &p, nil
+}
+
+// SetPolicy creates or replaces a product's reorder policy. A missing
+// product yields nil, nil. The new threshold applies from the next movement.
+func (r *AlertRepository) SetPolicy(ctx context.Context, productID int, req models.SetReorderPolicyRequest) (*models.ReorderPolicy, error) {
+	query := `
+		INSERT INTO reorder_policies (product_id, threshold, reorder_quantity)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (product_id)
+		DO UPDATE SET threshold = EXCLUDED.threshold, reorder_quantity = EXCLUDED.reorder_quantity, updated_at = CURRENT_TIMESTAMP
+		RETURNING product_id, threshold, reorder_quantity, updated_at
+	`
+
+	var p models.ReorderPolicy
+	err := r.db.QueryRowContext(ctx, query, productID, *req.Threshold, req.ReorderQuantity).
+		Scan(&p.ProductID, &p.Threshold, &p.ReorderQuantity, &p.UpdatedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code.Name() == "foreign_key_violation" {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to set reorder policy: %w", err)
+	}
+
+	return &p, nil
+}
+
+// DeletePolicy removes a product's reorder policy. It reports whether
+// there was one.
+func (r *AlertRepository) DeletePolicy(ctx context.Context, productID int) (bool, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM reorder_policies WHERE product_id = $1", productID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete reorder policy: %w", err)
+	}
+
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
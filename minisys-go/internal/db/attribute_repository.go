@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/models"
+)
+
+type AttributeRepository struct {
+	db *sql.DB
+}
+
+func NewAttributeRepository(database *PostgresDB) *AttributeRepository {
+	return &AttributeRepository{db: database.Conn}
+}
+
+// GetAll returns the attribute schema keyed by attribute name
+func (r *AttributeRepository) GetAll(ctx context.Context) (map[string]models.AttributeDefinition, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT name, type, allowed_values FROM attribute_definitions ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query attribute definitions: %w", err)
+	}
+	defer rows.Close()
+
+	definitions := make(map[string]models.AttributeDefinition)
+	for rows.Next() {
+		var d models.AttributeDefinition
+		if err := rows.Scan(&d.Name, &d.Type, pq.Array(&d.AllowedValues)); err != nil {
+			return nil, fmt.Errorf("failed to scan attribute definition: %w", err)
+		}
+		definitions[d.Name] = d
+	}
+
+	return definitions, rows.Err()
+}
+
+// Upsert creates or replaces an attribute definition. Existing product
+// values are not rewritten.
+func (r *AttributeRepository) Upsert(ctx context.Context, d models.AttributeDefinition) error {
+	query := `
+		INSERT INTO attribute_definitions (name, type, allowed_values)
+		VALUES ($1, $2, COALESCE($3, '{}'))
+		ON CONFLICT (name) DO UPDATE SET type = EXCLUDED.type, allowed_values = EXCLUDED.allowed_values
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, d.Name, d.Type, pq.Array(d.AllowedValues)); err != nil {
+		return fmt.Errorf("failed to save attribute definition: %w", err)
+	}
+	return nil
+}
+
+// Delete removes an attribute definition
+func (r *AttributeRepository) Delete(ctx context.Context, name string) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM attribute_definitions WHERE name = $1", name)
+	if err != nil {
+		return fmt.Errorf("failed to delete attribute definition: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return fmt.Errorf("attribute not found")
+	}
+
+	return nil
+}
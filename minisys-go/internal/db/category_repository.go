@@ -0,0 +1,201 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/models"
+)
+
+var (
+	// ErrCategoryCycle is returned when a move would make a category its own ancestor
+	ErrCategoryCycle = errors.New("category cannot be moved under itself")
+	// ErrCategoryInUse is returned when deleting a category that still has children
+	ErrCategoryInUse = errors.New("category still has subcategories")
+	// ErrCategoryConflict is returned for duplicate slugs and unknown parents
+	ErrCategoryConflict = errors.New("category slug is taken or parent does not exist")
+)
+
+// categorySubtree selects a category and all of its descendants, taking
+// the root category ID from param
+func categorySubtree(param string) string {
+	return `
+		WITH RECURSIVE subtree AS (
+			SELECT id FROM categories WHERE id = ` + param + `
+			UNION ALL
+			SELECT c.id FROM categories c JOIN subtree s ON c.parent_id = s.id
+		)
+		SELECT id FROM subtree`
+}
+
+const categoryColumns = "id, parent_id, name, slug, created_at"
+
+type CategoryRepository struct {
+	db *sql.DB
+}
+
+func NewCategoryRepository(database *PostgresDB) *CategoryRepository {
+	return &CategoryRepository{db: database.Conn}
+}
+
+func scanCategory(row rowScanner) (models.Category, error) {
+	var c models.Category
+	err := row.Scan(&c.ID, &c.ParentID, &c.Name, &c.Slug, &c.CreatedAt)
+	return c, err
+}
+
+// GetAll returns every category as a flat list ordered by name
+func (r *CategoryRepository) GetAll(ctx context.Context) ([]models.Category, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+categoryColumns+" FROM categories ORDER BY name, id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query categories: %w", err)
+	}
+	defer rows.Close()
+
+	var categories []models.Category
+	for rows.Next() {
+		c, err := scanCategory(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan category: %w", err)
+		}
+		categories = append(categories, c)
+	}
+
+	return categories, rows.Err()
+}
+
+// GetByID returns a single category, or nil if it does not exist
+func (r *CategoryRepository) GetByID(ctx context.Context, id int) (*models.Category, error) {
+	c, err := scanCategory(r.db.QueryRowContext(ctx, "SELECT "+categoryColumns+" FROM categories WHERE id = $1", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get category: %w", err)
+	}
+
+	return &c, nil
+}
+
+// Ancestors returns the IDs of id and every category above it
+func (r *CategoryRepository) Ancestors(ctx context.Context, id int) ([]int, error) {
+	query := `
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id FROM categories WHERE id = $1
+			UNION ALL
+			SELECT c.id, c.parent_id FROM categories c JOIN ancestors a ON c.id = a.parent_id
+		)
+		SELECT id FROM ancestors
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query category ancestors: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var ancestor int
+		if err := rows.Scan(&ancestor); err != nil {
+			return nil, fmt.Errorf("failed to scan category: %w", err)
+		}
+		ids = append(ids, ancestor)
+	}
+
+	return ids, rows.Err()
+}
+
+// Create inserts a category
+func (r *CategoryRepository) Create(ctx context.Context, req models.CreateCategoryRequest) (*models.Category, error) {
+	query := `
+		INSERT INTO categories (parent_id, name, slug)
+		VALUES ($1, $2, $3)
+		RETURNING ` + categoryColumns
+
+	c, err := scanCategory(r.db.QueryRowContext(ctx, query, req.ParentID, req.Name, req.Slug))
+	if err != nil {
+		return nil, categoryError("create", err)
+	}
+
+	return &c, nil
+}
+
+// Update renames or moves a category, refusing moves that create a cycle.
+// A missing category yields nil, nil.
+func (r *CategoryRepository) Update(ctx context.Context, id int, req models.UpdateCategoryRequest) (*models.Category, error) {
+	if req.ParentID != nil && *req.ParentID != 0 {
+		subtree, err := r.db.QueryContext(ctx, categorySubtree("$1"), id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query category subtree: %w", err)
+		}
+		defer subtree.Close()
+
+		for subtree.Next() {
+			var descendant int
+			if err := subtree.Scan(&descendant); err != nil {
+				return nil, fmt.Errorf("failed to scan category: %w", err)
+			}
+			if descendant == *req.ParentID {
+				return nil, ErrCategoryCycle
+			}
+		}
+		if err := subtree.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	query := `
+		UPDATE categories SET
+			name = COALESCE($2, name),
+			slug = COALESCE($3, slug),
+			parent_id = CASE WHEN $4::int IS NULL THEN parent_id ELSE NULLIF($4::int, 0) END
+		WHERE id = $1
+		RETURNING ` + categoryColumns
+
+	c, err := scanCategory(r.db.QueryRowContext(ctx, query, id, req.Name, req.Slug, req.ParentID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, categoryError("update", err)
+	}
+
+	return &c, nil
+}
+
+// Delete removes a category; its products become uncategorized
+func (r *CategoryRepository) Delete(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM categories WHERE id = $1", id)
+	if err != nil {
+		return categoryError("delete", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return fmt.Errorf("category not found")
+	}
+
+	return nil
+}
+
+// categoryError maps constraint violations to the exported sentinels
+func categoryError(action string, err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Name() {
+		case "unique_violation":
+			return ErrCategoryConflict
+		case "foreign_key_violation":
+			if action == "delete" {
+				return ErrCategoryInUse
+			}
+			return ErrCategoryConflict
+		}
+	}
+	return fmt.Errorf("failed to %s category: %w", action, err)
+}
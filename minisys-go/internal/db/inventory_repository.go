@@ -0,0 +1,321 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/models"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/requestid"
+)
+
+// systemActor is recorded for stock changes made without a known caller
+const systemActor = "system"
+
+// orderLockClass namespaces the per-order advisory locks that keep an
+// order from being allocated or released twice at once
+const orderLockClass = 72_301_005
+
+type movementKey struct{}
+
+// WithMovement attaches why stock is about to change, and who changes it,
+// to ctx. Every ledger entry written under ctx carries it.
+func WithMovement(ctx context.Context, source models.MovementSource) context.Context {
+	return context.WithValue(ctx, movementKey{}, source)
+}
+
+// movementFrom returns the source attached to ctx; unattributed changes
+// are system adjustments referencing the request ID
+func movementFrom(ctx context.Context) models.MovementSource {
+	source, _ := ctx.Value(movementKey{}).(models.MovementSource)
+	if source.Reason == "" {
+		source.Reason = models.MovementAdjustment
+	}
+	if source.Actor == "" {
+		source.Actor = systemActor
+	}
+	if source.ReferenceID == "" {
+		source.ReferenceID = requestid.FromContext(ctx)
+	}
+	return source
+}
+
+// recordMovement appends a ledger entry for a stock change just applied
+func recordMovement(ctx context.Context, tx *sql.Tx, warehouseID, variantID, quantityChange, quantityAfter int) error {
+	source := movementFrom(ctx)
+	query := `
+		INSERT INTO inventory_movements (product_id, variant_id, warehouse_id, quantity_change, quantity_after, reason, reference_id, actor)
+		SELECT product_id, id, $2, $3, $4, $5, NULLIF($6, ''), $7 FROM product_variants WHERE id = $1
+		RETURNING product_id
+	`
+
+	var productID int
+	err := tx.QueryRowContext(ctx, query, variantID, warehouseID, quantityChange, quantityAfter,
+		source.Reason, source.ReferenceID, source.Actor,
+	).Scan(&productID)
+	if err != nil {
+		return fmt.Errorf("failed to record inventory movement: %w", err)
+	}
+
+	return checkStockAlerts(ctx, tx, productID, quantityChange)
+}
+
+// checkStockAlerts raises an alert when a movement takes a product down to
+// its reorder threshold or to zero, drafting a purchase order if the
+// policy asks for one, and resolves open alerts once stock is back above
+// them. Deleted products raise no alerts. Alerts are published later by
+// the alerter.
+func checkStockAlerts(ctx context.Context, tx *sql.Tx, productID, quantityChange int) error {
+	query := `
+		SELECT p.quantity, r.threshold, COALESCE(r.reorder_quantity, 0), p.deleted_at IS NOT NULL
+		FROM products p LEFT JOIN reorder_policies r ON r.product_id = p.id
+		WHERE p.id = $1
+	`
+	var quantity, reorderQuantity int
+	var threshold sql.NullInt64
+	var deleted bool
+	if err := tx.QueryRowContext(ctx, query, productID).Scan(&quantity, &threshold, &reorderQuantity, &deleted); err != nil {
+		return fmt.Errorf("failed to check stock alerts: %w", err)
+	}
+	limit := int(threshold.Int64)
+	before := quantity - quantityChange
+
+	if quantityChange > 0 {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE stock_alerts SET resolved_at = CURRENT_TIMESTAMP
+			WHERE product_id = $1 AND resolved_at IS NULL
+				AND ((kind = 'out_of_stock' AND $2 > 0) OR (kind = 'low_stock' AND $2 > $3))
+		`, productID, quantity, limit)
+		if err != nil {
+			return fmt.Errorf("failed to resolve stock alerts: %w", err)
+		}
+		return nil
+	}
+
+	var kind string
+	switch {
+	case deleted:
+		return nil
+	case quantity == 0 && before > 0:
+		kind = models.AlertOutOfStock
+	case threshold.Valid && quantity <= limit && before > limit:
+		kind = models.AlertLowStock
+	default:
+		return nil
+	}
+
+	var alertID int
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO stock_alerts (product_id, kind, quantity, threshold)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (product_id, kind) WHERE resolved_at IS NULL DO NOTHING
+		RETURNING id
+	`, productID, kind, quantity, limit).Scan(&alertID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to raise stock alert: %w", err)
+	}
+
+	// One draft per shortage, even if it deepens from low to out of stock
+	if reorderQuantity > 0 {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO purchase_order_drafts (product_id, alert_id, quantity)
+			SELECT $1, $2, $3
+			WHERE NOT EXISTS (
+				SELECT 1 FROM purchase_order_drafts d JOIN stock_alerts a ON a.id = d.alert_id
+				WHERE a.product_id = $1 AND a.resolved_at IS NULL AND a.id <> $2
+			)
+		`, productID, alertID, reorderQuantity)
+		if err != nil {
+			return fmt.Errorf("failed to draft purchase order: %w", err)
+		}
+	}
+	return nil
+}
+
+// lockOrder serializes stock work on one order until the transaction ends
+func lockOrder(ctx context.Context, tx *sql.Tx, orderID int) error {
+	_, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1, $2)", orderLockClass, orderID)
+	return err
+}
+
+// orderMovements sums the ledger entries with reason for an order by
+// warehouse and variant. For order entries this is where the order's
+// stock came from.
+func orderMovements(ctx context.Context, tx *sql.Tx, orderID int, reason string) ([]models.Allocation, error) {
+	query := `
+		SELECT m.product_id, m.variant_id, v.sku, m.warehouse_id, w.code, -SUM(m.quantity_change)
+		FROM inventory_movements m
+		JOIN product_variants v ON v.id = m.variant_id
+		JOIN warehouses w ON w.id = m.warehouse_id
+		WHERE m.reason = $1 AND m.reference_id = $2
+		GROUP BY m.product_id, m.variant_id, v.sku, m.warehouse_id, w.code
+		ORDER BY MIN(m.id)
+	`
+
+	rows, err := tx.QueryContext(ctx, query, reason, strconv.Itoa(orderID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order movements: %w", err)
+	}
+	defer rows.Close()
+
+	var allocations []models.Allocation
+	for rows.Next() {
+		var a models.Allocation
+		if err := rows.Scan(&a.ProductID, &a.VariantID, &a.SKU, &a.WarehouseID, &a.WarehouseCode, &a.Quantity); err != nil {
+			return nil, fmt.Errorf("failed to scan order movement: %w", err)
+		}
+		allocations = append(allocations, a)
+	}
+
+	return allocations, rows.Err()
+}
+
+type InventoryRepository struct {
+	db *sql.DB
+}
+
+func NewInventoryRepository(database *PostgresDB) *InventoryRepository {
+	return &InventoryRepository{db: database.Conn}
+}
+
+// movementSorts whitelists the fields inventory history may be sorted by
+var movementSorts = map[string]sortField[models.InventoryMovement]{
+	"id":         {column: "id", cast: "::bigint", value: func(m models.InventoryMovement) string { return strconv.Itoa(m.ID) }},
+	"created_at": {column: "created_at", cast: "::timestamp", value: func(m models.InventoryMovement) string { return m.CreatedAt.Format(time.RFC3339Nano) }},
+}
+
+// History returns one page of a product's ledger entries, plus the cursor
+// for the next page ("" on the last page)
+func (r *InventoryRepository) History(ctx context.Context, productID int, filter models.MovementFilter, page models.PageRequest) ([]models.InventoryMovement, string, error) {
+	var where whereBuilder
+	where.add("product_id = ?", productID)
+	if filter.VariantID != 0 {
+		where.add("variant_id = ?", filter.VariantID)
+	}
+	if filter.WarehouseID != 0 {
+		where.add("warehouse_id = ?", filter.WarehouseID)
+	}
+	if filter.Reason != "" {
+		where.add("reason = ?", filter.Reason)
+	}
+
+	orderBy, field, err := keyset(&where, movementSorts, page)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Keyset pagination expects an unqualified id column, hence the subquery
+	query := `
+		SELECT id, product_id, variant_id, sku, warehouse_id, warehouse_code,
+			quantity_change, quantity_after, reason, reference_id, actor, created_at
+		FROM (
+			SELECT m.id, m.product_id, m.variant_id, COALESCE(v.sku, '') AS sku,
+				m.warehouse_id, COALESCE(w.code, '') AS warehouse_code,
+				m.quantity_change, m.quantity_after, m.reason, COALESCE(m.reference_id, '') AS reference_id,
+				m.actor, m.created_at
+			FROM inventory_movements m
+			LEFT JOIN product_variants v ON v.id = m.variant_id
+			LEFT JOIN warehouses w ON w.id = m.warehouse_id
+		) m` + where.sql() + orderBy
+
+	rows, err := r.db.QueryContext(ctx, query, where.args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to query inventory history: %w", err)
+	}
+	defer rows.Close()
+
+	var movements []models.InventoryMovement
+	for rows.Next() {
+		var m models.InventoryMovement
+		err := rows.Scan(&m.ID, &m.ProductID, &m.VariantID, &m.SKU, &m.WarehouseID, &m.WarehouseCode,
+			&m.QuantityChange, &m.QuantityAfter, &m.Reason, &m.ReferenceID, &m.Actor, &m.CreatedAt)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to scan inventory movement: %w", err)
+		}
+		movements = append(movements, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	movements, next := trimPage(movements, field, page, func(m models.InventoryMovement) int { return m.ID })
+	return movements, next, nil
+}
+
+// Adjust changes a variant's stock in one warehouse (0 means the default
+// one) and records it under the source attached to ctx. It returns the
+// variant's product ID, or 0 if the variant does not belong to productID.
+func (r *InventoryRepository) Adjust(ctx context.Context, productID, variantID, warehouseID, quantityChange int) (int, error) {
+	found := false
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		query := `
+			UPDATE product_variants SET version = version + 1, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $1 AND product_id = $2
+		`
+		result, err := tx.ExecContext(ctx, query, variantID, productID)
+		if err != nil {
+			return err
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return nil
+		}
+		found = true
+
+		if err := adjustWarehouseStock(ctx, tx, warehouseID, variantID, quantityChange); err != nil {
+			return err
+		}
+		return touchProduct(ctx, tx, productID)
+	})
+	if err != nil || !found {
+		return 0, err
+	}
+
+	return productID, nil
+}
+
+// Drift compares every warehouse stock level with the sum of its ledger
+// entries and returns the ones that disagree
+func (r *InventoryRepository) Drift(ctx context.Context) ([]models.StockDrift, error) {
+	query := `
+		WITH ledger AS (
+			SELECT warehouse_id, variant_id, SUM(quantity_change) AS total
+			FROM inventory_movements
+			GROUP BY warehouse_id, variant_id
+		), compared AS (
+			SELECT COALESCE(s.warehouse_id, l.warehouse_id) AS warehouse_id,
+				COALESCE(s.variant_id, l.variant_id) AS variant_id,
+				COALESCE(s.quantity, 0) AS recorded,
+				COALESCE(l.total, 0) AS ledger
+			FROM warehouse_stock s
+			FULL JOIN ledger l ON l.warehouse_id = s.warehouse_id AND l.variant_id = s.variant_id
+		)
+		SELECT c.warehouse_id, w.code, v.product_id, c.variant_id, v.sku, c.recorded, c.ledger
+		FROM compared c
+		JOIN warehouses w ON w.id = c.warehouse_id
+		JOIN product_variants v ON v.id = c.variant_id
+		WHERE c.recorded <> c.ledger
+		ORDER BY v.product_id, c.variant_id, c.warehouse_id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare stock with ledger: %w", err)
+	}
+	defer rows.Close()
+
+	drift := []models.StockDrift{}
+	for rows.Next() {
+		var d models.StockDrift
+		if err := rows.Scan(&d.WarehouseID, &d.WarehouseCode, &d.ProductID, &d.VariantID, &d.SKU, &d.Recorded, &d.Ledger); err != nil {
+			return nil, fmt.Errorf("failed to scan stock drift: %w", err)
+		}
+		drift = append(drift, d)
+	}
+
+	return drift, rows.Err()
+}
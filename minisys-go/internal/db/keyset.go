@@ -0,0 +1,128 @@
+package db
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/models"
+)
+
+// ErrInvalidPage is returned for unknown sort fields and bad cursors
+var ErrInvalidPage = errors.New("invalid page request")
+
+// sortField is a column a listing may be ordered by. Cast turns the
+// cursor's text value back into the column type, and value reads the same
+// field from a row so the next cursor can be built.
+type sortField[T any] struct {
+	column string
+	cast   string
+	value  func(T) string
+}
+
+// cursor marks the last row of a page: its sort value plus its ID, which
+// breaks ties so rows with equal sort values are never skipped
+type cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    int    `json:"id"`
+}
+
+func encodeCursor(c cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, fmt.Errorf("%w: malformed cursor", ErrInvalidPage)
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("%w: malformed cursor", ErrInvalidPage)
+	}
+
+	return c, nil
+}
+
+// whereBuilder collects AND-ed conditions; "?" in a condition is replaced
+// by the next positional parameter
+type whereBuilder struct {
+	conds []string
+	args  []any
+}
+
+func (w *whereBuilder) add(cond string, args ...any) {
+	for _, arg := range args {
+		w.args = append(w.args, arg)
+		cond = strings.Replace(cond, "?", fmt.Sprintf("$%d", len(w.args)), 1)
+	}
+	w.conds = append(w.conds, cond)
+}
+
+func (w *whereBuilder) sql() string {
+	if len(w.conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(w.conds, " AND ")
+}
+
+// keyset adds the cursor condition and returns the ORDER BY and LIMIT
+// clauses for one page. It fetches one extra row to detect a next page.
+func keyset[T any](w *whereBuilder, fields map[string]sortField[T], page models.PageRequest) (string, sortField[T], error) {
+	name := strings.TrimPrefix(page.Sort, "-")
+	desc := strings.HasPrefix(page.Sort, "-")
+
+	field, ok := fields[name]
+	if !ok {
+		return "", field, fmt.Errorf("%w: cannot sort by %q", ErrInvalidPage, name)
+	}
+
+	direction, op := "ASC", ">"
+	if desc {
+		direction, op = "DESC", "<"
+	}
+
+	if page.Cursor != "" {
+		c, err := decodeCursor(page.Cursor)
+		if err != nil {
+			return "", field, err
+		}
+		if c.Sort != page.Sort {
+			return "", field, fmt.Errorf("%w: cursor was issued for sort %q", ErrInvalidPage, c.Sort)
+		}
+		w.add(fmt.Sprintf("(%s, id) %s (?%s, ?)", field.column, op, field.cast), c.Value, c.ID)
+	}
+
+	clause := fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT %d", field.column, direction, direction, page.Limit+1)
+	return clause, field, nil
+}
+
+// trimPage drops the look-ahead row and builds the cursor for the next page
+func trimPage[T any](rows []T, field sortField[T], page models.PageRequest, id func(T) int) ([]T, string) {
+	if rows == nil {
+		rows = []T{}
+	}
+	if len(rows) <= page.Limit {
+		return rows, ""
+	}
+
+	rows = rows[:page.Limit]
+	last := rows[len(rows)-1]
+	return rows, encodeCursor(cursor{Sort: page.Sort, Value: field.value(last), ID: id(last)})
+}
+
+// sortedKeys gives map-driven filters a stable SQL shape
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
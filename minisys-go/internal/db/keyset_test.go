@@ -0,0 +1,165 @@
+package db
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/models"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := []cursor{
+		{Sort: "id", Value: "42", ID: 42},
+		{Sort: "-price", Value: "999.99", ID: 7},
+		{Sort: "name", Value: "Widget, \"large\" / 10% off", ID: 3},
+		{Sort: "created_at", Value: "2026-10-18T12:00:00.123456Z", ID: 1},
+		{Sort: "name", Value: "", ID: 0},
+	}
+
+	for _, want := range tests {
+		encoded := encodeCursor(want)
+		got, err := decodeCursor(encoded)
+		if err != nil {
+			t.Fatalf("decodeCursor(%q) error = %v", encoded, err)
+		}
+		if got != want {
+			t.Errorf("round trip = %+v, want %+v", got, want)
+		}
+	}
+}
+
+func TestDecodeCursorRejectsGarbage(t *testing.T) {
+	tests := []string{
+		"not base64!",
+		"eyJzIjoiaWQi",               // truncated JSON
+		"W10",                        // a JSON array
+		"eyJpZCI6Im5vdCBhbiBpbnQifQ", // id is a string
+	}
+
+	for _, s := range tests {
+		if _, err := decodeCursor(s); !errors.Is(err, ErrInvalidPage) {
+			t.Errorf("decodeCursor(%q) error = %v, want ErrInvalidPage", s, err)
+		}
+	}
+}
+
+type row struct {
+	id   int
+	name string
+}
+
+var rowSorts = map[string]sortField[row]{
+	"id":   {column: "id", cast: "::int", value: func(r row) string { return strconv.Itoa(r.id) }},
+	"name": {column: "name", cast: "::text", value: func(r row) string { return r.name }},
+}
+
+func TestKeyset(t *testing.T) {
+	tests := []struct {
+		name       string
+		page       models.PageRequest
+		wantClause string
+		wantWhere  string
+		wantArgs   []any
+		wantErr    bool
+	}{
+		{
+			name:       "first page ascending",
+			page:       models.PageRequest{Limit: 10, Sort: "id"},
+			wantClause: " ORDER BY id ASC, id ASC LIMIT 11",
+		},
+		{
+			name:       "first page descending",
+			page:       models.PageRequest{Limit: 5, Sort: "-name"},
+			wantClause: " ORDER BY name DESC, id DESC LIMIT 6",
+		},
+		{
+			name:       "next page ascending",
+			page:       models.PageRequest{Limit: 10, Sort: "name", Cursor: encodeCursor(cursor{Sort: "name", Value: "m", ID: 9})},
+			wantClause: " ORDER BY name ASC, id ASC LIMIT 11",
+			wantWhere:  " WHERE (name, id) > ($1::text, $2)",
+			wantArgs:   []any{"m", 9},
+		},
+		{
+			name:       "next page descending",
+			page:       models.PageRequest{Limit: 10, Sort: "-id", Cursor: encodeCursor(cursor{Sort: "-id", Value: "50", ID: 50})},
+			wantClause: " ORDER BY id DESC, id DESC LIMIT 11",
+			wantWhere:  " WHERE (id, id) < ($1::int, $2)",
+			wantArgs:   []any{"50", 50},
+		},
+		{
+			name:    "unknown sort",
+			page:    models.PageRequest{Limit: 10, Sort: "price"},
+			wantErr: true,
+		},
+		{
+			name:    "cursor from another sort",
+			page:    models.PageRequest{Limit: 10, Sort: "name", Cursor: encodeCursor(cursor{Sort: "-name", Value: "m", ID: 9})},
+			wantErr: true,
+		},
+		{
+			name:    "malformed cursor",
+			page:    models.PageRequest{Limit: 10, Sort: "id", Cursor: "%%%"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var where whereBuilder
+			clause, _, err := keyset(&where, rowSorts, tt.page)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidPage) {
+					t.Fatalf("error = %v, want ErrInvalidPage", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if clause != tt.wantClause {
+				t.Errorf("clause = %q, want %q", clause, tt.wantClause)
+			}
+			if got := where.sql(); got != tt.wantWhere {
+				t.Errorf("where = %q, want %q", got, tt.wantWhere)
+			}
+			if len(where.args) != len(tt.wantArgs) {
+				t.Fatalf("args = %v, want %v", where.args, tt.wantArgs)
+			}
+			for i := range where.args {
+				if where.args[i] != tt.wantArgs[i] {
+					t.Errorf("args[%d] = %v, want %v", i, where.args[i], tt.wantArgs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestTrimPage(t *testing.T) {
+	rows := []row{{1, "a"}, {2, "b"}, {3, "c"}}
+	id := func(r row) int { return r.id }
+	page := models.PageRequest{Limit: 2, Sort: "name"}
+
+	got, next := trimPage(rows, rowSorts["name"], page, id)
+	if len(got) != 2 {
+		t.Fatalf("kept %d rows, want 2", len(got))
+	}
+	c, err := decodeCursor(next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (cursor{Sort: "name", Value: "b", ID: 2}); c != want {
+		t.Errorf("next cursor = %+v, want %+v", c, want)
+	}
+
+	got, next = trimPage(rows[:2], rowSorts["name"], page, id)
+	if len(got) != 2 || next != "" {
+		t.Errorf("last page = %d rows, cursor %q; want 2 rows and no cursor", len(got), next)
+	}
+
+	got, next = trimPage[row](nil, rowSorts["name"], page, id)
+	if got == nil || len(got) != 0 || next != "" {
+		t.Errorf("empty page = %#v, cursor %q; want an empty slice and no cursor", got, next)
+	}
+}
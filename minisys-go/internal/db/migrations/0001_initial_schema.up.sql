@@ -0,0 +1,40 @@
+-- Products table
+CREATE TABLE IF NOT EXISTS products (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    price DECIMAL(10,2) NOT NULL,
+    quantity INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Orders table
+CREATE TABLE IF NOT EXISTS orders (
+    id SERIAL PRIMARY KEY,
+    customer_name VARCHAR(255) NOT NULL,
+    total_amount DECIMAL(10,2) NOT NULL,
+    status VARCHAR(50) NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Order items table
+CREATE TABLE IF NOT EXISTS order_items (
+    id SERIAL PRIMARY KEY,
+    order_id INT REFERENCES orders(id),
+    product_id INT NOT NULL,
+    product_name VARCHAR(255) NOT NULL,
+    quantity INT NOT NULL,
+    price DECIMAL(10,2) NOT NULL
+);
+
+-- Sample products
+INSERT INTO products (name, price, quantity)
+SELECT 'Laptop', 999.99, 10
+WHERE NOT EXISTS (SELECT 1 FROM products WHERE name = 'Laptop');
+
+INSERT INTO products (name, price, quantity)
+SELECT 'Mouse', 29.99, 50
+WHERE NOT EXISTS (SELECT 1 FROM products WHERE name = 'Mouse');
+
+INSERT INTO products (name, price, quantity)
+SELECT 'Keyboard', 79.99, 30
+WHERE NOT EXISTS (SELECT 1 FROM products WHERE name = 'Keyboard');
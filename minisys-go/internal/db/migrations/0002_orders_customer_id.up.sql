@@ -0,0 +1,2 @@
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS customer_id VARCHAR(255);
+CREATE INDEX IF NOT EXISTS idx_orders_customer_id ON orders(customer_id);
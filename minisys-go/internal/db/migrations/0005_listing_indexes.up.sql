@@ -0,0 +1,6 @@
+-- Keyset pagination walks (sort column, id); these cover the default sorts
+CREATE INDEX IF NOT EXISTS idx_products_price_id ON products(price, id);
+CREATE INDEX IF NOT EXISTS idx_products_created_at_id ON products(created_at, id);
+CREATE INDEX IF NOT EXISTS idx_orders_created_at_id ON orders(created_at, id);
+CREATE INDEX IF NOT EXISTS idx_orders_customer_created_at ON orders(customer_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_orders_status ON orders(status);
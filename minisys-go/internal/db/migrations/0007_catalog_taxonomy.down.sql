@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_products_attributes;
+DROP INDEX IF EXISTS idx_products_tags;
+DROP INDEX IF EXISTS idx_products_category_id;
+
+ALTER TABLE products DROP COLUMN IF EXISTS attributes;
+ALTER TABLE products DROP COLUMN IF EXISTS tags;
+ALTER TABLE products DROP COLUMN IF EXISTS category_id;
+
+DROP TABLE IF EXISTS attribute_definitions;
+DROP TABLE IF EXISTS categories;
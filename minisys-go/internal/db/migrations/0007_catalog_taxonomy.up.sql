@@ -0,0 +1,25 @@
+-- Category tree; a category cannot be removed while it has children
+CREATE TABLE IF NOT EXISTS categories (
+    id SERIAL PRIMARY KEY,
+    parent_id INT REFERENCES categories(id) ON DELETE RESTRICT,
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(255) NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_categories_parent_id ON categories(parent_id);
+
+-- Typed attribute schema; product attribute values are checked against it
+CREATE TABLE IF NOT EXISTS attribute_definitions (
+    name VARCHAR(64) PRIMARY KEY,
+    type VARCHAR(16) NOT NULL CHECK (type IN ('string', 'number', 'boolean', 'enum')),
+    allowed_values TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS category_id INT REFERENCES categories(id) ON DELETE SET NULL;
+ALTER TABLE products ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE products ADD COLUMN IF NOT EXISTS attributes JSONB NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS idx_products_category_id ON products(category_id);
+CREATE INDEX IF NOT EXISTS idx_products_tags ON products USING GIN (tags);
+CREATE INDEX IF NOT EXISTS idx_products_attributes ON products USING GIN (attributes);
@@ -0,0 +1,6 @@
+ALTER TABLE order_items DROP COLUMN IF EXISTS sku;
+ALTER TABLE order_items DROP COLUMN IF EXISTS variant_id;
+
+DROP TRIGGER IF EXISTS product_variants_sync_quantity ON product_variants;
+DROP FUNCTION IF EXISTS sync_product_quantity();
+DROP TABLE IF EXISTS product_variants;
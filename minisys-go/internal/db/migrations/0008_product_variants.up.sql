@@ -0,0 +1,45 @@
+-- Sellable SKUs under each product. Every product has one default variant
+-- that carries product-level stock for clients that know nothing of SKUs.
+CREATE TABLE IF NOT EXISTS product_variants (
+    id SERIAL PRIMARY KEY,
+    product_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    sku VARCHAR(64) NOT NULL UNIQUE,
+    options JSONB NOT NULL DEFAULT '{}',
+    price DECIMAL(10,2),
+    quantity INT NOT NULL DEFAULT 0 CHECK (quantity >= 0),
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    version INT NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_product_variants_product_id ON product_variants(product_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_variants_default ON product_variants(product_id) WHERE is_default;
+
+INSERT INTO product_variants (product_id, sku, quantity, is_default)
+SELECT id, 'SKU-' || id, GREATEST(quantity, 0), TRUE FROM products
+ON CONFLICT DO NOTHING;
+
+-- products.quantity becomes the sum of its variants' stock
+CREATE OR REPLACE FUNCTION sync_product_quantity() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE products SET quantity = (
+            SELECT COALESCE(SUM(quantity), 0) FROM product_variants WHERE product_id = OLD.product_id
+        ) WHERE id = OLD.product_id;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        UPDATE products SET quantity = (
+            SELECT COALESCE(SUM(quantity), 0) FROM product_variants WHERE product_id = NEW.product_id
+        ) WHERE id = NEW.product_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS product_variants_sync_quantity ON product_variants;
+CREATE TRIGGER product_variants_sync_quantity
+AFTER INSERT OR UPDATE OF quantity, product_id OR DELETE ON product_variants
+FOR EACH ROW EXECUTE FUNCTION sync_product_quantity();
+
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS variant_id INT;
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS sku VARCHAR(64);
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS order_allocations;
+ALTER TABLE orders DROP COLUMN IF EXISTS allocation_strategy;
+ALTER TABLE orders DROP COLUMN IF EXISTS ship_longitude;
+ALTER TABLE orders DROP COLUMN IF EXISTS ship_latitude;
+
+DROP TRIGGER IF EXISTS warehouse_stock_sync_quantity ON warehouse_stock;
+DROP FUNCTION IF EXISTS sync_variant_quantity();
+DROP TABLE IF EXISTS warehouse_stock;
+DROP TABLE IF EXISTS warehouses;
@@ -0,0 +1,72 @@
+CREATE TABLE IF NOT EXISTS warehouses (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(32) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    latitude DOUBLE PRECISION,
+    longitude DOUBLE PRECISION,
+    priority INT NOT NULL DEFAULT 0,
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_warehouses_default ON warehouses(is_default) WHERE is_default;
+
+-- The default warehouse takes stock changes that do not name a warehouse
+INSERT INTO warehouses (code, name, is_default) VALUES ('MAIN', 'Main warehouse', TRUE)
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS warehouse_stock (
+    warehouse_id INT NOT NULL REFERENCES warehouses(id) ON DELETE RESTRICT,
+    variant_id INT NOT NULL REFERENCES product_variants(id) ON DELETE CASCADE,
+    quantity INT NOT NULL DEFAULT 0 CHECK (quantity >= 0),
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (warehouse_id, variant_id)
+);
+CREATE INDEX IF NOT EXISTS idx_warehouse_stock_variant_id ON warehouse_stock(variant_id);
+
+INSERT INTO warehouse_stock (warehouse_id, variant_id, quantity)
+SELECT w.id, v.id, v.quantity FROM product_variants v, warehouses w
+WHERE w.is_default AND v.quantity > 0
+ON CONFLICT DO NOTHING;
+
+-- product_variants.quantity becomes the sum of its per-warehouse stock,
+-- which in turn feeds products.quantity
+CREATE OR REPLACE FUNCTION sync_variant_quantity() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE product_variants SET quantity = (
+            SELECT COALESCE(SUM(quantity), 0) FROM warehouse_stock WHERE variant_id = OLD.variant_id
+        ) WHERE id = OLD.variant_id;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        UPDATE product_variants SET quantity = (
+            SELECT COALESCE(SUM(quantity), 0) FROM warehouse_stock WHERE variant_id = NEW.variant_id
+        ) WHERE id = NEW.variant_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS warehouse_stock_sync_quantity ON warehouse_stock;
+CREATE TRIGGER warehouse_stock_sync_quantity
+AFTER INSERT OR UPDATE OF quantity, variant_id OR DELETE ON warehouse_stock
+FOR EACH ROW EXECUTE FUNCTION sync_variant_quantity();
+
+-- Where an order ships to and how its warehouses are picked
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS ship_latitude DOUBLE PRECISION;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS ship_longitude DOUBLE PRECISION;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS allocation_strategy VARCHAR(16);
+
+-- Which warehouse fulfils which part of an order
+CREATE TABLE IF NOT EXISTS order_allocations (
+    id SERIAL PRIMARY KEY,
+    order_id INT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    product_id INT NOT NULL,
+    variant_id INT NOT NULL,
+    sku VARCHAR(64) NOT NULL,
+    warehouse_id INT NOT NULL,
+    warehouse_code VARCHAR(32) NOT NULL,
+    quantity INT NOT NULL,
+    allocated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_order_allocations_order_id ON order_allocations(order_id);
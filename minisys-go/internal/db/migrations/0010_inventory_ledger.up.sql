@@ -0,0 +1,34 @@
+-- Every stock change, never updated or deleted. warehouse_stock is the
+-- projection of this ledger; the reconciler reports where they disagree.
+CREATE TABLE IF NOT EXISTS inventory_movements (
+    id BIGSERIAL PRIMARY KEY,
+    product_id INT NOT NULL,
+    variant_id INT NOT NULL,
+    warehouse_id INT NOT NULL,
+    quantity_change INT NOT NULL,
+    quantity_after INT NOT NULL,
+    reason VARCHAR(32) NOT NULL CHECK (reason IN ('initial', 'order', 'cancellation', 'adjustment', 'restock')),
+    reference_id VARCHAR(64),
+    actor VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_inventory_movements_product_id ON inventory_movements(product_id, id);
+CREATE INDEX IF NOT EXISTS idx_inventory_movements_stock ON inventory_movements(warehouse_id, variant_id);
+CREATE INDEX IF NOT EXISTS idx_inventory_movements_reference ON inventory_movements(reason, reference_id);
+
+CREATE OR REPLACE FUNCTION reject_movement_change() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'inventory_movements is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS inventory_movements_append_only ON inventory_movements;
+CREATE TRIGGER inventory_movements_append_only
+BEFORE UPDATE OR DELETE ON inventory_movements
+FOR EACH ROW EXECUTE FUNCTION reject_movement_change();
+
+-- Opening balances so the ledger explains today's stock
+INSERT INTO inventory_movements (product_id, variant_id, warehouse_id, quantity_change, quantity_after, reason, actor)
+SELECT v.product_id, s.variant_id, s.warehouse_id, s.quantity, s.quantity, 'initial', 'migration'
+FROM warehouse_stock s JOIN product_variants v ON v.id = s.variant_id
+WHERE s.quantity > 0;
@@ -0,0 +1,29 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS reservation_id;
+
+DROP TABLE IF EXISTS reservation_items;
+DROP TABLE IF EXISTS reservations;
+
+CREATE OR REPLACE FUNCTION sync_product_quantity() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE products SET quantity = (
+            SELECT COALESCE(SUM(quantity), 0) FROM product_variants WHERE product_id = OLD.product_id
+        ) WHERE id = OLD.product_id;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        UPDATE products SET quantity = (
+            SELECT COALESCE(SUM(quantity), 0) FROM product_variants WHERE product_id = NEW.product_id
+        ) WHERE id = NEW.product_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS product_variants_sync_quantity ON product_variants;
+CREATE TRIGGER product_variants_sync_quantity
+AFTER INSERT OR UPDATE OF quantity, product_id OR DELETE ON product_variants
+FOR EACH ROW EXECUTE FUNCTION sync_product_quantity();
+
+ALTER TABLE products DROP COLUMN IF EXISTS reserved;
+ALTER TABLE product_variants DROP CONSTRAINT IF EXISTS product_variants_reserved_check;
+ALTER TABLE product_variants DROP COLUMN IF EXISTS reserved;
@@ -0,0 +1,54 @@
+-- Stock held for checkouts that have not been paid for yet. Holds are per
+-- variant; available stock is quantity - reserved, and the check keeps
+-- orders and stock changes from eating into held units.
+ALTER TABLE product_variants ADD COLUMN IF NOT EXISTS reserved INT NOT NULL DEFAULT 0;
+ALTER TABLE product_variants DROP CONSTRAINT IF EXISTS product_variants_reserved_check;
+ALTER TABLE product_variants ADD CONSTRAINT product_variants_reserved_check CHECK (reserved >= 0 AND reserved <= quantity);
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS reserved INT NOT NULL DEFAULT 0;
+
+-- products.quantity and products.reserved are the sums over its variants
+CREATE OR REPLACE FUNCTION sync_product_quantity() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE products SET (quantity, reserved) = (
+            SELECT COALESCE(SUM(quantity), 0), COALESCE(SUM(reserved), 0)
+            FROM product_variants WHERE product_id = OLD.product_id
+        ) WHERE id = OLD.product_id;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        UPDATE products SET (quantity, reserved) = (
+            SELECT COALESCE(SUM(quantity), 0), COALESCE(SUM(reserved), 0)
+            FROM product_variants WHERE product_id = NEW.product_id
+        ) WHERE id = NEW.product_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS product_variants_sync_quantity ON product_variants;
+CREATE TRIGGER product_variants_sync_quantity
+AFTER INSERT OR UPDATE OF quantity, reserved, product_id OR DELETE ON product_variants
+FOR EACH ROW EXECUTE FUNCTION sync_product_quantity();
+
+CREATE TABLE IF NOT EXISTS reservations (
+    id SERIAL PRIMARY KEY,
+    reference VARCHAR(64) NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'confirmed', 'released', 'expired')),
+    order_id INT,
+    actor VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_reservations_expiry ON reservations(expires_at) WHERE status = 'active';
+
+CREATE TABLE IF NOT EXISTS reservation_items (
+    reservation_id INT NOT NULL REFERENCES reservations(id) ON DELETE CASCADE,
+    variant_id INT NOT NULL REFERENCES product_variants(id) ON DELETE CASCADE,
+    product_id INT NOT NULL,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    PRIMARY KEY (reservation_id, variant_id)
+);
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS reservation_id INT;
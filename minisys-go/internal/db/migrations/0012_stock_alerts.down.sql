@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS purchase_order_drafts;
+DROP TABLE IF EXISTS stock_alerts;
+DROP TABLE IF EXISTS reorder_policies;
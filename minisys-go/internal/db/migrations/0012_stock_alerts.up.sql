@@ -0,0 +1,32 @@
+-- When a product's stock falls to threshold or below, a low_stock alert is
+-- raised; reorder_quantity > 0 also drafts a purchase order for that many
+CREATE TABLE IF NOT EXISTS reorder_policies (
+    product_id INT PRIMARY KEY REFERENCES products(id) ON DELETE CASCADE,
+    threshold INT NOT NULL CHECK (threshold >= 0),
+    reorder_quantity INT NOT NULL DEFAULT 0 CHECK (reorder_quantity >= 0),
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Raised inside the transaction that moved the stock and published
+-- afterwards by the alerter, so no crossing is lost to a broker outage
+CREATE TABLE IF NOT EXISTS stock_alerts (
+    id SERIAL PRIMARY KEY,
+    product_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    kind VARCHAR(16) NOT NULL CHECK (kind IN ('low_stock', 'out_of_stock')),
+    quantity INT NOT NULL,
+    threshold INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    published_at TIMESTAMP,
+    resolved_at TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_stock_alerts_open ON stock_alerts(product_id, kind) WHERE resolved_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_stock_alerts_unpublished ON stock_alerts(id) WHERE published_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS purchase_order_drafts (
+    id SERIAL PRIMARY KEY,
+    product_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    alert_id INT NOT NULL REFERENCES stock_alerts(id) ON DELETE CASCADE,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_purchase_order_drafts_alert ON purchase_order_drafts(alert_id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_order_items_product_id;
+DROP INDEX IF EXISTS idx_products_deleted_at;
+
+ALTER TABLE products DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,8 @@
+-- Deleted products stay in place so orders can still resolve them; they
+-- are hidden from listings and new orders until restored or purged
+ALTER TABLE products ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_products_deleted_at ON products(deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- Purging checks for open orders by product
+CREATE INDEX IF NOT EXISTS idx_order_items_product_id ON order_items(product_id);
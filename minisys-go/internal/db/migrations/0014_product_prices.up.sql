@@ -0,0 +1,27 @@
+-- Every price a product has had or is scheduled to have. A product's
+-- ranges never overlap; effective_to NULL means until further notice.
+-- products.price mirrors the range in effect, which the price scheduler
+-- activates once effective_from passes.
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+CREATE TABLE IF NOT EXISTS product_prices (
+    id SERIAL PRIMARY KEY,
+    product_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    currency CHAR(3) NOT NULL,
+    price DECIMAL(10,2) NOT NULL,
+    effective_from TIMESTAMP NOT NULL,
+    effective_to TIMESTAMP CHECK (effective_to > effective_from),
+    actor VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    activated_at TIMESTAMP,
+    published_at TIMESTAMP,
+    EXCLUDE USING gist (product_id WITH =, tsrange(effective_from, effective_to) WITH &&)
+);
+CREATE INDEX IF NOT EXISTS idx_product_prices_pending ON product_prices(effective_from) WHERE activated_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_product_prices_unpublished ON product_prices(id) WHERE activated_at IS NOT NULL AND published_at IS NULL;
+
+-- Today's prices open the history
+INSERT INTO product_prices (product_id, currency, price, effective_from, actor, activated_at, published_at)
+SELECT p.id, p.currency, p.price, COALESCE(p.created_at, CURRENT_TIMESTAMP), 'system', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+FROM products p
+WHERE NOT EXISTS (SELECT 1 FROM product_prices pp WHERE pp.product_id = p.id);
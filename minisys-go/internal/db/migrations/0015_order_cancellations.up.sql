@@ -0,0 +1,13 @@
+-- Orders whose stock has been released for good. The marker is written
+-- even when nothing was allocated yet, so an order.created handled after
+-- its order.cancelled cannot take stock for a cancelled order.
+CREATE TABLE IF NOT EXISTS order_cancellations (
+    order_id INT PRIMARY KEY,
+    cancelled_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO order_cancellations (order_id, cancelled_at)
+SELECT reference_id::int, MIN(created_at) FROM inventory_movements
+WHERE reason = 'cancellation' AND reference_id ~ '^[0-9]+$'
+GROUP BY reference_id
+ON CONFLICT DO NOTHING;
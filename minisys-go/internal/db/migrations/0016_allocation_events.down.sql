@@ -0,0 +1 @@
+DROP TABLE IF EXISTS allocation_events;
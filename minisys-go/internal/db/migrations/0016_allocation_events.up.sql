@@ -0,0 +1,11 @@
+-- Where each order ships from, written inside the transaction that took
+-- its stock and published afterwards as inventory.allocated, so a broker
+-- outage delays the shipping plan instead of losing it
+CREATE TABLE IF NOT EXISTS allocation_events (
+    order_id INT PRIMARY KEY,
+    strategy VARCHAR(16) NOT NULL,
+    allocations JSONB NOT NULL,
+    allocated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    published_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_allocation_events_unpublished ON allocation_events(allocated_at) WHERE published_at IS NULL;
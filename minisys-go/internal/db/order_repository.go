@@ -1,12 +1,28 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/models"
 )
 
+// orderColumns lists the columns scanOrder expects, in order
+const orderColumns = "id, COALESCE(customer_id, ''), customer_name, currency, total_amount, status, ship_latitude, ship_longitude, COALESCE(allocation_strategy, ''), COALESCE(reservation_id, 0), created_at"
+
+func scanOrder(row rowScanner) (models.Order, error) {
+	var o models.Order
+	var lat, lon sql.NullFloat64
+	err := row.Scan(&o.ID, &o.CustomerID, &o.CustomerName, &o.TotalAmount.Currency, &o.TotalAmount, &o.Status, &lat, &lon, &o.AllocationStrategy, &o.ReservationID, &o.CreatedAt)
+	if lat.Valid && lon.Valid {
+		o.ShipTo = &models.Location{Latitude: lat.Float64, Longitude: lon.Float64}
+	}
+	return o, err
+}
+
 type OrderRepository struct {
 	db *sql.DB
 }
@@ -16,9 +32,9 @@ func NewOrderRepository(database *PostgresDB) *OrderRepository {
 }
 
 // Create inserts a new order with items
-func (r *OrderRepository) Create(order *models.Order) error {
+func (r *OrderRepository) Create(ctx context.Context, order *models.Order) error {
 	// Start transaction
-	tx, err := r.db.Begin()
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
@@ -26,29 +42,34 @@ func (r *OrderRepository) Create(order *models.Order) error {
 
 	// Insert order
 	orderQuery := `
-		INSERT INTO orders (customer_name, total_amount, status)
-		VALUES ($1, $2, $3)
+		INSERT INTO orders (customer_id, customer_name, currency, total_amount, status, ship_latitude, ship_longitude, allocation_strategy, reservation_id)
+		VALUES (NULLIF($1, ''), $2, $3, $4, $5, $6, $7, NULLIF($8, ''), NULLIF($9, 0))
 		RETURNING id, created_at
 	`
-	err = tx.QueryRow(orderQuery, order.CustomerName, order.TotalAmount, order.Status).
-		Scan(&order.ID, &order.CreatedAt)
+	lat, lon := locationArgs(order.ShipTo)
+	err = tx.QueryRowContext(ctx, orderQuery, order.CustomerID, order.CustomerName, order.TotalAmount.Currency, order.TotalAmount, order.Status,
+		lat, lon, order.AllocationStrategy, order.ReservationID,
+	).Scan(&order.ID, &order.CreatedAt)
 	if err != nil {
 		return fmt.Errorf("failed to insert order: %w", err)
 	}
 
 	// Insert order items
 	itemQuery := `
-		INSERT INTO order_items (order_id, product_id, product_name, quantity, price)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO order_items (order_id, product_id, variant_id, sku, product_name, quantity, currency, price)
+		VALUES ($1, $2, NULLIF($3, 0), NULLIF($4, ''), $5, $6, $7, $8)
 		RETURNING id
 	`
 	for i := range order.Items {
 		order.Items[i].OrderID = order.ID
-		err = tx.QueryRow(itemQuery,
+		err = tx.QueryRowContext(ctx, itemQuery,
 			order.ID,
 			order.Items[i].ProductID,
+			order.Items[i].VariantID,
+			order.Items[i].SKU,
 			order.Items[i].ProductName,
 			order.Items[i].Quantity,
+			order.Items[i].Price.Currency,
 			order.Items[i].Price,
 		).Scan(&order.Items[i].ID)
 		if err != nil {
@@ -64,20 +85,57 @@ func (r *OrderRepository) Create(order *models.Order) error {
 	return nil
 }
 
-// GetAll returns all orders
-func (r *OrderRepository) GetAll() ([]models.Order, error) {
-	query := `SELECT id, customer_name, total_amount, status, created_at FROM orders ORDER BY id DESC`
+// orderSorts whitelists the fields order listings may be sorted by
+var orderSorts = map[string]sortField[models.Order]{
+	"id":           {column: "id", cast: "::int", value: func(o models.Order) string { return strconv.Itoa(o.ID) }},
+	"created_at":   {column: "created_at", cast: "::timestamp", value: func(o models.Order) string { return o.CreatedAt.Format(time.RFC3339Nano) }},
+	"total_amount": {column: "total_amount", cast: "::numeric", value: func(o models.Order) string { return o.TotalAmount.Decimal() }},
+	"status":       {column: "status", cast: "::text", value: func(o models.Order) string { return o.Status }},
+}
+
+// List returns one page of orders matching filter, plus the cursor for the
+// next page ("" on the last page)
+func (r *OrderRepository) List(ctx context.Context, filter models.OrderFilter, page models.PageRequest) ([]models.Order, string, error) {
+	var where whereBuilder
+	if filter.Status != "" {
+		where.add("status = ?", filter.Status)
+	}
+	if filter.CustomerID != nil {
+		where.add("customer_id = ?", *filter.CustomerID)
+	}
+	if filter.CreatedAfter != nil {
+		where.add("created_at >= ?", *filter.CreatedAfter)
+	}
+	if filter.CreatedBefore != nil {
+		where.add("created_at < ?", *filter.CreatedBefore)
+	}
+
+	orderBy, field, err := keyset(&where, orderSorts, page)
+	if err != nil {
+		return nil, "", err
+	}
+
+	query := "SELECT " + orderColumns + " FROM orders" + where.sql() + orderBy
 
-	rows, err := r.db.Query(query)
+	rows, err := r.db.QueryContext(ctx, query, where.args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query orders: %w", err)
+		return nil, "", fmt.Errorf("failed to query orders: %w", err)
 	}
 	defer rows.Close()
 
+	orders, err := scanOrders(rows)
+	if err != nil {
+		return nil, "", err
+	}
+
+	orders, next := trimPage(orders, field, page, func(o models.Order) int { return o.ID })
+	return orders, next, nil
+}
+
+func scanOrders(rows *sql.Rows) ([]models.Order, error) {
 	var orders []models.Order
 	for rows.Next() {
-		var o models.Order
-		err := rows.Scan(&o.ID, &o.CustomerName, &o.TotalAmount, &o.Status, &o.CreatedAt)
+		o, err := scanOrder(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan order: %w", err)
 		}
@@ -88,13 +146,11 @@ func (r *OrderRepository) GetAll() ([]models.Order, error) {
 }
 
 // GetByID returns a single order with items
-func (r *OrderRepository) GetByID(id int) (*models.Order, error) {
+func (r *OrderRepository) GetByID(ctx context.Context, id int) (*models.Order, error) {
 	// Get order
-	orderQuery := `SELECT id, customer_name, total_amount, status, created_at FROM orders WHERE id = $1`
+	orderQuery := "SELECT " + orderColumns + " FROM orders WHERE id = $1"
 
-	var order models.Order
-	err := r.db.QueryRow(orderQuery, id).
-		Scan(&order.ID, &order.CustomerName, &order.TotalAmount, &order.Status, &order.CreatedAt)
+	order, err := scanOrder(r.db.QueryRowContext(ctx, orderQuery, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -103,9 +159,9 @@ func (r *OrderRepository) GetByID(id int) (*models.Order, error) {
 	}
 
 	// Get order items
-	itemsQuery := `SELECT id, order_id, product_id, product_name, quantity, price FROM order_items WHERE order_id = $1`
+	itemsQuery := `SELECT id, order_id, product_id, COALESCE(variant_id, 0), COALESCE(sku, ''), product_name, quantity, currency, price FROM order_items WHERE order_id = $1`
 
-	rows, err := r.db.Query(itemsQuery, id)
+	rows, err := r.db.QueryContext(ctx, itemsQuery, id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query order items: %w", err)
 	}
@@ -113,21 +169,106 @@ func (r *OrderRepository) GetByID(id int) (*models.Order, error) {
 
 	for rows.Next() {
 		var item models.OrderItem
-		err := rows.Scan(&item.ID, &item.OrderID, &item.ProductID, &item.ProductName, &item.Quantity, &item.Price)
+		err := rows.Scan(&item.ID, &item.OrderID, &item.ProductID, &item.VariantID, &item.SKU, &item.ProductName, &item.Quantity, &item.Price.Currency, &item.Price)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan order item: %w", err)
 		}
 		order.Items = append(order.Items, item)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read order items: %w", err)
+	}
+
+	order.Allocations, err = r.allocations(ctx, id)
+	if err != nil {
+		return nil, err
+	}
 
 	return &order, nil
 }
 
+// allocations returns where each part of an order ships from
+func (r *OrderRepository) allocations(ctx context.Context, orderID int) ([]models.Allocation, error) {
+	query := `
+		SELECT product_id, variant_id, sku, warehouse_id, warehouse_code, quantity
+		FROM order_allocations WHERE order_id = $1 ORDER BY id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order allocations: %w", err)
+	}
+	defer rows.Close()
+
+	var allocations []models.Allocation
+	for rows.Next() {
+		var a models.Allocation
+		if err := rows.Scan(&a.ProductID, &a.VariantID, &a.SKU, &a.WarehouseID, &a.WarehouseCode, &a.Quantity); err != nil {
+			return nil, fmt.Errorf("failed to scan order allocation: %w", err)
+		}
+		allocations = append(allocations, a)
+	}
+
+	return allocations, rows.Err()
+}
+
+// SetAllocations records where an order ships from, replacing any earlier
+// record so a redelivered event does no harm. It reports whether the order exists.
+func (r *OrderRepository) SetAllocations(ctx context.Context, orderID int, allocations []models.Allocation) (bool, error) {
+	found := false
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, "SELECT true FROM orders WHERE id = $1 FOR UPDATE", orderID).Scan(&found)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, "DELETE FROM order_allocations WHERE order_id = $1", orderID); err != nil {
+			return err
+		}
+
+		query := `
+			INSERT INTO order_allocations (order_id, product_id, variant_id, sku, warehouse_id, warehouse_code, quantity)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`
+		for _, a := range allocations {
+			_, err := tx.ExecContext(ctx, query, orderID, a.ProductID, a.VariantID, a.SKU, a.WarehouseID, a.WarehouseCode, a.Quantity)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to record order allocations: %w", err)
+	}
+
+	return found, nil
+}
+
+// MarkFailed fails an order whose stock could not be allocated. Only a
+// pending order is changed, so one cancelled meanwhile stays cancelled;
+// it reports whether the order exists.
+func (r *OrderRepository) MarkFailed(ctx context.Context, id int) (bool, error) {
+	result, err := r.db.ExecContext(ctx, "UPDATE orders SET status = 'failed' WHERE id = $1 AND status = 'pending'", id)
+	if err != nil {
+		return false, fmt.Errorf("failed to fail order: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		return true, nil
+	}
+
+	order, err := r.GetByID(ctx, id)
+	return order != nil, err
+}
+
 // UpdateStatus updates order status
-func (r *OrderRepository) UpdateStatus(id int, status string) error {
+func (r *OrderRepository) UpdateStatus(ctx context.Context, id int, status string) error {
 	query := `UPDATE orders SET status = $1 WHERE id = $2`
 
-	result, err := r.db.Exec(query, status, id)
+	result, err := r.db.ExecContext(ctx, query, status, id)
 	if err != nil {
 		return fmt.Errorf("failed to update order: %w", err)
 	}
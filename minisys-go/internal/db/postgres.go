@@ -3,9 +3,11 @@ package db
 import (
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
 
+	"github.com/XSAM/otelsql"
 	_ "github.com/lib/pq"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
 )
 
 type PostgresDB struct {
@@ -18,7 +20,8 @@ func NewPostgresDB(host string, port int, user, password, dbname string) (*Postg
 		host, port, user, password, dbname,
 	)
 
-	conn, err := sql.Open("postgres", connStr)
+	// Every query made with a context becomes a child span of the caller
+	conn, err := otelsql.Open("postgres", connStr, otelsql.WithAttributes(semconv.DBSystemNamePostgreSQL))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -28,7 +31,7 @@ func NewPostgresDB(host string, port int, user, password, dbname string) (*Postg
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	log.Println("✅ Connected to PostgreSQL")
+	slog.Info("connected to PostgreSQL", "host", host, "port", port, "database", dbname)
 	return &PostgresDB{Conn: conn}, nil
 }
 
@@ -0,0 +1,383 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/models"
+)
+
+// ErrInvalidPriceRange is returned when a price would end before it starts
+var ErrInvalidPriceRange = errors.New("effective_to must be after effective_from and in the future")
+
+// ErrNoPriceAt is returned when a product had no price at the requested time
+var ErrNoPriceAt = errors.New("product had no price at that time")
+
+// priceColumns lists the columns scanPrice expects, in order
+const priceColumns = "id, product_id, currency, price, effective_from, effective_to, actor, created_at, activated_at"
+
+func scanPrice(row rowScanner) (models.ProductPrice, error) {
+	var p models.ProductPrice
+	err := row.Scan(&p.ID, &p.ProductID, &p.Price.Currency, &p.Price, &p.EffectiveFrom, &p.EffectiveTo,
+		&p.Actor, &p.CreatedAt, &p.ActivatedAt)
+	return p, err
+}
+
+// setPrice makes price a product's price from from (now when nil, or when
+// already past) until to, or until the next scheduled change when to is
+// nil. Ranges it covers are cut back or dropped, and a range it interrupts
+// resumes at to. A price starting now is marked activated, but applying it
+// to the product row is left to the caller.
+func setPrice(ctx context.Context, tx *sql.Tx, productID int, price models.Money, from, to *time.Time, actor string) (models.ProductPrice, error) {
+	if actor == "" {
+		actor = systemActor
+	}
+
+	// Timestamps are stored without a zone, in UTC
+	var start time.Time
+	var end sql.NullTime
+	if from != nil {
+		utc := from.UTC()
+		from = &utc
+	}
+	if err := tx.QueryRowContext(ctx, "SELECT GREATEST(COALESCE($1, LOCALTIMESTAMP), LOCALTIMESTAMP)", from).Scan(&start); err != nil {
+		return models.ProductPrice{}, err
+	}
+
+	if to != nil {
+		end = sql.NullTime{Time: to.UTC(), Valid: true}
+		if !end.Time.After(start) {
+			return models.ProductPrice{}, ErrInvalidPriceRange
+		}
+	} else {
+		err := tx.QueryRowContext(ctx,
+			"SELECT MIN(effective_from) FROM product_prices WHERE product_id = $1 AND effective_from > $2",
+			productID, start,
+		).Scan(&end)
+		if err != nil {
+			return models.ProductPrice{}, err
+		}
+	}
+
+	// The range in effect at start ends there, resuming at end if it would
+	// have outlasted the new price
+	var covering struct {
+		id    int
+		price models.Money
+		to    sql.NullTime
+	}
+	err := tx.QueryRowContext(ctx, `
+		SELECT id, currency, price, effective_to FROM product_prices
+		WHERE product_id = $1 AND effective_from < $2 AND (effective_to IS NULL OR effective_to > $2)
+		FOR UPDATE
+	`, productID, start).Scan(&covering.id, &covering.price.Currency, &covering.price, &covering.to)
+	if err != nil && err != sql.ErrNoRows {
+		return models.ProductPrice{}, err
+	}
+	interrupted := err == nil
+	if interrupted {
+		if _, err := tx.ExecContext(ctx, "UPDATE product_prices SET effective_to = $2 WHERE id = $1", covering.id, start); err != nil {
+			return models.ProductPrice{}, err
+		}
+	}
+
+	// Later ranges inside the new one are dropped; one reaching past its end
+	// now starts there
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM product_prices
+		WHERE product_id = $1 AND effective_from >= $2
+			AND ($3::timestamp IS NULL OR effective_to <= $3)
+	`, productID, start, end)
+	if err != nil {
+		return models.ProductPrice{}, err
+	}
+	if end.Valid {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE product_prices SET effective_from = $3, activated_at = NULL, published_at = NULL
+			WHERE product_id = $1 AND effective_from >= $2 AND effective_from < $3
+				AND (effective_to IS NULL OR effective_to > $3)
+		`, productID, start, end)
+		if err != nil {
+			return models.ProductPrice{}, err
+		}
+	}
+
+	if interrupted && end.Valid && (!covering.to.Valid || covering.to.Time.After(end.Time)) {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO product_prices (product_id, currency, price, effective_from, effective_to, actor)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, productID, covering.price.Currency, covering.price, end, covering.to, actor)
+		if err != nil {
+			return models.ProductPrice{}, err
+		}
+	}
+
+	query := `
+		INSERT INTO product_prices (product_id, currency, price, effective_from, effective_to, actor, activated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, CASE WHEN $4 <= LOCALTIMESTAMP THEN LOCALTIMESTAMP END)
+		RETURNING ` + priceColumns
+	return scanPrice(tx.QueryRowContext(ctx, query, productID, price.Currency, price, start, end, actor))
+}
+
+// recordPrice starts a new range now when a direct change leaves the
+// product at a price other than the one in effect. Later scheduled changes
+// still apply.
+func recordPrice(ctx context.Context, tx *sql.Tx, productID int, price models.Money) error {
+	var current models.Money
+	err := tx.QueryRowContext(ctx, `
+		SELECT currency, price FROM product_prices
+		WHERE product_id = $1 AND effective_from <= LOCALTIMESTAMP
+			AND (effective_to IS NULL OR effective_to > LOCALTIMESTAMP)
+	`, productID).Scan(&current.Currency, &current)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to read current price: %w", err)
+	}
+	if err == nil && current == price {
+		return nil
+	}
+
+	if _, err := setPrice(ctx, tx, productID, price, nil, nil, movementFrom(ctx).Actor); err != nil {
+		return fmt.Errorf("failed to record price: %w", err)
+	}
+	return nil
+}
+
+// applyPrice sets the product row to a price that has taken effect
+func applyPrice(ctx context.Context, tx *sql.Tx, productID int, price models.Money) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE products SET currency = $2, price = $3, version = version + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND (currency <> $2 OR price <> $3::numeric)
+	`, productID, price.Currency, price)
+	return err
+}
+
+type PriceRepository struct {
+	db *sql.DB
+}
+
+func NewPriceRepository(database *PostgresDB) *PriceRepository {
+	return &PriceRepository{db: database.Conn}
+}
+
+// priceSorts whitelists the fields price history may be sorted by
+var priceSorts = map[string]sortField[models.ProductPrice]{
+	"id":             {column: "id", cast: "::int", value: func(p models.ProductPrice) string { return strconv.Itoa(p.ID) }},
+	"effective_from": {column: "effective_from", cast: "::timestamp", value: func(p models.ProductPrice) string { return p.EffectiveFrom.Format(time.RFC3339Nano) }},
+}
+
+// History returns one page of a product's price ranges, scheduled ones
+// included, plus the cursor for the next page ("" on the last page)
+func (r *PriceRepository) History(ctx context.Context, productID int, page models.PageRequest) ([]models.ProductPrice, string, error) {
+	var where whereBuilder
+	where.add("product_id = ?", productID)
+
+	orderBy, field, err := keyset(&where, priceSorts, page)
+	if err != nil {
+		return nil, "", err
+	}
+
+	query := "SELECT " + priceColumns + " FROM product_prices" + where.sql() + orderBy
+
+	rows, err := r.db.QueryContext(ctx, query, where.args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to query price history: %w", err)
+	}
+	defer rows.Close()
+
+	var prices []models.ProductPrice
+	for rows.Next() {
+		p, err := scanPrice(rows)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to scan price: %w", err)
+		}
+		prices = append(prices, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	prices, next := trimPage(prices, field, page, func(p models.ProductPrice) int { return p.ID })
+	return prices, next, nil
+}
+
+// Schedule sets a product's price for a range; see SchedulePriceRequest.
+// A price starting now is applied straight away, later ones by the price
+// scheduler. A missing product yields nil, nil.
+func (r *PriceRepository) Schedule(ctx context.Context, productID int, req models.SchedulePriceRequest, actor string) (*models.ProductPrice, error) {
+	var price *models.ProductPrice
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, "SELECT id FROM products WHERE id = $1 FOR UPDATE", productID).Scan(&productID)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		p, err := setPrice(ctx, tx, productID, req.Price, req.EffectiveFrom, req.EffectiveTo, actor)
+		if err != nil {
+			return err
+		}
+		price = &p
+
+		if p.ActivatedAt == nil {
+			return nil
+		}
+		return applyPrice(ctx, tx, productID, p.Price)
+	})
+	if errors.Is(err, ErrInvalidPriceRange) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to schedule price: %w", err)
+	}
+
+	return price, nil
+}
+
+// Activate applies up to limit scheduled prices that have come due and
+// returns the products whose price changed. A due price whose range has
+// already ended, because activation fell behind, is skipped without an
+// event. Prices of products being changed right now are left for the
+// next call.
+func (r *PriceRepository) Activate(ctx context.Context, limit int) ([]int, error) {
+	var changed []int
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, `
+			SELECT pp.id, pp.product_id, pp.currency, pp.price,
+				pp.effective_to IS NULL OR pp.effective_to > LOCALTIMESTAMP
+			FROM product_prices pp
+			JOIN products p ON p.id = pp.product_id
+			WHERE pp.activated_at IS NULL AND pp.effective_from <= LOCALTIMESTAMP
+			ORDER BY pp.effective_from, pp.id
+			LIMIT $1
+			FOR UPDATE OF pp, p SKIP LOCKED
+		`, limit)
+		if err != nil {
+			return err
+		}
+
+		type duePrice struct {
+			id, productID int
+			price         models.Money
+			current       bool
+		}
+		var due []duePrice
+		for rows.Next() {
+			var d duePrice
+			if err := rows.Scan(&d.id, &d.productID, &d.price.Currency, &d.price, &d.current); err != nil {
+				rows.Close()
+				return err
+			}
+			due = append(due, d)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, d := range due {
+			if d.current {
+				if err := applyPrice(ctx, tx, d.productID, d.price); err != nil {
+					return err
+				}
+				changed = append(changed, d.productID)
+			}
+
+			_, err := tx.ExecContext(ctx, `
+				UPDATE product_prices SET activated_at = LOCALTIMESTAMP,
+					published_at = CASE WHEN $2 THEN NULL ELSE LOCALTIMESTAMP END
+				WHERE id = $1
+			`, d.id, d.current)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to activate prices: %w", err)
+	}
+
+	return changed, nil
+}
+
+// PublishPending hands up to limit activated but unannounced price
+// changes, oldest first, to publish and marks each one published once
+// publish succeeds. It stops at the first failure so the rest are retried
+// on the next call, and returns how many were published.
+func (r *PriceRepository) PublishPending(ctx context.Context, limit int, publish func(models.ProductPriceChangedEvent) error) (int, error) {
+	published := 0
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		query := `
+			SELECT pp.id, pp.product_id, pp.currency, pp.price, pp.effective_from, pp.effective_to,
+				pp.activated_at, prev.currency, prev.price
+			FROM product_prices pp
+			LEFT JOIN product_prices prev
+				ON prev.product_id = pp.product_id AND prev.effective_to = pp.effective_from
+			WHERE pp.id IN (
+				SELECT id FROM product_prices
+				WHERE activated_at IS NOT NULL AND published_at IS NULL
+				ORDER BY id
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED
+			)
+			ORDER BY pp.id
+		`
+
+		rows, err := tx.QueryContext(ctx, query, limit)
+		if err != nil {
+			return err
+		}
+		var ids []int
+		var pending []models.ProductPriceChangedEvent
+		for rows.Next() {
+			var id int
+			var e models.ProductPriceChangedEvent
+			var prevCurrency, prevPrice sql.NullString
+			err := rows.Scan(&id, &e.ProductID, &e.Price.Currency, &e.Price, &e.EffectiveFrom, &e.EffectiveTo,
+				&e.ChangedAt, &prevCurrency, &prevPrice)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			if prevPrice.Valid {
+				previous, err := models.ParseMoney(prevPrice.String, prevCurrency.String)
+				if err != nil {
+					rows.Close()
+					return err
+				}
+				e.PreviousPrice = &previous
+			}
+			ids = append(ids, id)
+			pending = append(pending, e)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for i, e := range pending {
+			if err := publish(e); err != nil {
+				break
+			}
+			published = i + 1
+		}
+
+		_, err = tx.ExecContext(ctx,
+			"UPDATE product_prices SET published_at = CURRENT_TIMESTAMP WHERE id = ANY($1)",
+			pq.Array(ids[:published]),
+		)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to publish price changes: %w", err)
+	}
+
+	return published, nil
+}
@@ -1,12 +1,70 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
 
 	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/models"
 )
 
+// ErrVersionConflict is returned when a conditional update names a version
+// that is no longer current
+var ErrVersionConflict = errors.New("product was modified concurrently")
+
+// ErrProductInUse is returned when purging a product that open orders or
+// active reservations still refer to
+var ErrProductInUse = errors.New("product is referenced by open orders or reservations")
+
+// ErrNotDeleted is returned when purging a product that has not been deleted first
+var ErrNotDeleted = errors.New("product must be deleted before it is purged")
+
+// ErrNotDefaultSKU is returned when an import row names the SKU of a
+// variant other than a product's default
+var ErrNotDefaultSKU = errors.New("sku belongs to a variant, not to a product's default")
+
+// ErrProductDeleted is returned when an update or import row names a
+// deleted product
+var ErrProductDeleted = errors.New("product is deleted; restore it first")
+
+// ErrUnknownCategory is returned when a product names a category that does not exist
+var ErrUnknownCategory = errors.New("category does not exist")
+
+// productWriteError maps a missing category to ErrUnknownCategory
+func productWriteError(action string, err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code.Name() == "foreign_key_violation" {
+		return ErrUnknownCategory
+	}
+	return fmt.Errorf("failed to %s product: %w", action, err)
+}
+
+// productColumns lists the columns scanProduct expects, in order. The
+// currency must come before the price so Money knows its minor units.
+const productColumns = "id, name, currency, price, quantity, quantity - reserved, category_id, tags, attributes, version, created_at, COALESCE(updated_at, created_at), deleted_at"
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// productDest returns scan targets matching productColumns
+func productDest(p *models.Product) []any {
+	return []any{&p.ID, &p.Name, &p.Price.Currency, &p.Price, &p.Quantity, &p.Available, &p.CategoryID, pq.Array(&p.Tags), &p.Attributes, &p.Version, &p.CreatedAt, &p.UpdatedAt, &p.DeletedAt}
+}
+
+func scanProduct(row rowScanner) (models.Product, error) {
+	var p models.Product
+	err := row.Scan(productDest(&p)...)
+	return p, err
+}
+
 type ProductRepository struct {
 	db *sql.DB
 }
@@ -15,35 +73,85 @@ func NewProductRepository(database *PostgresDB) *ProductRepository {
 	return &ProductRepository{db: database.Conn}
 }
 
-// GetAll returns all products
-func (r *ProductRepository) GetAll() ([]models.Product, error) {
-	query := "SELECT id, name, price, quantity, created_at FROM products ORDER BY id"
+// productSorts whitelists the fields product listings may be sorted by
+var productSorts = map[string]sortField[models.Product]{
+	"id":         {column: "id", cast: "::int", value: func(p models.Product) string { return strconv.Itoa(p.ID) }},
+	"name":       {column: "name", cast: "::text", value: func(p models.Product) string { return p.Name }},
+	"price":      {column: "price", cast: "::numeric", value: func(p models.Product) string { return p.Price.Decimal() }},
+	"created_at": {column: "created_at", cast: "::timestamp", value: func(p models.Product) string { return p.CreatedAt.Format(time.RFC3339Nano) }},
+}
 
-	rows, err := r.db.Query(query)
+// List returns one page of products matching filter, plus the cursor for
+// the next page ("" on the last page). Deleted products are not listed.
+func (r *ProductRepository) List(ctx context.Context, filter models.ProductFilter, page models.PageRequest) ([]models.Product, string, error) {
+	var where whereBuilder
+	where.add("deleted_at IS NULL")
+	if filter.MinPrice != nil {
+		where.add("currency = ? AND price >= ?", filter.MinPrice.Currency, filter.MinPrice)
+	}
+	if filter.MaxPrice != nil {
+		where.add("currency = ? AND price <= ?", filter.MaxPrice.Currency, filter.MaxPrice)
+	}
+	if filter.InStock {
+		where.add("quantity - reserved > 0")
+	}
+	if filter.CreatedAfter != nil {
+		where.add("created_at >= ?", *filter.CreatedAfter)
+	}
+	if filter.CreatedBefore != nil {
+		where.add("created_at < ?", *filter.CreatedBefore)
+	}
+	if filter.CategoryID != nil {
+		where.add("category_id IN ("+categorySubtree("?")+")", *filter.CategoryID)
+	}
+	if len(filter.Tags) > 0 {
+		where.add("tags @> ?", pq.Array(filter.Tags))
+	}
+	for _, name := range sortedKeys(filter.Attributes) {
+		where.add("attributes->>? = ?", name, filter.Attributes[name])
+	}
+
+	orderBy, field, err := keyset(&where, productSorts, page)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query products: %w", err)
+		return nil, "", err
+	}
+
+	query := "SELECT " + productColumns + " FROM products" + where.sql() + orderBy
+
+	rows, err := r.db.QueryContext(ctx, query, where.args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to query products: %w", err)
 	}
 	defer rows.Close()
 
+	products, err := scanProducts(rows)
+	if err != nil {
+		return nil, "", err
+	}
+
+	products, next := trimPage(products, field, page, func(p models.Product) int { return p.ID })
+	return products, next, nil
+}
+
+func scanProducts(rows *sql.Rows) ([]models.Product, error) {
 	var products []models.Product
 	for rows.Next() {
-		var p models.Product
-		err := rows.Scan(&p.ID, &p.Name, &p.Price, &p.Quantity, &p.CreatedAt)
+		p, err := scanProduct(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan product: %w", err)
 		}
 		products = append(products, p)
 	}
 
-	return products, nil
+	return products, rows.Err()
 }
 
-// GetByID returns a single product
-func (r *ProductRepository) GetByID(id int) (*models.Product, error) {
-	query := "SELECT id, name, price, quantity, created_at FROM products WHERE id = $1"
+// GetByID returns a single product, deleted or not, so that orders can
+// still resolve the products they refer to
+func (r *ProductRepository) GetByID(ctx context.Context, id int) (*models.Product, error) {
+	query := "SELECT " + productColumns + " FROM products WHERE id = $1"
 
-	var p models.Product
-	err := r.db.QueryRow(query, id).Scan(&p.ID, &p.Name, &p.Price, &p.Quantity, &p.CreatedAt)
+	p, err := scanProduct(r.db.QueryRowContext(ctx, query, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -54,54 +162,521 @@ func (r *ProductRepository) GetByID(id int) (*models.Product, error) {
 	return &p, nil
 }
 
-// Create inserts a new product
-func (r *ProductRepository) Create(req models.CreateProductRequest) (*models.Product, error) {
+// GetByIDAt returns a product as GetByID does, but priced as it was at
+// at. ErrNoPriceAt is returned if it had no price then, e.g. because it
+// did not exist yet.
+func (r *ProductRepository) GetByIDAt(ctx context.Context, id int, at time.Time) (*models.Product, error) {
+	p, err := r.GetByID(ctx, id)
+	if err != nil || p == nil {
+		return p, err
+	}
+
 	query := `
-		INSERT INTO products (name, price, quantity)
-		VALUES ($1, $2, $3)
-		RETURNING id, name, price, quantity, created_at
+		SELECT currency, price FROM product_prices
+		WHERE product_id = $1 AND effective_from <= $2 AND (effective_to IS NULL OR effective_to > $2)
 	`
+	err = r.db.QueryRowContext(ctx, query, id, at.UTC()).Scan(&p.Price.Currency, &p.Price)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoPriceAt
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get price: %w", err)
+	}
+
+	return p, nil
+}
+
+// GetByIDs returns the products with the given IDs in a single query,
+// including deleted ones; IDs that do not exist are simply absent from the
+// result
+func (r *ProductRepository) GetByIDs(ctx context.Context, ids []int) ([]models.Product, error) {
+	query := "SELECT " + productColumns + " FROM products WHERE id = ANY($1) ORDER BY id"
 
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query products: %w", err)
+	}
+	defer rows.Close()
+
+	return scanProducts(rows)
+}
+
+// Search ranks live products whose name matches q, either as full-text terms
+// or, to tolerate typos, by trigram similarity. Highlight marks the
+// matched terms with <mark> tags in the otherwise HTML-escaped name.
+func (r *ProductRepository) Search(ctx context.Context, q string, limit int) ([]models.ProductSearchResult, error) {
+	query := `
+		SELECT ` + productColumns + `,
+			ts_rank(search_vector, tsq) + word_similarity($1, name) AS rank,
+			ts_headline('english', translate(name, $3, ''), tsq, $4) AS highlight
+		FROM products, websearch_to_tsquery('english', $1) AS tsq
+		WHERE (search_vector @@ tsq OR name % $1 OR $1 <% name) AND deleted_at IS NULL
+		ORDER BY rank DESC, id
+		LIMIT $2
+	`
+
+	// Matches are delimited with characters stripped from the name first,
+	// so the name can be HTML-escaped before they become <mark> tags
+	options := "StartSel=" + highlightStart + ", StopSel=" + highlightStop + ", HighlightAll=true"
+	rows, err := r.db.QueryContext(ctx, query, q, limit, highlightStart+highlightStop, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search products: %w", err)
+	}
+	defer rows.Close()
+
+	var results []models.ProductSearchResult
+	for rows.Next() {
+		var result models.ProductSearchResult
+		p := &result.Product
+		err := rows.Scan(append(productDest(p), &result.Rank, &result.Highlight)...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan search result: %w", err)
+		}
+		result.Highlight = highlightHTML(result.Highlight)
+		results = append(results, result)
+	}
+
+	return results, rows.Err()
+}
+
+// highlightStart and highlightStop delimit search matches in private-use
+// characters that product names cannot carry into a highlight
+const (
+	highlightStart = "\uE000"
+	highlightStop  = "\uE001"
+)
+
+// highlightHTML escapes a delimited highlight and marks its matches, so a
+// product name can never inject markup
+func highlightHTML(s string) string {
+	s = html.EscapeString(s)
+	return strings.NewReplacer(highlightStart, "<mark>", highlightStop, "</mark>").Replace(s)
+}
+
+// Create inserts a new product together with its default variant, which
+// holds the initial stock
+func (r *ProductRepository) Create(ctx context.Context, req models.CreateProductRequest) (*models.Product, error) {
 	var p models.Product
-	err := r.db.QueryRow(query, req.Name, req.Price, req.Quantity).
-		Scan(&p.ID, &p.Name, &p.Price, &p.Quantity, &p.CreatedAt)
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		var err error
+		p, err = createProduct(ctx, tx, req)
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create product: %w", err)
+		return nil, err
 	}
 
 	return &p, nil
 }
 
-// Delete removes a product
-func (r *ProductRepository) Delete(id int) error {
-	query := "DELETE FROM products WHERE id = $1"
+func createProduct(ctx context.Context, tx *sql.Tx, req models.CreateProductRequest) (models.Product, error) {
+	query := `
+		INSERT INTO products (name, currency, price, quantity, category_id, tags, attributes, search_vector)
+		VALUES ($1, $2, $3, $4, $5, COALESCE($6, '{}'), $7, to_tsvector('english', $1))
+		RETURNING ` + productColumns
+
+	p, err := scanProduct(tx.QueryRowContext(ctx, query,
+		req.Name, req.Price.Currency, req.Price, req.Quantity, req.CategoryID, pq.Array(req.Tags), req.Attributes,
+	))
+	if err != nil {
+		return p, productWriteError("create", err)
+	}
 
-	result, err := r.db.Exec(query, id)
+	sku := req.SKU
+	if sku == "" {
+		sku = defaultSKU(p.ID)
+	}
+	var variantID int
+	err = tx.QueryRowContext(ctx,
+		"INSERT INTO product_variants (product_id, sku, is_default) VALUES ($1, $2, TRUE) RETURNING id",
+		p.ID, sku,
+	).Scan(&variantID)
 	if err != nil {
-		return fmt.Errorf("failed to delete product: %w", err)
+		return p, variantWriteError("create", err)
 	}
 
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
-		return fmt.Errorf("product not found")
+	// The opening price is history from the start but not a change worth
+	// announcing
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO product_prices (product_id, currency, price, effective_from, actor, activated_at, published_at)
+		VALUES ($1, $2, $3, LOCALTIMESTAMP, $4, LOCALTIMESTAMP, LOCALTIMESTAMP)
+	`, p.ID, p.Price.Currency, p.Price, movementFrom(ctx).Actor)
+	if err != nil {
+		return p, fmt.Errorf("failed to record price: %w", err)
 	}
 
-	return nil
+	return p, adjustWarehouseStock(ctx, tx, 0, variantID, req.Quantity)
 }
 
-// UpdateQuantity updates product inventory
-func (r *ProductRepository) UpdateQuantity(id int, quantityChange int) error {
-	query := `UPDATE products SET quantity = quantity + $1 WHERE id = $2 AND quantity + $1 >= 0`
+// Update applies the non-nil fields of patch and bumps the version. If
+// expectedVersion is non-zero the update only succeeds against that
+// version, otherwise ErrVersionConflict is returned. A deleted product
+// returns ErrProductDeleted; a missing one yields nil, nil.
+func (r *ProductRepository) Update(ctx context.Context, id int, patch models.PatchProductRequest, expectedVersion int) (*models.Product, error) {
+	var currency *string
+	if patch.Price != nil {
+		currency = &patch.Price.Currency
+	}
+	var tags any
+	if patch.Tags != nil {
+		tags = pq.Array(*patch.Tags)
+	}
+
+	query := `
+		UPDATE products SET
+			name = COALESCE($2, name),
+			search_vector = to_tsvector('english', COALESCE($2, name)),
+			currency = COALESCE($3, currency),
+			price = COALESCE($4, price),
+			quantity = COALESCE($5, quantity),
+			category_id = CASE WHEN $7::int IS NULL THEN category_id ELSE NULLIF($7::int, 0) END,
+			tags = COALESCE($8, tags),
+			attributes = COALESCE($9::jsonb, attributes),
+			version = version + 1,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND deleted_at IS NULL AND ($6 = 0 OR version = $6)
+		RETURNING ` + productColumns
 
-	result, err := r.db.Exec(query, quantityChange, id)
+	var p models.Product
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		var err error
+		p, err = scanProduct(tx.QueryRowContext(ctx, query,
+			id, patch.Name, currency, patch.Price, patch.Quantity, expectedVersion,
+			patch.CategoryID, tags, patch.Attributes,
+		))
+		if err != nil {
+			return err
+		}
+		if patch.Price != nil {
+			if err := recordPrice(ctx, tx, id, p.Price); err != nil {
+				return err
+			}
+		}
+		if patch.Quantity == nil {
+			return nil
+		}
+		return setDefaultStock(ctx, tx, id, *patch.Quantity)
+	})
+	if err == nil {
+		return &p, nil
+	}
+	if err != sql.ErrNoRows {
+		if errors.Is(err, ErrHasVariants) || errors.Is(err, ErrInsufficientStock) {
+			return nil, err
+		}
+		return nil, productWriteError("update", err)
+	}
+
+	// Nothing matched: the product is gone or archived, or the version moved on
+	existing, err := r.GetByID(ctx, id)
+	if err != nil || existing == nil {
+		return nil, err
+	}
+	if existing.DeletedAt != nil {
+		return nil, ErrProductDeleted
+	}
+	return nil, ErrVersionConflict
+}
+
+// setDefaultStock sets product-level stock, which lives on the default
+// variant. Once the product has other variants the total cannot be split
+// between them, so only restating the current total is allowed.
+func setDefaultStock(ctx context.Context, tx *sql.Tx, productID, quantity int) error {
+	query := `
+		SELECT COALESCE(SUM(quantity), 0),
+			COUNT(*) FILTER (WHERE NOT is_default),
+			COALESCE(MAX(id) FILTER (WHERE is_default), 0)
+		FROM product_variants WHERE product_id = $1
+	`
+
+	var total, others, defaultID int
+	if err := tx.QueryRowContext(ctx, query, productID).Scan(&total, &others, &defaultID); err != nil {
+		return err
+	}
+	if total == quantity {
+		return nil
+	}
+	if others > 0 || defaultID == 0 {
+		return ErrHasVariants
+	}
+
+	return setVariantStock(ctx, tx, defaultID, quantity)
+}
+
+// Delete archives a product: it keeps its row, stock and history for the
+// orders that refer to it, but is no longer listed or orderable. Open stock
+// alerts are resolved since an archived product is not restocked. Deleting
+// a missing or already deleted product yields nil, nil.
+func (r *ProductRepository) Delete(ctx context.Context, id int) (*models.Product, error) {
+	query := `
+		UPDATE products SET deleted_at = CURRENT_TIMESTAMP, version = version + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING ` + productColumns
+
+	var p models.Product
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		var err error
+		p, err = scanProduct(tx.QueryRowContext(ctx, query, id))
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx,
+			"UPDATE stock_alerts SET resolved_at = CURRENT_TIMESTAMP WHERE product_id = $1 AND resolved_at IS NULL",
+			id,
+		)
+		return err
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to delete product: %w", err)
+	}
+
+	return &p, nil
+}
+
+// Restore brings a deleted product back into listings and new orders,
+// reporting whether it was deleted. Restoring a product that is not
+// deleted returns it unchanged; a missing product yields nil, false, nil.
+func (r *ProductRepository) Restore(ctx context.Context, id int) (*models.Product, bool, error) {
+	query := `
+		UPDATE products SET deleted_at = NULL, version = version + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND deleted_at IS NOT NULL
+		RETURNING ` + productColumns
+
+	p, err := scanProduct(r.db.QueryRowContext(ctx, query, id))
+	if err == nil {
+		return &p, true, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, false, fmt.Errorf("failed to restore product: %w", err)
+	}
+
+	existing, err := r.GetByID(ctx, id)
+	return existing, false, err
+}
+
+// Purge removes a deleted product for good, together with its variants,
+// stock and alerts; the ledger keeps its movements. It refuses with
+// ErrProductInUse while an order that is neither delivered nor cancelled,
+// or an active reservation, refers to the product. It reports whether the
+// product existed.
+func (r *ProductRepository) Purge(ctx context.Context, id int) (bool, error) {
+	found := false
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		var deleted bool
+		err := tx.QueryRowContext(ctx,
+			"SELECT deleted_at IS NOT NULL FROM products WHERE id = $1 FOR UPDATE", id,
+		).Scan(&deleted)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		found = true
+		if !deleted {
+			return ErrNotDeleted
+		}
+
+		query := `
+			SELECT EXISTS (
+				SELECT 1 FROM order_items i JOIN orders o ON o.id = i.order_id
+				WHERE i.product_id = $1 AND o.status NOT IN ('delivered', 'cancelled')
+			) OR EXISTS (
+				SELECT 1 FROM reservation_items i JOIN reservations r ON r.id = i.reservation_id
+				WHERE i.product_id = $1 AND r.status = 'active'
+			)
+		`
+		var inUse bool
+		if err := tx.QueryRowContext(ctx, query, id).Scan(&inUse); err != nil {
+			return err
+		}
+		if inUse {
+			return ErrProductInUse
+		}
+
+		_, err = tx.ExecContext(ctx, "DELETE FROM products WHERE id = $1", id)
+		return err
+	})
+	if errors.Is(err, ErrNotDeleted) || errors.Is(err, ErrProductInUse) {
+		return found, err
+	}
+	if err != nil {
+		return found, fmt.Errorf("failed to purge product: %w", err)
+	}
+
+	return found, nil
+}
+
+// errDryRun rolls back a dry-run import
+var errDryRun = errors.New("dry run")
+
+// ImportOutcome is what an import did with one row
+type ImportOutcome struct {
+	Product models.Product // as written; zero if the row was skipped
+	Created bool
+	Err     error // why the row was skipped
+}
+
+// Import upserts a batch of catalog rows in one transaction, matching
+// products by the SKU of their default variant. A failing row is rolled
+// back on its own and reported in its outcome while the rest of the batch
+// applies. A dry run rolls the whole transaction back, though IDs it
+// allocated stay used.
+func (r *ProductRepository) Import(ctx context.Context, rows []models.CatalogRow, dryRun bool) ([]ImportOutcome, error) {
+	outcomes := make([]ImportOutcome, len(rows))
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		for i, row := range rows {
+			if _, err := tx.ExecContext(ctx, "SAVEPOINT import_row"); err != nil {
+				return err
+			}
+
+			product, created, rowErr := importRow(ctx, tx, row)
+			release := "RELEASE SAVEPOINT import_row"
+			if rowErr != nil {
+				release = "ROLLBACK TO SAVEPOINT import_row"
+			}
+			if _, err := tx.ExecContext(ctx, release); err != nil {
+				return err
+			}
+			outcomes[i] = ImportOutcome{Product: product, Created: created, Err: rowErr}
+		}
+
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && err != errDryRun {
+		return nil, fmt.Errorf("failed to import products: %w", err)
+	}
+
+	return outcomes, nil
+}
+
+// importRow creates the product a row describes, or replaces the one whose
+// default variant has the row's SKU, returning it as written and whether
+// it was created
+func importRow(ctx context.Context, tx *sql.Tx, row models.CatalogRow) (models.Product, bool, error) {
+	query := `
+		SELECT v.product_id, v.is_default, p.deleted_at IS NOT NULL
+		FROM product_variants v JOIN products p ON p.id = v.product_id
+		WHERE v.sku = $1
+		FOR UPDATE OF p
+	`
+
+	var id int
+	var isDefault, deleted bool
+	err := tx.QueryRowContext(ctx, query, row.SKU).Scan(&id, &isDefault, &deleted)
+	if err == sql.ErrNoRows {
+		quantity := 0
+		if row.Quantity != nil {
+			quantity = *row.Quantity
+		}
+		source := movementFrom(ctx)
+		source.Reason = models.MovementInitial
+
+		p, err := createProduct(WithMovement(ctx, source), tx, models.CreateProductRequest{
+			Name:       row.Name,
+			Price:      row.Price,
+			Quantity:   quantity,
+			CategoryID: row.CategoryID,
+			Tags:       row.Tags,
+			Attributes: row.Attributes,
+			SKU:        row.SKU,
+		})
+		return p, true, err
+	}
+	if err != nil {
+		return models.Product{}, false, fmt.Errorf("failed to look up sku: %w", err)
+	}
+	if !isDefault {
+		return models.Product{}, false, ErrNotDefaultSKU
+	}
+	if deleted {
+		return models.Product{}, false, ErrProductDeleted
+	}
+
+	tags := row.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE products SET
+			name = $2,
+			search_vector = to_tsvector('english', $2),
+			currency = $3,
+			price = $4,
+			category_id = $5,
+			tags = $6,
+			attributes = $7,
+			version = version + 1,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`, id, row.Name, row.Price.Currency, row.Price, row.CategoryID, pq.Array(tags), row.Attributes)
 	if err != nil {
-		return fmt.Errorf("failed to update quantity: %w", err)
+		return models.Product{}, false, productWriteError("update", err)
+	}
+	if err := recordPrice(ctx, tx, id, row.Price); err != nil {
+		return models.Product{}, false, err
 	}
 
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
-		return fmt.Errorf("product not found or insufficient inventory")
+	if row.Quantity != nil {
+		if err := setDefaultStock(ctx, tx, id, *row.Quantity); err != nil {
+			return models.Product{}, false, err
+		}
+	}
+	p, err := scanProduct(tx.QueryRowContext(ctx, "SELECT "+productColumns+" FROM products WHERE id = $1", id))
+	if err != nil {
+		return models.Product{}, false, fmt.Errorf("failed to read imported product: %w", err)
 	}
+	return p, false, nil
+}
+
+// Export hands every product that is not deleted to fn as a catalog row,
+// in ID order, streaming them from a single query. It stops at the first
+// error fn returns.
+func (r *ProductRepository) Export(ctx context.Context, fn func(models.CatalogRow) error) error {
+	query := `
+		SELECT v.sku, p.name, p.currency, p.price, p.quantity, p.category_id, p.tags, p.attributes
+		FROM products p JOIN product_variants v ON v.product_id = p.id AND v.is_default
+		WHERE p.deleted_at IS NULL
+		ORDER BY p.id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to export products: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row models.CatalogRow
+		var quantity int
+		err := rows.Scan(&row.SKU, &row.Name, &row.Price.Currency, &row.Price, &quantity,
+			&row.CategoryID, pq.Array(&row.Tags), &row.Attributes)
+		if err != nil {
+			return fmt.Errorf("failed to scan product: %w", err)
+		}
+		row.Quantity = &quantity
+
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
 
-	return nil
+// UpdateQuantity changes product-level inventory, which is the stock of
+// the product's default variant. Clients that know about SKUs use
+// VariantRepository.UpdateQuantity instead.
+func (r *ProductRepository) UpdateQuantity(ctx context.Context, id int, quantityChange int) error {
+	return inTx(ctx, r.db, func(tx *sql.Tx) error {
+		_, err := adjustStock(ctx, tx, "product_id = $1 AND is_default", id, quantityChange)
+		return err
+	})
 }
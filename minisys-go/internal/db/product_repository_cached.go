@@ -2,12 +2,19 @@ package db
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/cache"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/metrics"
 	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/models"
-	"github.com/redis/go-redis/v9"
 )
 
 type CachedProductRepository struct {
@@ -27,101 +34,347 @@ func productKey(id int) string {
 	return fmt.Sprintf("product:%d", id)
 }
 
-func allProductsKey() string {
-	return "products:all"
+// productVariantsKey holds a product's variants; it sits under the
+// product so every product invalidation drops it too
+func productVariantsKey(id int) string {
+	return fmt.Sprintf("product:%d:variants", id)
 }
 
-// GetAll returns all products (with caching)
-func (r *CachedProductRepository) GetAll(ctx context.Context) ([]models.Product, error) {
-	cacheKey := allProductsKey()
+// productListVersionKey counts changes to any product. Every listing page
+// is keyed on it, so bumping it drops them all without scanning for them.
+const productListVersionKey = "products:list:version"
 
-	// Try cache first
-	var products []models.Product
-	err := r.cache.Get(ctx, cacheKey, &products)
-	if err == nil {
-		log.Println("📦 Cache HIT: all products")
-		return products, nil
+// categoryListVersionKey counts changes to a category; pages filtered by
+// it are keyed on this as well
+func categoryListVersionKey(id int) string {
+	return fmt.Sprintf("products:list:category:%d:version", id)
+}
+
+// productListKey identifies one listing query shape under the current
+// list versions. The normalized parameters are hashed so keys stay short
+// whatever the filters are.
+func (r *CachedProductRepository) productListKey(ctx context.Context, filter models.ProductFilter, page models.PageRequest) (string, error) {
+	versionKeys := []string{productListVersionKey}
+	if filter.CategoryID != nil {
+		versionKeys = append(versionKeys, categoryListVersionKey(*filter.CategoryID))
+	}
+	versions, err := r.cache.Versions(ctx, versionKeys...)
+	if err != nil {
+		return "", err
+	}
+
+	key := fmt.Sprintf("products:list:v%d:", versions[0])
+	if filter.CategoryID != nil {
+		key += fmt.Sprintf("category:%d:v%d:", *filter.CategoryID, versions[1])
+	}
+	return key + productListHash(filter, page), nil
+}
+
+// productListHash digests the parameters of a listing query
+func productListHash(filter models.ProductFilter, page models.PageRequest) string {
+	params := url.Values{}
+	params.Set("limit", strconv.Itoa(page.Limit))
+	params.Set("sort", page.Sort)
+	params.Set("cursor", page.Cursor)
+	if filter.MinPrice != nil {
+		params.Set("min_price", filter.MinPrice.String())
+	}
+	if filter.MaxPrice != nil {
+		params.Set("max_price", filter.MaxPrice.String())
 	}
+	if filter.InStock {
+		params.Set("in_stock", "true")
+	}
+	if filter.CreatedAfter != nil {
+		params.Set("created_after", filter.CreatedAfter.UTC().Format(time.RFC3339Nano))
+	}
+	if filter.CreatedBefore != nil {
+		params.Set("created_before", filter.CreatedBefore.UTC().Format(time.RFC3339Nano))
+	}
+
+	if len(filter.Tags) > 0 {
+		params.Set("tags", strings.Join(filter.Tags, ","))
+	}
+	for name, value := range filter.Attributes {
+		params.Set("attr."+name, value)
+	}
+
+	sum := sha256.Sum256([]byte(params.Encode()))
+	return hex.EncodeToString(sum[:8])
+}
+
+// productListEntry is one cached listing page
+type productListEntry struct {
+	Products   []models.Product `json:"products"`
+	NextCursor string           `json:"next_cursor"`
+}
 
-	// Cache miss - get from database
-	log.Println("💾 Cache MISS: all products - fetching from DB")
-	products, err = r.repo.GetAll()
+// List returns one page of products (with caching per query shape)
+func (r *CachedProductRepository) List(ctx context.Context, filter models.ProductFilter, page models.PageRequest) ([]models.Product, string, error) {
+	cacheKey, err := r.productListKey(ctx, filter, page)
 	if err != nil {
-		return nil, err
+		// Without the versions a cached page cannot be trusted
+		slog.WarnContext(ctx, "cache error", "key", productListVersionKey, "error", err)
+		metrics.RecordCacheLookup("products", metrics.CacheError)
+		return r.repo.List(ctx, filter, page)
 	}
 
-	// Store in cache
-	if err := r.cache.Set(ctx, cacheKey, products); err != nil {
-		log.Printf("⚠️ Failed to cache products: %v", err)
+	var entry productListEntry
+	_, lookup, err := r.cache.Fetch(ctx, cacheKey, &entry, func(ctx context.Context) (interface{}, error) {
+		slog.DebugContext(ctx, "cache miss", "key", cacheKey)
+		products, next, err := r.repo.List(ctx, filter, page)
+		if err != nil {
+			return nil, err
+		}
+		return productListEntry{Products: products, NextCursor: next}, nil
+	})
+	metrics.RecordCacheLookup("products", lookup)
+	if err != nil {
+		return nil, "", err
 	}
 
-	return products, nil
+	return entry.Products, entry.NextCursor, nil
 }
 
-// GetByID returns a single product (with caching)
+// GetByID returns a single product (with caching). Missing products are
+// cached briefly too, so lookups of unknown IDs do not all reach the
+// database.
 func (r *CachedProductRepository) GetByID(ctx context.Context, id int) (*models.Product, error) {
 	cacheKey := productKey(id)
 
-	// Try cache first
 	var product models.Product
-	err := r.cache.Get(ctx, cacheKey, &product)
-	if err == nil {
-		log.Printf("📦 Cache HIT: product %d", id)
-		return &product, nil
+	found, lookup, err := r.cache.Fetch(ctx, cacheKey, &product, func(ctx context.Context) (interface{}, error) {
+		slog.DebugContext(ctx, "cache miss", "key", cacheKey, "product_id", id)
+		return r.repo.GetByID(ctx, id)
+	})
+	metrics.RecordCacheLookup("product", lookup)
+	if err != nil || !found {
+		return nil, err
 	}
 
-	if err != redis.Nil {
-		log.Printf("⚠️ Cache error: %v", err)
+	return &product, nil
+}
+
+// GetByIDs returns several products, reading hits with one MGET and
+// loading all misses with one query. Results follow the order of ids;
+// unknown IDs are skipped, and cached as missing like GetByID does.
+func (r *CachedProductRepository) GetByIDs(ctx context.Context, ids []int) ([]models.Product, error) {
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = productKey(id)
 	}
 
-	// Cache miss - get from database
-	log.Printf("💾 Cache MISS: product %d - fetching from DB", id)
-	p, err := r.repo.GetByID(id)
+	found := make(map[int]models.Product, len(ids))
+	var missing []int
+
+	cached, err := r.cache.MGet(ctx, keys)
 	if err != nil {
-		return nil, err
+		slog.WarnContext(ctx, "cache error", "keys", len(keys), "error", err)
 	}
+	for i, id := range ids {
+		if err == nil && cache.IsNegative(cached[i]) {
+			// Known not to exist
+			metrics.RecordCacheLookup("product", metrics.CacheHit)
+			continue
+		}
+		var product models.Product
+		if err == nil && cached[i] != nil && json.Unmarshal(cached[i], &product) == nil {
+			metrics.RecordCacheLookup("product", metrics.CacheHit)
+			found[id] = product
+			continue
+		}
+		if err != nil {
+			metrics.RecordCacheLookup("product", metrics.CacheError)
+		} else {
+			metrics.RecordCacheLookup("product", metrics.CacheMiss)
+		}
+		missing = append(missing, id)
+	}
+	slog.DebugContext(ctx, "batch cache lookup", "requested", len(ids), "misses", len(missing))
+
+	if len(missing) > 0 {
+		products, err := r.repo.GetByIDs(ctx, missing)
+		if err != nil {
+			return nil, err
+		}
 
-	if p == nil {
-		return nil, nil
+		values := make(map[string]interface{}, len(missing))
+		for _, id := range missing {
+			// Stays nil, a negative entry, unless the query found it
+			values[productKey(id)] = nil
+		}
+		for _, p := range products {
+			found[p.ID] = p
+			values[productKey(p.ID)] = p
+		}
+		if err := r.cache.SetMany(ctx, values); err != nil {
+			slog.WarnContext(ctx, "failed to cache products", "count", len(values), "error", err)
+		}
 	}
 
-	// Store in cache
-	if err := r.cache.Set(ctx, cacheKey, p); err != nil {
-		log.Printf("⚠️ Failed to cache product: %v", err)
+	products := make([]models.Product, 0, len(found))
+	for _, id := range ids {
+		if p, ok := found[id]; ok {
+			products = append(products, p)
+		}
 	}
 
-	return p, nil
+	return products, nil
 }
 
 // Create inserts a new product and invalidates cache
 func (r *CachedProductRepository) Create(ctx context.Context, req models.CreateProductRequest) (*models.Product, error) {
 	// Create in database
-	product, err := r.repo.Create(req)
+	product, err := r.repo.Create(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 
-	// Invalidate all products cache
-	if err := r.cache.Delete(ctx, allProductsKey()); err != nil {
-		log.Printf("⚠️ Failed to invalidate cache: %v", err)
+	// Drop listing pages, and any negative entry cached for the new ID
+	r.Invalidate(ctx, product.ID)
+
+	return product, nil
+}
+
+// Delete archives a product and invalidates cache
+func (r *CachedProductRepository) Delete(ctx context.Context, id int) (*models.Product, error) {
+	product, err := r.repo.Delete(ctx, id)
+	if err != nil || product == nil {
+		return product, err
 	}
-	log.Println("🗑️ Cache invalidated: all products")
 
+	r.Invalidate(ctx, id)
 	return product, nil
 }
 
-// Delete removes a product and invalidates cache
-func (r *CachedProductRepository) Delete(ctx context.Context, id int) error {
-	// Delete from database
-	err := r.repo.Delete(id)
-	if err != nil {
-		return err
+// Restore un-archives a product and invalidates cache
+func (r *CachedProductRepository) Restore(ctx context.Context, id int) (*models.Product, bool, error) {
+	product, restored, err := r.repo.Restore(ctx, id)
+	if err != nil || !restored {
+		return product, restored, err
 	}
 
-	// Invalidate caches
-	r.cache.Delete(ctx, productKey(id))
-	r.cache.Delete(ctx, allProductsKey())
-	log.Printf("🗑️ Cache invalidated: product %d and all products", id)
+	r.Invalidate(ctx, id)
+	return product, true, nil
+}
+
+// Purge removes a deleted product for good and invalidates cache
+func (r *CachedProductRepository) Purge(ctx context.Context, id int) (bool, error) {
+	found, err := r.repo.Purge(ctx, id)
+	if err != nil || !found {
+		return found, err
+	}
 
-	return nil
+	r.Invalidate(ctx, id)
+	return true, nil
+}
+
+// Update changes a product and invalidates both the item and list caches
+func (r *CachedProductRepository) Update(ctx context.Context, id int, patch models.PatchProductRequest, expectedVersion int) (*models.Product, error) {
+	product, err := r.repo.Update(ctx, id, patch, expectedVersion)
+	if err != nil || product == nil {
+		return product, err
+	}
+
+	r.Invalidate(ctx, id)
+	return product, nil
+}
+
+// Import upserts one batch of catalog rows. Unless it was a dry run, the
+// products it wrote and the listing pages are invalidated once for the
+// whole batch.
+func (r *CachedProductRepository) Import(ctx context.Context, rows []models.CatalogRow, dryRun bool) ([]ImportOutcome, error) {
+	outcomes, err := r.repo.Import(ctx, rows, dryRun)
+	if err != nil || dryRun {
+		return outcomes, err
+	}
+
+	var ids []int
+	for _, outcome := range outcomes {
+		if outcome.Err == nil {
+			ids = append(ids, outcome.Product.ID)
+		}
+	}
+	InvalidateProducts(ctx, r.cache, ids)
+
+	return outcomes, nil
+}
+
+// Export is not cached; it streams straight from the database
+func (r *CachedProductRepository) Export(ctx context.Context, fn func(models.CatalogRow) error) error {
+	return r.repo.Export(ctx, fn)
+}
+
+// Invalidate drops a product and every cached listing page, e.g. after
+// one of its variants changed
+func (r *CachedProductRepository) Invalidate(ctx context.Context, id int) {
+	InvalidateProduct(ctx, r.cache, id)
+}
+
+// InvalidateProduct drops a product, its variants and every cached
+// listing page. Other writers, such as the inventory consumer, use it to
+// stay consistent.
+func InvalidateProduct(ctx context.Context, c *cache.RedisCache, id int) {
+	if err := c.DeleteMany(ctx, []string{productKey(id), productVariantsKey(id)}); err != nil {
+		slog.WarnContext(ctx, "failed to invalidate cache", "key", productKey(id), "error", err)
+	}
+	if err := c.Bump(ctx, productListVersionKey); err != nil {
+		slog.WarnContext(ctx, "failed to invalidate cache", "key", productListVersionKey, "error", err)
+	}
+	slog.DebugContext(ctx, "cache invalidated", "key", productKey(id), "product_id", id)
+}
+
+// InvalidateProducts drops a batch of products with their variants and,
+// once, every cached listing page
+func InvalidateProducts(ctx context.Context, c *cache.RedisCache, ids []int) {
+	if len(ids) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, 2*len(ids))
+	for _, id := range ids {
+		keys = append(keys, productKey(id), productVariantsKey(id))
+	}
+	if err := c.DeleteMany(ctx, keys); err != nil {
+		slog.WarnContext(ctx, "failed to invalidate cache", "keys", len(keys), "error", err)
+	}
+	if err := c.Bump(ctx, productListVersionKey); err != nil {
+		slog.WarnContext(ctx, "failed to invalidate cache", "key", productListVersionKey, "error", err)
+	}
+	slog.DebugContext(ctx, "cache invalidated", "products", len(ids))
+}
+
+// GetByIDAt is not cached; past prices are looked up rarely
+func (r *CachedProductRepository) GetByIDAt(ctx context.Context, id int, at time.Time) (*models.Product, error) {
+	return r.repo.GetByIDAt(ctx, id, at)
+}
+
+// Search is not cached; queries are too varied to hit often
+func (r *CachedProductRepository) Search(ctx context.Context, q string, limit int) ([]models.ProductSearchResult, error) {
+	return r.repo.Search(ctx, q, limit)
+}
+
+// InvalidateCategories drops cached pages scoped to any of the given
+// categories. Callers pass a category together with its ancestors, since
+// an ancestor's listing includes every product below it.
+func (r *CachedProductRepository) InvalidateCategories(ctx context.Context, ids []int) {
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, categoryListVersionKey(id))
+	}
+	if err := r.cache.Bump(ctx, keys...); err != nil {
+		slog.WarnContext(ctx, "failed to invalidate cache", "keys", keys, "error", err)
+	}
+	slog.DebugContext(ctx, "category caches invalidated", "categories", ids)
+}
+
+// InvalidateAll drops every cached product and listing page
+func (r *CachedProductRepository) InvalidateAll(ctx context.Context) {
+	if err := r.cache.DeleteByPattern(ctx, "product:*"); err != nil {
+		slog.WarnContext(ctx, "failed to invalidate cache", "key", "product:*", "error", err)
+	}
+	if err := r.cache.Bump(ctx, productListVersionKey); err != nil {
+		slog.WarnContext(ctx, "failed to invalidate cache", "key", productListVersionKey, "error", err)
+	}
+	slog.DebugContext(ctx, "product caches invalidated")
 }
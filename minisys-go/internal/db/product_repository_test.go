@@ -0,0 +1,27 @@
+package db
+
+import "testing"
+
+func TestHighlightHTML(t *testing.T) {
+	mark := func(s string) string { return highlightStart + s + highlightStop }
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"no match", "Blue Widget", "Blue Widget"},
+		{"one match", "Blue " + mark("Widget"), "Blue <mark>Widget</mark>"},
+		{"several matches", mark("Blue") + " " + mark("Widget"), "<mark>Blue</mark> <mark>Widget</mark>"},
+		{"markup in the name", `<img src=x onerror=alert(1)> ` + mark("Widget"), `&lt;img src=x onerror=alert(1)&gt; <mark>Widget</mark>`},
+		{"markup inside a match", mark("<script>"), "<mark>&lt;script&gt;</mark>"},
+		{"literal mark tags", "<mark>Widget</mark>", "&lt;mark&gt;Widget&lt;/mark&gt;"},
+		{"quotes and ampersands", `Tom & "Jerry's"`, "Tom &amp; &#34;Jerry&#39;s&#34;"},
+	}
+
+	for _, tt := range tests {
+		if got := highlightHTML(tt.in); got != tt.want {
+			t.Errorf("%s: highlightHTML(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
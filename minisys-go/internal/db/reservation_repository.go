@@ -0,0 +1,387 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/allocation"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/models"
+)
+
+// ErrReservationClosed is returned when an order is confirmed against, or
+// a caller releases, a reservation that no longer holds stock
+var ErrReservationClosed = errors.New("reservation is no longer active")
+
+// ErrTooManyReservations is returned when the caller already holds as
+// many active reservations as allowed
+var ErrTooManyReservations = errors.New("too many active reservations")
+
+// ErrReservationNotOwned is returned when an order cites a reservation
+// someone else made
+var ErrReservationNotOwned = errors.New("reservation belongs to another customer")
+
+const reservationColumns = "id, reference, status, COALESCE(order_id, 0), actor, expires_at, created_at, COALESCE(updated_at, created_at)"
+
+func scanReservation(row rowScanner) (models.Reservation, error) {
+	var r models.Reservation
+	err := row.Scan(&r.ID, &r.Reference, &r.Status, &r.OrderID, &r.Actor, &r.ExpiresAt, &r.CreatedAt, &r.UpdatedAt)
+	return r, err
+}
+
+type ReservationRepository struct {
+	db *sql.DB
+}
+
+func NewReservationRepository(database *PostgresDB) *ReservationRepository {
+	return &ReservationRepository{db: database.Conn}
+}
+
+// GetByID returns a reservation with its items, or nil if there is none
+func (r *ReservationRepository) GetByID(ctx context.Context, id int) (*models.Reservation, error) {
+	query := "SELECT " + reservationColumns + " FROM reservations WHERE id = $1"
+
+	res, err := scanReservation(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get reservation: %w", err)
+	}
+
+	if res.Items, err = reservationItems(ctx, r.db, id); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Create holds stock for every item until ttl has passed, all or nothing.
+// An item fails with ErrInsufficientStock if its variant does not exist
+// or has fewer units available than requested. An actor may hold at most
+// maxActive reservations at once; beyond that ErrTooManyReservations is
+// returned.
+func (r *ReservationRepository) Create(ctx context.Context, req models.CreateReservationRequest, ttl time.Duration, maxActive int) (*models.Reservation, error) {
+	actor := movementFrom(ctx).Actor
+	var id int
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		// Serialize an actor's checkouts so concurrent ones cannot all
+		// squeeze under the limit
+		if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext('reservations:' || $1))", actor); err != nil {
+			return err
+		}
+		var active int
+		err := tx.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM reservations
+			WHERE actor = $1 AND status = $2 AND expires_at > CURRENT_TIMESTAMP
+		`, actor, models.ReservationActive).Scan(&active)
+		if err != nil {
+			return err
+		}
+		if active >= maxActive {
+			return ErrTooManyReservations
+		}
+
+		query := `
+			INSERT INTO reservations (reference, actor, expires_at)
+			VALUES ($1, $2, CURRENT_TIMESTAMP + $3 * INTERVAL '1 millisecond')
+			RETURNING id
+		`
+		err = tx.QueryRowContext(ctx, query, req.Reference, actor, ttl.Milliseconds()).Scan(&id)
+		if err != nil {
+			return err
+		}
+
+		touched := make(map[int]bool)
+		for _, item := range req.Items {
+			productID, err := holdStock(ctx, tx, id, item)
+			if err != nil {
+				return err
+			}
+			touched[productID] = true
+		}
+
+		for productID := range touched {
+			if err := touchProduct(ctx, tx, productID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, ErrInsufficientStock) || errors.Is(err, ErrTooManyReservations) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create reservation: %w", err)
+	}
+
+	return r.GetByID(ctx, id)
+}
+
+// holdStock reserves one item's quantity of its variant, named by SKU or
+// as a product's default variant, and returns the product ID. Variants of
+// deleted products cannot be held.
+func holdStock(ctx context.Context, tx *sql.Tx, reservationID int, item models.CreateOrderItemRequest) (int, error) {
+	cond, arg, ref := "sku = $1", any(item.SKU), item.SKU
+	if item.SKU == "" {
+		cond, arg, ref = "product_id = $1 AND is_default", item.ProductID, fmt.Sprintf("product %d", item.ProductID)
+	}
+	query := `
+		UPDATE product_variants SET reserved = reserved + $2, version = version + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE ` + cond + ` AND quantity - reserved >= $2
+			AND product_id IN (SELECT id FROM products WHERE deleted_at IS NULL)
+		RETURNING id, product_id
+	`
+
+	var variantID, productID int
+	err := tx.QueryRowContext(ctx, query, arg, item.Quantity).Scan(&variantID, &productID)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("%w: %s", ErrInsufficientStock, ref)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	query = `
+		INSERT INTO reservation_items (reservation_id, variant_id, product_id, quantity)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (reservation_id, variant_id)
+		DO UPDATE SET quantity = reservation_items.quantity + EXCLUDED.quantity
+	`
+	if _, err := tx.ExecContext(ctx, query, reservationID, variantID, productID, item.Quantity); err != nil {
+		return 0, err
+	}
+	return productID, nil
+}
+
+// Confirm turns an active reservation into the stock allocation of an
+// order: the hold is dropped and items are allocated under orderID in the
+// same transaction. Callers take orderID, customerID and items from an
+// order that cites the reservation, either its order.created event or the
+// stored order; customerID must be who made the reservation, otherwise
+// ErrReservationNotOwned is returned. Confirming again for
+// the same order returns the original allocations; a reservation that was
+// released, expired or confirmed for another order yields ErrReservationClosed.
+func (r *ReservationRepository) Confirm(ctx context.Context, id, orderID int, customerID string, items []models.OrderItemEvent, strategy allocation.Strategy, shipTo *models.Location) (*models.Reservation, []models.Allocation, error) {
+	var allocations []models.Allocation
+	found := false
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		res, lapsed, err := lockReservation(ctx, tx, id)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		found = true
+
+		switch {
+		case customerID == "" || res.Actor != customerID:
+			return ErrReservationNotOwned
+		case res.Status == models.ReservationConfirmed && res.OrderID == orderID:
+			allocations, err = orderMovements(ctx, tx, orderID, models.MovementOrder)
+			return err
+		case res.Status != models.ReservationActive, lapsed:
+			return ErrReservationClosed
+		}
+
+		if _, err := releaseHolds(ctx, tx, id); err != nil {
+			return err
+		}
+
+		if allocations, err = allocateOrder(ctx, tx, orderID, items, strategy, shipTo); err != nil {
+			return err
+		}
+		return setReservationStatus(ctx, tx, id, models.ReservationConfirmed, orderID)
+	})
+	if errors.Is(err, ErrReservationClosed) || errors.Is(err, ErrReservationNotOwned) || errors.Is(err, ErrOrderCancelled) || errors.Is(err, ErrInsufficientStock) || errors.Is(err, allocation.ErrUnfulfillable) {
+		return nil, nil, err
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to confirm reservation: %w", err)
+	}
+	if !found {
+		return nil, nil, nil
+	}
+
+	res, err := r.GetByID(ctx, id)
+	return res, allocations, err
+}
+
+// Release gives up an active reservation's hold. Releasing it again is a
+// no-op; a confirmed or expired reservation yields ErrReservationClosed.
+// A missing reservation yields nil, nil.
+func (r *ReservationRepository) Release(ctx context.Context, id int) (*models.Reservation, error) {
+	found := false
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		res, _, err := lockReservation(ctx, tx, id)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		found = true
+
+		switch res.Status {
+		case models.ReservationReleased:
+			return nil
+		case models.ReservationActive:
+		default:
+			return ErrReservationClosed
+		}
+
+		if _, err := releaseHolds(ctx, tx, id); err != nil {
+			return err
+		}
+		return setReservationStatus(ctx, tx, id, models.ReservationReleased, 0)
+	})
+	if errors.Is(err, ErrReservationClosed) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to release reservation: %w", err)
+	}
+	if !found {
+		return nil, nil
+	}
+
+	return r.GetByID(ctx, id)
+}
+
+// Expire releases up to limit active reservations past their expiry and
+// returns them. Rows locked by a concurrent confirm or release are
+// skipped, so several instances may sweep at once.
+func (r *ReservationRepository) Expire(ctx context.Context, limit int) ([]models.Reservation, error) {
+	var expired []models.Reservation
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		query := "SELECT " + reservationColumns + ` FROM reservations
+			WHERE status = 'active' AND expires_at <= CURRENT_TIMESTAMP
+			ORDER BY expires_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED`
+
+		rows, err := tx.QueryContext(ctx, query, limit)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			res, err := scanReservation(rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			expired = append(expired, res)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for i := range expired {
+			if expired[i].Items, err = releaseHolds(ctx, tx, expired[i].ID); err != nil {
+				return err
+			}
+			if err := setReservationStatus(ctx, tx, expired[i].ID, models.ReservationExpired, 0); err != nil {
+				return err
+			}
+			expired[i].Status = models.ReservationExpired
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire reservations: %w", err)
+	}
+
+	return expired, nil
+}
+
+// lockReservation reads a reservation, locking it until the transaction
+// ends, and reports whether it is past its expiry
+func lockReservation(ctx context.Context, tx *sql.Tx, id int) (models.Reservation, bool, error) {
+	query := "SELECT " + reservationColumns + ", expires_at <= CURRENT_TIMESTAMP FROM reservations WHERE id = $1 FOR UPDATE"
+
+	var res models.Reservation
+	var lapsed bool
+	err := tx.QueryRowContext(ctx, query, id).Scan(&res.ID, &res.Reference, &res.Status, &res.OrderID, &res.Actor,
+		&res.ExpiresAt, &res.CreatedAt, &res.UpdatedAt, &lapsed)
+	return res, lapsed, err
+}
+
+// releaseHolds gives a reservation's held units back to available stock
+// and returns its items
+func releaseHolds(ctx context.Context, tx *sql.Tx, reservationID int) ([]models.ReservationItem, error) {
+	query := `
+		UPDATE product_variants v
+		SET reserved = v.reserved - i.quantity, version = v.version + 1, updated_at = CURRENT_TIMESTAMP
+		FROM reservation_items i
+		WHERE i.reservation_id = $1 AND i.variant_id = v.id
+		RETURNING v.product_id, v.id, v.sku, i.quantity
+	`
+
+	rows, err := tx.QueryContext(ctx, query, reservationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to release held stock: %w", err)
+	}
+
+	var items []models.ReservationItem
+	for rows.Next() {
+		var item models.ReservationItem
+		if err := rows.Scan(&item.ProductID, &item.VariantID, &item.SKU, &item.Quantity); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan reservation item: %w", err)
+		}
+		items = append(items, item)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	touched := make(map[int]bool)
+	for _, item := range items {
+		if !touched[item.ProductID] {
+			touched[item.ProductID] = true
+			if err := touchProduct(ctx, tx, item.ProductID); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return items, nil
+}
+
+func setReservationStatus(ctx context.Context, tx *sql.Tx, id int, status string, orderID int) error {
+	_, err := tx.ExecContext(ctx,
+		"UPDATE reservations SET status = $2, order_id = NULLIF($3, 0), updated_at = CURRENT_TIMESTAMP WHERE id = $1",
+		id, status, orderID,
+	)
+	return err
+}
+
+// reservationItems lists what a reservation holds, or held
+func reservationItems(ctx context.Context, conn *sql.DB, reservationID int) ([]models.ReservationItem, error) {
+	query := `
+		SELECT i.product_id, i.variant_id, v.sku, i.quantity
+		FROM reservation_items i JOIN product_variants v ON v.id = i.variant_id
+		WHERE i.reservation_id = $1
+		ORDER BY v.sku
+	`
+
+	rows, err := conn.QueryContext(ctx, query, reservationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reservation items: %w", err)
+	}
+	defer rows.Close()
+
+	items := []models.ReservationItem{}
+	for rows.Next() {
+		var item models.ReservationItem
+		if err := rows.Scan(&item.ProductID, &item.VariantID, &item.SKU, &item.Quantity); err != nil {
+			return nil, fmt.Errorf("failed to scan reservation item: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
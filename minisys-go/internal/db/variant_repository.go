@@ -0,0 +1,315 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/models"
+)
+
+// ErrDuplicateSKU is returned when a SKU is already taken by another variant
+var ErrDuplicateSKU = errors.New("sku already exists")
+
+// ErrDefaultVariant is returned when deleting a product's default variant
+var ErrDefaultVariant = errors.New("the default variant cannot be deleted")
+
+// ErrHasVariants is returned when product-level stock is set on a product
+// whose stock is split across several variants
+var ErrHasVariants = errors.New("product has several variants; set stock per variant")
+
+// ErrInsufficientStock is returned when a stock change would take a
+// warehouse below zero or a variant below what is reserved
+var ErrInsufficientStock = errors.New("variant not found or insufficient inventory")
+
+// variantColumns lists the columns scanVariant expects, in order. A
+// variant is priced in its product's currency; price falls back to the
+// product's price when the variant has no override.
+const variantColumns = `v.id, v.product_id, p.name, v.sku, v.options, p.currency,
+	COALESCE(v.price, p.price), v.price, v.quantity, v.quantity - v.reserved, v.is_default, v.version,
+	v.created_at, COALESCE(v.updated_at, v.created_at)`
+
+const variantFrom = " FROM product_variants v JOIN products p ON p.id = v.product_id"
+
+func scanVariant(row rowScanner) (models.Variant, error) {
+	var v models.Variant
+	var override sql.NullString
+	err := row.Scan(&v.ID, &v.ProductID, &v.ProductName, &v.SKU, &v.Options, &v.Price.Currency,
+		&v.Price, &override, &v.Quantity, &v.Available, &v.IsDefault, &v.Version, &v.CreatedAt, &v.UpdatedAt)
+	if err != nil {
+		return v, err
+	}
+
+	if override.Valid {
+		price, err := models.ParseMoney(override.String, v.Price.Currency)
+		if err != nil {
+			return v, err
+		}
+		v.PriceOverride = &price
+	}
+
+	return v, nil
+}
+
+func scanVariants(rows *sql.Rows) ([]models.Variant, error) {
+	variants := []models.Variant{}
+	for rows.Next() {
+		v, err := scanVariant(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan variant: %w", err)
+		}
+		variants = append(variants, v)
+	}
+
+	return variants, rows.Err()
+}
+
+// variantWriteError maps a taken SKU to ErrDuplicateSKU
+func variantWriteError(action string, err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" {
+		return ErrDuplicateSKU
+	}
+	return fmt.Errorf("failed to %s variant: %w", action, err)
+}
+
+// defaultSKU names the variant created along with a product
+func defaultSKU(productID int) string {
+	return fmt.Sprintf("SKU-%d", productID)
+}
+
+type VariantRepository struct {
+	db *sql.DB
+}
+
+func NewVariantRepository(database *PostgresDB) *VariantRepository {
+	return &VariantRepository{db: database.Conn}
+}
+
+// ListByProduct returns a product's variants, default first
+func (r *VariantRepository) ListByProduct(ctx context.Context, productID int) ([]models.Variant, error) {
+	query := "SELECT " + variantColumns + variantFrom + " WHERE v.product_id = $1 ORDER BY v.is_default DESC, v.id"
+
+	rows, err := r.db.QueryContext(ctx, query, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query variants: %w", err)
+	}
+	defer rows.Close()
+
+	return scanVariants(rows)
+}
+
+// ListByProducts returns the variants of several products that are not
+// deleted, each product's default first
+func (r *VariantRepository) ListByProducts(ctx context.Context, productIDs []int) ([]models.Variant, error) {
+	query := "SELECT " + variantColumns + variantFrom + `
+		WHERE v.product_id = ANY($1) AND p.deleted_at IS NULL
+		ORDER BY v.product_id, v.is_default DESC, v.id`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(productIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query variants: %w", err)
+	}
+	defer rows.Close()
+
+	return scanVariants(rows)
+}
+
+// Lookup returns the variants with the given SKUs together with the
+// default variants of the given products, in one query. Unknown SKUs and
+// products, and those of deleted products, are simply absent from the
+// result, so new orders cannot name them.
+func (r *VariantRepository) Lookup(ctx context.Context, skus []string, productIDs []int) ([]models.Variant, error) {
+	query := "SELECT " + variantColumns + variantFrom + `
+		WHERE (v.sku = ANY($1) OR (v.is_default AND v.product_id = ANY($2))) AND p.deleted_at IS NULL
+		ORDER BY v.id`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(skus), pq.Array(productIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query variants: %w", err)
+	}
+	defer rows.Close()
+
+	return scanVariants(rows)
+}
+
+// GetByID returns a single variant of a product, or nil if there is none
+func (r *VariantRepository) GetByID(ctx context.Context, productID, id int) (*models.Variant, error) {
+	query := "SELECT " + variantColumns + variantFrom + " WHERE v.product_id = $1 AND v.id = $2"
+
+	v, err := scanVariant(r.db.QueryRowContext(ctx, query, productID, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get variant: %w", err)
+	}
+
+	return &v, nil
+}
+
+// Create adds a variant to a product. A missing product yields nil, nil.
+func (r *VariantRepository) Create(ctx context.Context, productID int, req models.CreateVariantRequest) (*models.Variant, error) {
+	var id int
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		query := `
+			INSERT INTO product_variants (product_id, sku, options, price)
+			SELECT id, $2::text, COALESCE($3::jsonb, '{}'), $4::numeric FROM products WHERE id = $1
+			RETURNING id
+		`
+		err := tx.QueryRowContext(ctx, query, productID, req.SKU, req.Options, req.PriceOverride).Scan(&id)
+		if err != nil {
+			return err
+		}
+		if err := adjustWarehouseStock(ctx, tx, 0, id, req.Quantity); err != nil {
+			return err
+		}
+		return touchProduct(ctx, tx, productID)
+	})
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, variantWriteError("create", err)
+	}
+
+	return r.GetByID(ctx, productID, id)
+}
+
+// Update applies the non-nil fields of patch. A missing variant yields nil, nil.
+func (r *VariantRepository) Update(ctx context.Context, productID, id int, patch models.PatchVariantRequest) (*models.Variant, error) {
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		query := `
+			UPDATE product_variants SET
+				sku = COALESCE($3, sku),
+				options = COALESCE($4::jsonb, options),
+				price = CASE WHEN $6 THEN NULL ELSE COALESCE($5, price) END,
+				version = version + 1,
+				updated_at = CURRENT_TIMESTAMP
+			WHERE product_id = $1 AND id = $2
+		`
+		result, err := tx.ExecContext(ctx, query, productID, id,
+			patch.SKU, patch.Options, patch.PriceOverride, patch.ClearPriceOverride,
+		)
+		if err != nil {
+			return err
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return sql.ErrNoRows
+		}
+		if patch.Quantity != nil {
+			if err := setVariantStock(ctx, tx, id, *patch.Quantity); err != nil {
+				return err
+			}
+		}
+		return touchProduct(ctx, tx, productID)
+	})
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if errors.Is(err, ErrInsufficientStock) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, variantWriteError("update", err)
+	}
+
+	return r.GetByID(ctx, productID, id)
+}
+
+// Delete removes a non-default variant. It reports whether the variant existed.
+func (r *VariantRepository) Delete(ctx context.Context, productID, id int) (bool, error) {
+	found := false
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		var isDefault bool
+		err := tx.QueryRowContext(ctx,
+			"SELECT is_default FROM product_variants WHERE product_id = $1 AND id = $2 FOR UPDATE",
+			productID, id,
+		).Scan(&isDefault)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		found = true
+		if isDefault {
+			return ErrDefaultVariant
+		}
+
+		if _, err := tx.ExecContext(ctx, "DELETE FROM product_variants WHERE id = $1", id); err != nil {
+			return err
+		}
+		return touchProduct(ctx, tx, productID)
+	})
+	if err != nil && !errors.Is(err, ErrDefaultVariant) {
+		return found, fmt.Errorf("failed to delete variant: %w", err)
+	}
+
+	return found, err
+}
+
+// UpdateQuantity changes one variant's stock and returns the product it
+// belongs to
+func (r *VariantRepository) UpdateQuantity(ctx context.Context, id int, quantityChange int) (int, error) {
+	var productID int
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		var err error
+		productID, err = adjustStock(ctx, tx, "id = $1", id, quantityChange)
+		return err
+	})
+	return productID, err
+}
+
+// adjustStock changes the stock of the variant matching cond (with $1
+// bound to arg) in the default warehouse and bumps the variant's and its
+// product's versions so ETags see the new quantity. It returns the product ID.
+func adjustStock(ctx context.Context, tx *sql.Tx, cond string, arg any, quantityChange int) (int, error) {
+	query := `
+		UPDATE product_variants
+		SET version = version + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE ` + cond + `
+		RETURNING id, product_id
+	`
+
+	var variantID, productID int
+	err := tx.QueryRowContext(ctx, query, arg).Scan(&variantID, &productID)
+	if err == sql.ErrNoRows {
+		return 0, ErrInsufficientStock
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to update quantity: %w", err)
+	}
+
+	if err := adjustWarehouseStock(ctx, tx, 0, variantID, quantityChange); err != nil {
+		return 0, err
+	}
+	return productID, touchProduct(ctx, tx, productID)
+}
+
+// touchProduct bumps a product's version after its variants change; the
+// product's quantity itself is kept in sync by a trigger
+func touchProduct(ctx context.Context, tx *sql.Tx, productID int) error {
+	_, err := tx.ExecContext(ctx,
+		"UPDATE products SET version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = $1",
+		productID,
+	)
+	return err
+}
+
+func inTx(ctx context.Context, conn *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
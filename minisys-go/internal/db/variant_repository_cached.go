@@ -0,0 +1,202 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/cache"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/metrics"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/models"
+)
+
+// CachedVariantRepository answers variant lookups, which every new order
+// makes, from each product's cached variant list
+type CachedVariantRepository struct {
+	repo  *VariantRepository
+	cache *cache.RedisCache
+}
+
+func NewCachedVariantRepository(repo *VariantRepository, cache *cache.RedisCache) *CachedVariantRepository {
+	return &CachedVariantRepository{
+		repo:  repo,
+		cache: cache,
+	}
+}
+
+// skuKey maps a SKU to the product that owns it. A SKU only ever moves
+// to another product by being deleted and recreated, which Lookup
+// notices because the SKU is then absent from the old product's list.
+func skuKey(sku string) string {
+	return fmt.Sprintf("sku:%s", sku)
+}
+
+// Lookup behaves like VariantRepository.Lookup, reading each product's
+// variants with one MGET and loading the rest with one query. SKUs not
+// cached yet, or cached against a product that no longer has them, are
+// looked up directly. Unknown SKUs are not cached, so a variant created
+// meanwhile is orderable straight away.
+func (r *CachedVariantRepository) Lookup(ctx context.Context, skus []string, productIDs []int) ([]models.Variant, error) {
+	owners := r.owners(ctx, skus)
+
+	wanted := append([]int(nil), productIDs...)
+	for _, id := range owners {
+		wanted = append(wanted, id)
+	}
+	lists, err := r.variantLists(ctx, wanted)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int]models.Variant)
+	for _, id := range productIDs {
+		for _, v := range lists[id] {
+			if v.IsDefault {
+				byID[v.ID] = v
+			}
+		}
+	}
+
+	var unresolved []string
+	for _, sku := range skus {
+		v, ok := findSKU(lists[owners[sku]], sku)
+		if !ok {
+			unresolved = append(unresolved, sku)
+			continue
+		}
+		byID[v.ID] = v
+	}
+
+	if len(unresolved) > 0 {
+		variants, err := r.repo.Lookup(ctx, unresolved, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		values := make(map[string]interface{}, len(variants))
+		for _, v := range variants {
+			byID[v.ID] = v
+			values[skuKey(v.SKU)] = v.ProductID
+		}
+		if err := r.cache.SetMany(ctx, values); err != nil {
+			slog.WarnContext(ctx, "failed to cache skus", "count", len(values), "error", err)
+		}
+	}
+
+	variants := make([]models.Variant, 0, len(byID))
+	for _, v := range byID {
+		variants = append(variants, v)
+	}
+	sort.Slice(variants, func(i, j int) bool { return variants[i].ID < variants[j].ID })
+	return variants, nil
+}
+
+// owners reads which product each SKU belongs to; SKUs not cached are
+// absent from the result
+func (r *CachedVariantRepository) owners(ctx context.Context, skus []string) map[string]int {
+	owners := make(map[string]int, len(skus))
+	if len(skus) == 0 {
+		return owners
+	}
+
+	keys := make([]string, len(skus))
+	for i, sku := range skus {
+		keys[i] = skuKey(sku)
+	}
+
+	cached, err := r.cache.MGet(ctx, keys)
+	if err != nil {
+		slog.WarnContext(ctx, "cache error", "keys", len(keys), "error", err)
+		return owners
+	}
+	for i, sku := range skus {
+		var id int
+		if cached[i] != nil && !cache.IsNegative(cached[i]) && json.Unmarshal(cached[i], &id) == nil {
+			owners[sku] = id
+		}
+	}
+	return owners
+}
+
+// variantLists returns the variants of each product, keyed by product ID.
+// Products that are missing or deleted have none, and are cached as such.
+func (r *CachedVariantRepository) variantLists(ctx context.Context, productIDs []int) (map[int][]models.Variant, error) {
+	lists := make(map[int][]models.Variant, len(productIDs))
+	if len(productIDs) == 0 {
+		return lists, nil
+	}
+
+	keys := make([]string, len(productIDs))
+	for i, id := range productIDs {
+		keys[i] = productVariantsKey(id)
+	}
+
+	cached, err := r.cache.MGet(ctx, keys)
+	if err != nil {
+		slog.WarnContext(ctx, "cache error", "keys", len(keys), "error", err)
+	}
+
+	var missing []int
+	seen := make(map[int]bool, len(productIDs))
+	for i, id := range productIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		if err == nil && cache.IsNegative(cached[i]) {
+			metrics.RecordCacheLookup("variants", metrics.CacheHit)
+			continue
+		}
+		var variants []models.Variant
+		if err == nil && cached[i] != nil && json.Unmarshal(cached[i], &variants) == nil {
+			metrics.RecordCacheLookup("variants", metrics.CacheHit)
+			lists[id] = variants
+			continue
+		}
+		if err != nil {
+			metrics.RecordCacheLookup("variants", metrics.CacheError)
+		} else {
+			metrics.RecordCacheLookup("variants", metrics.CacheMiss)
+		}
+		missing = append(missing, id)
+	}
+	slog.DebugContext(ctx, "batch variant cache lookup", "products", len(seen), "misses", len(missing))
+
+	if len(missing) == 0 {
+		return lists, nil
+	}
+
+	variants, err := r.repo.ListByProducts(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range variants {
+		lists[v.ProductID] = append(lists[v.ProductID], v)
+	}
+
+	values := make(map[string]interface{}, len(missing))
+	for _, id := range missing {
+		if list, ok := lists[id]; ok {
+			values[productVariantsKey(id)] = list
+		} else {
+			values[productVariantsKey(id)] = nil
+		}
+	}
+	if err := r.cache.SetMany(ctx, values); err != nil {
+		slog.WarnContext(ctx, "failed to cache variants", "count", len(values), "error", err)
+	}
+
+	return lists, nil
+}
+
+func findSKU(variants []models.Variant, sku string) (models.Variant, bool) {
+	for _, v := range variants {
+		if v.SKU == sku {
+			return v, true
+		}
+	}
+	return models.Variant{}, false
+}
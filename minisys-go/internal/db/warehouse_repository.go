@@ -0,0 +1,588 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/lib/pq"
+
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/allocation"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/models"
+)
+
+// ErrWarehouseConflict is returned when a warehouse code is already taken
+var ErrWarehouseConflict = errors.New("warehouse code already exists")
+
+// ErrOrderCancelled is returned when allocating an order that has already
+// been cancelled
+var ErrOrderCancelled = errors.New("order is cancelled")
+
+const warehouseColumns = "id, code, name, latitude, longitude, priority, is_default, active, created_at"
+
+func scanWarehouse(row rowScanner) (models.Warehouse, error) {
+	var w models.Warehouse
+	var lat, lon sql.NullFloat64
+	err := row.Scan(&w.ID, &w.Code, &w.Name, &lat, &lon, &w.Priority, &w.IsDefault, &w.Active, &w.CreatedAt)
+	if lat.Valid && lon.Valid {
+		w.Location = &models.Location{Latitude: lat.Float64, Longitude: lon.Float64}
+	}
+	return w, err
+}
+
+// locationArgs splits an optional location into nullable coordinates
+func locationArgs(l *models.Location) (any, any) {
+	if l == nil {
+		return nil, nil
+	}
+	return l.Latitude, l.Longitude
+}
+
+const stockColumns = `s.warehouse_id, w.code, v.product_id, s.variant_id, v.sku, s.quantity, COALESCE(s.updated_at, w.created_at)`
+
+const stockFrom = ` FROM warehouse_stock s
+	JOIN warehouses w ON w.id = s.warehouse_id
+	JOIN product_variants v ON v.id = s.variant_id`
+
+func scanStock(rows *sql.Rows) ([]models.WarehouseStock, error) {
+	stock := []models.WarehouseStock{}
+	for rows.Next() {
+		var s models.WarehouseStock
+		err := rows.Scan(&s.WarehouseID, &s.WarehouseCode, &s.ProductID, &s.VariantID, &s.SKU, &s.Quantity, &s.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan stock: %w", err)
+		}
+		stock = append(stock, s)
+	}
+
+	return stock, rows.Err()
+}
+
+type WarehouseRepository struct {
+	db *sql.DB
+}
+
+func NewWarehouseRepository(database *PostgresDB) *WarehouseRepository {
+	return &WarehouseRepository{db: database.Conn}
+}
+
+// GetAll returns every warehouse, default first
+func (r *WarehouseRepository) GetAll(ctx context.Context) ([]models.Warehouse, error) {
+	query := "SELECT " + warehouseColumns + " FROM warehouses ORDER BY is_default DESC, code"
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query warehouses: %w", err)
+	}
+	defer rows.Close()
+
+	warehouses := []models.Warehouse{}
+	for rows.Next() {
+		w, err := scanWarehouse(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan warehouse: %w", err)
+		}
+		warehouses = append(warehouses, w)
+	}
+
+	return warehouses, rows.Err()
+}
+
+// GetByID returns a single warehouse, or nil if there is none
+func (r *WarehouseRepository) GetByID(ctx context.Context, id int) (*models.Warehouse, error) {
+	query := "SELECT " + warehouseColumns + " FROM warehouses WHERE id = $1"
+
+	w, err := scanWarehouse(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get warehouse: %w", err)
+	}
+
+	return &w, nil
+}
+
+// Create adds a warehouse
+func (r *WarehouseRepository) Create(ctx context.Context, req models.CreateWarehouseRequest) (*models.Warehouse, error) {
+	query := `
+		INSERT INTO warehouses (code, name, latitude, longitude, priority)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + warehouseColumns
+
+	lat, lon := locationArgs(req.Location)
+	w, err := scanWarehouse(r.db.QueryRowContext(ctx, query, req.Code, req.Name, lat, lon, req.Priority))
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" {
+			return nil, ErrWarehouseConflict
+		}
+		return nil, fmt.Errorf("failed to create warehouse: %w", err)
+	}
+
+	return &w, nil
+}
+
+// Update applies the non-nil fields of req. A missing warehouse yields nil, nil.
+func (r *WarehouseRepository) Update(ctx context.Context, id int, req models.UpdateWarehouseRequest) (*models.Warehouse, error) {
+	query := `
+		UPDATE warehouses SET
+			name = COALESCE($2, name),
+			latitude = CASE WHEN $3::float8 IS NULL THEN latitude ELSE $3::float8 END,
+			longitude = CASE WHEN $4::float8 IS NULL THEN longitude ELSE $4::float8 END,
+			priority = COALESCE($5, priority),
+			active = COALESCE($6, active)
+		WHERE id = $1
+		RETURNING ` + warehouseColumns
+
+	lat, lon := locationArgs(req.Location)
+	w, err := scanWarehouse(r.db.QueryRowContext(ctx, query, id, req.Name, lat, lon, req.Priority, req.Active))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to update warehouse: %w", err)
+	}
+
+	return &w, nil
+}
+
+// Stock returns what a warehouse holds, by SKU
+func (r *WarehouseRepository) Stock(ctx context.Context, warehouseID int) ([]models.WarehouseStock, error) {
+	query := "SELECT " + stockColumns + stockFrom + " WHERE s.warehouse_id = $1 ORDER BY v.sku"
+
+	rows, err := r.db.QueryContext(ctx, query, warehouseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stock: %w", err)
+	}
+	defer rows.Close()
+
+	return scanStock(rows)
+}
+
+// ProductStock returns a product's stock broken down by variant and warehouse
+func (r *WarehouseRepository) ProductStock(ctx context.Context, productID int) ([]models.WarehouseStock, error) {
+	query := "SELECT " + stockColumns + stockFrom + " WHERE v.product_id = $1 ORDER BY v.sku, w.code"
+
+	rows, err := r.db.QueryContext(ctx, query, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stock: %w", err)
+	}
+	defer rows.Close()
+
+	return scanStock(rows)
+}
+
+// SetStock sets how many units of a variant a warehouse holds, recording
+// the difference in the ledger, and returns the variant's product ID, or 0
+// if the variant does not exist
+func (r *WarehouseRepository) SetStock(ctx context.Context, warehouseID, variantID, quantity int) (int, error) {
+	var productID int
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		query := `
+			UPDATE product_variants SET version = version + 1, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $1
+			RETURNING product_id
+		`
+		if err := tx.QueryRowContext(ctx, query, variantID).Scan(&productID); err != nil {
+			return err
+		}
+
+		var current int
+		err := tx.QueryRowContext(ctx,
+			"SELECT quantity FROM warehouse_stock WHERE warehouse_id = $1 AND variant_id = $2 FOR UPDATE",
+			warehouseID, variantID,
+		).Scan(&current)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+
+		if err := adjustWarehouseStock(ctx, tx, warehouseID, variantID, quantity-current); err != nil {
+			return err
+		}
+		return touchProduct(ctx, tx, productID)
+	})
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if errors.Is(err, ErrInsufficientStock) {
+		return 0, err
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to set stock: %w", err)
+	}
+
+	return productID, nil
+}
+
+// Allocate takes the stock for every item of an order from warehouses
+// chosen by strategy, all or nothing. Items without a variant ID use their
+// product's default variant. It returns one allocation per warehouse per
+// item; allocating the same order again returns the original allocations
+// from the ledger without touching stock. A cancelled order yields
+// ErrOrderCancelled, even if its cancellation was handled first. The
+// inventory.allocated event is queued in the same transaction, for
+// PublishAllocations to send.
+func (r *WarehouseRepository) Allocate(ctx context.Context, orderID int, items []models.OrderItemEvent, strategy allocation.Strategy, shipTo *models.Location) ([]models.Allocation, error) {
+	var allocations []models.Allocation
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		var err error
+		allocations, err = allocateOrder(ctx, tx, orderID, items, strategy, shipTo)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return allocations, nil
+}
+
+// allocateOrder does the work of Allocate inside tx
+func allocateOrder(ctx context.Context, tx *sql.Tx, orderID int, items []models.OrderItemEvent, strategy allocation.Strategy, shipTo *models.Location) ([]models.Allocation, error) {
+	source := movementFrom(ctx)
+	ctx = WithMovement(ctx, models.MovementSource{
+		Reason:      models.MovementOrder,
+		ReferenceID: strconv.Itoa(orderID),
+		Actor:       source.Actor,
+	})
+
+	if err := lockOrder(ctx, tx, orderID); err != nil {
+		return nil, err
+	}
+	cancelled, err := orderCancelled(ctx, tx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if cancelled {
+		return nil, ErrOrderCancelled
+	}
+	existing, err := orderMovements(ctx, tx, orderID, models.MovementOrder)
+	if err != nil || len(existing) > 0 {
+		return existing, err
+	}
+
+	var allocations []models.Allocation
+	touched := make(map[int]bool)
+	for _, item := range items {
+		variantID, productID, sku, err := resolveVariant(ctx, tx, item)
+		if err != nil {
+			return nil, err
+		}
+
+		candidates, err := lockCandidates(ctx, tx, variantID)
+		if err != nil {
+			return nil, err
+		}
+
+		picks, err := allocation.Allocate(strategy, item.Quantity, candidates, shipTo)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", err, sku)
+		}
+
+		for _, pick := range picks {
+			if err := adjustWarehouseStock(ctx, tx, pick.WarehouseID, variantID, -pick.Quantity); err != nil {
+				return nil, err
+			}
+			allocations = append(allocations, models.Allocation{
+				ProductID:     productID,
+				VariantID:     variantID,
+				SKU:           sku,
+				WarehouseID:   pick.WarehouseID,
+				WarehouseCode: pick.Code,
+				Quantity:      pick.Quantity,
+			})
+		}
+		touched[productID] = true
+	}
+
+	for productID := range touched {
+		if err := touchProduct(ctx, tx, productID); err != nil {
+			return nil, err
+		}
+	}
+	if err := recordAllocated(ctx, tx, orderID, strategy, allocations); err != nil {
+		return nil, err
+	}
+	return allocations, nil
+}
+
+// recordAllocated queues the inventory.allocated event for an order, to be
+// published once tx commits
+func recordAllocated(ctx context.Context, tx *sql.Tx, orderID int, strategy allocation.Strategy, allocations []models.Allocation) error {
+	data, err := json.Marshal(allocations)
+	if err != nil {
+		return fmt.Errorf("failed to marshal allocations: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO allocation_events (order_id, strategy, allocations) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING",
+		orderID, string(strategy), data,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record allocation event: %w", err)
+	}
+	return nil
+}
+
+// PublishAllocations hands up to limit unpublished inventory.allocated
+// events, oldest first, to publish and marks each one published once
+// publish succeeds. It stops at the first failure so the rest are retried
+// on the next call, and returns how many were published.
+func (r *WarehouseRepository) PublishAllocations(ctx context.Context, limit int, publish func(models.InventoryAllocatedEvent) error) (int, error) {
+	published := 0
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		query := `
+			SELECT order_id, strategy, allocations, allocated_at FROM allocation_events
+			WHERE published_at IS NULL
+			ORDER BY allocated_at, order_id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		`
+
+		rows, err := tx.QueryContext(ctx, query, limit)
+		if err != nil {
+			return err
+		}
+		var pending []models.InventoryAllocatedEvent
+		for rows.Next() {
+			var e models.InventoryAllocatedEvent
+			var data []byte
+			if err := rows.Scan(&e.OrderID, &e.Strategy, &data, &e.AllocatedAt); err != nil {
+				rows.Close()
+				return err
+			}
+			if err := json.Unmarshal(data, &e.Allocations); err != nil {
+				rows.Close()
+				return err
+			}
+			pending = append(pending, e)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		var ids []int
+		for _, e := range pending {
+			if err := publish(e); err != nil {
+				break
+			}
+			ids = append(ids, e.OrderID)
+		}
+		published = len(ids)
+
+		_, err = tx.ExecContext(ctx,
+			"UPDATE allocation_events SET published_at = CURRENT_TIMESTAMP WHERE order_id = ANY($1)",
+			pq.Array(ids),
+		)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to publish allocation events: %w", err)
+	}
+
+	return published, nil
+}
+
+// Release puts an order's allocated stock back in the warehouses it came
+// from and returns what was returned. The order is marked cancelled even
+// when nothing was allocated, so a later Allocate refuses it. Releasing an
+// order twice changes nothing.
+func (r *WarehouseRepository) Release(ctx context.Context, orderID int) ([]models.Allocation, error) {
+	source := movementFrom(ctx)
+	ctx = WithMovement(ctx, models.MovementSource{
+		Reason:      models.MovementCancellation,
+		ReferenceID: strconv.Itoa(orderID),
+		Actor:       source.Actor,
+	})
+
+	var released []models.Allocation
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		if err := lockOrder(ctx, tx, orderID); err != nil {
+			return err
+		}
+		marked, err := markCancelled(ctx, tx, orderID)
+		if err != nil || !marked {
+			return err
+		}
+		allocated, err := orderMovements(ctx, tx, orderID, models.MovementOrder)
+		if err != nil {
+			return err
+		}
+
+		touched := make(map[int]bool)
+		for _, a := range allocated {
+			if err := adjustWarehouseStock(ctx, tx, a.WarehouseID, a.VariantID, a.Quantity); err != nil {
+				return err
+			}
+			touched[a.ProductID] = true
+		}
+		for productID := range touched {
+			if err := touchProduct(ctx, tx, productID); err != nil {
+				return err
+			}
+		}
+
+		released = allocated
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to release order stock: %w", err)
+	}
+
+	return released, nil
+}
+
+// markCancelled records that orderID is cancelled and reports whether it
+// was not already
+func markCancelled(ctx context.Context, tx *sql.Tx, orderID int) (bool, error) {
+	result, err := tx.ExecContext(ctx,
+		"INSERT INTO order_cancellations (order_id) VALUES ($1) ON CONFLICT DO NOTHING", orderID)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark order cancelled: %w", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to mark order cancelled: %w", err)
+	}
+	return n > 0, nil
+}
+
+// orderCancelled reports whether orderID's stock was released or
+// order-service has already cancelled it
+func orderCancelled(ctx context.Context, tx *sql.Tx, orderID int) (bool, error) {
+	query := `
+		SELECT EXISTS (SELECT 1 FROM order_cancellations WHERE order_id = $1)
+		    OR EXISTS (SELECT 1 FROM orders WHERE id = $1 AND status = 'cancelled')
+	`
+
+	var cancelled bool
+	if err := tx.QueryRowContext(ctx, query, orderID).Scan(&cancelled); err != nil {
+		return false, fmt.Errorf("failed to check order cancellation: %w", err)
+	}
+	return cancelled, nil
+}
+
+// resolveVariant finds the variant an order item takes stock from and
+// bumps its version
+func resolveVariant(ctx context.Context, tx *sql.Tx, item models.OrderItemEvent) (int, int, string, error) {
+	cond, arg := "id = $1", item.VariantID
+	if item.VariantID == 0 {
+		cond, arg = "product_id = $1 AND is_default", item.ProductID
+	}
+	query := `
+		UPDATE product_variants SET version = version + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE ` + cond + `
+		RETURNING id, product_id, sku
+	`
+
+	var variantID, productID int
+	var sku string
+	err := tx.QueryRowContext(ctx, query, arg).Scan(&variantID, &productID, &sku)
+	if err == sql.ErrNoRows {
+		return 0, 0, "", fmt.Errorf("%w: product %d", ErrInsufficientStock, item.ProductID)
+	}
+	if err != nil {
+		return 0, 0, "", fmt.Errorf("failed to resolve variant: %w", err)
+	}
+
+	return variantID, productID, sku, nil
+}
+
+// lockCandidates returns the active warehouses holding a variant, locking
+// their stock rows until the transaction ends
+func lockCandidates(ctx context.Context, tx *sql.Tx, variantID int) ([]allocation.Candidate, error) {
+	query := `
+		SELECT w.id, w.code, w.latitude, w.longitude, w.priority, s.quantity
+		FROM warehouse_stock s JOIN warehouses w ON w.id = s.warehouse_id
+		WHERE s.variant_id = $1 AND w.active AND s.quantity > 0
+		ORDER BY w.id
+		FOR UPDATE OF s
+	`
+
+	rows, err := tx.QueryContext(ctx, query, variantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stock: %w", err)
+	}
+	defer rows.Close()
+
+	var candidates []allocation.Candidate
+	for rows.Next() {
+		var c allocation.Candidate
+		var lat, lon sql.NullFloat64
+		if err := rows.Scan(&c.WarehouseID, &c.Code, &lat, &lon, &c.Priority, &c.Available); err != nil {
+			return nil, fmt.Errorf("failed to scan stock: %w", err)
+		}
+		if lat.Valid && lon.Valid {
+			c.Location = &models.Location{Latitude: lat.Float64, Longitude: lon.Float64}
+		}
+		candidates = append(candidates, c)
+	}
+
+	return candidates, rows.Err()
+}
+
+// adjustWarehouseStock changes a variant's stock in one warehouse (0 means
+// the default warehouse), refusing to go below zero, and appends the change
+// to the ledger under the source attached to ctx. The variant and product
+// totals follow through triggers.
+func adjustWarehouseStock(ctx context.Context, tx *sql.Tx, warehouseID, variantID, quantityChange int) error {
+	if quantityChange == 0 {
+		return nil
+	}
+
+	var warehouse any
+	if warehouseID != 0 {
+		warehouse = warehouseID
+	}
+
+	query := `
+		INSERT INTO warehouse_stock (warehouse_id, variant_id, quantity)
+		VALUES (COALESCE($1::int, (SELECT id FROM warehouses WHERE is_default)), $2, $3)
+		ON CONFLICT (warehouse_id, variant_id)
+		DO UPDATE SET quantity = warehouse_stock.quantity + EXCLUDED.quantity, updated_at = CURRENT_TIMESTAMP
+		RETURNING warehouse_id, quantity
+	`
+	if quantityChange < 0 {
+		query = `
+			UPDATE warehouse_stock SET quantity = quantity + $3, updated_at = CURRENT_TIMESTAMP
+			WHERE warehouse_id = COALESCE($1::int, (SELECT id FROM warehouses WHERE is_default))
+				AND variant_id = $2 AND quantity + $3 >= 0
+			RETURNING warehouse_id, quantity
+		`
+	}
+
+	var resolvedID, quantityAfter int
+	err := tx.QueryRowContext(ctx, query, warehouse, variantID, quantityChange).Scan(&resolvedID, &quantityAfter)
+	if err == sql.ErrNoRows || isHeldStock(err) {
+		return ErrInsufficientStock
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update quantity: %w", err)
+	}
+
+	return recordMovement(ctx, tx, resolvedID, variantID, quantityChange, quantityAfter)
+}
+
+// isHeldStock reports whether err is a stock change refused because it
+// would leave fewer units than are reserved
+func isHeldStock(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Constraint == "product_variants_reserved_check"
+}
+
+// setVariantStock sets a variant's total stock by moving the difference
+// in or out of the default warehouse
+func setVariantStock(ctx context.Context, tx *sql.Tx, variantID, quantity int) error {
+	var current int
+	err := tx.QueryRowContext(ctx, "SELECT quantity FROM product_variants WHERE id = $1 FOR UPDATE", variantID).Scan(&current)
+	if err != nil {
+		return fmt.Errorf("failed to read variant stock: %w", err)
+	}
+
+	return adjustWarehouseStock(ctx, tx, 0, variantID, quantity-current)
+}
@@ -0,0 +1,206 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/allocation"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/models"
+)
+
+// testDB connects to the database named by TEST_DATABASE_URL and migrates
+// it, or skips the test when none is configured. Tests share the database,
+// so each one creates the rows it needs.
+func testDB(t *testing.T) *PostgresDB {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	conn, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	migrator, err := NewMigrator(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := migrator.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	return &PostgresDB{Conn: conn}
+}
+
+// stockedOrder creates a product with quantity in stock and a pending
+// order for it, and returns the order's item and ID
+func stockedOrder(t *testing.T, database *PostgresDB, quantity int) (models.OrderItemEvent, int) {
+	t.Helper()
+	ctx := context.Background()
+
+	product, err := NewProductRepository(database).Create(ctx, models.CreateProductRequest{
+		Name:     t.Name(),
+		Price:    models.NewMoney(1000, models.DefaultCurrency),
+		Quantity: quantity,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var orderID int
+	err = database.Conn.QueryRowContext(ctx,
+		"INSERT INTO orders (customer_name, total_amount) VALUES ($1, 0) RETURNING id", t.Name(),
+	).Scan(&orderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return models.OrderItemEvent{ProductID: product.ID, Quantity: 1}, orderID
+}
+
+func stockOf(t *testing.T, database *PostgresDB, productID int) int {
+	t.Helper()
+	product, err := NewProductRepository(database).GetByID(context.Background(), productID)
+	if err != nil || product == nil {
+		t.Fatalf("GetByID(%d) = %v, %v", productID, product, err)
+	}
+	return product.Quantity
+}
+
+func TestAllocateAfterRelease(t *testing.T) {
+	database := testDB(t)
+	repo := NewWarehouseRepository(database)
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		cancel func(t *testing.T, orderID int)
+	}{
+		{
+			// order.cancelled was handled before order.created
+			name: "released before allocation",
+			cancel: func(t *testing.T, orderID int) {
+				released, err := repo.Release(ctx, orderID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(released) != 0 {
+					t.Fatalf("released %v from an order that never took stock", released)
+				}
+			},
+		},
+		{
+			name: "cancelled by order-service",
+			cancel: func(t *testing.T, orderID int) {
+				if _, err := database.Conn.ExecContext(ctx, "UPDATE orders SET status = 'cancelled' WHERE id = $1", orderID); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item, orderID := stockedOrder(t, database, 5)
+			tt.cancel(t, orderID)
+
+			_, err := repo.Allocate(ctx, orderID, []models.OrderItemEvent{item}, allocation.DefaultStrategy, nil)
+			if !errors.Is(err, ErrOrderCancelled) {
+				t.Fatalf("Allocate error = %v, want ErrOrderCancelled", err)
+			}
+			if got := stockOf(t, database, item.ProductID); got != 5 {
+				t.Errorf("stock = %d, want 5 untouched", got)
+			}
+		})
+	}
+}
+
+func TestReleaseThenRedeliveredCreate(t *testing.T) {
+	database := testDB(t)
+	repo := NewWarehouseRepository(database)
+	ctx := context.Background()
+	item, orderID := stockedOrder(t, database, 5)
+	items := []models.OrderItemEvent{item}
+
+	allocations, err := repo.Allocate(ctx, orderID, items, allocation.DefaultStrategy, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(allocations) != 1 || stockOf(t, database, item.ProductID) != 4 {
+		t.Fatalf("allocations = %v, want one unit taken", allocations)
+	}
+
+	released, err := repo.Release(ctx, orderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(released) != 1 || stockOf(t, database, item.ProductID) != 5 {
+		t.Fatalf("released = %v, want the unit back", released)
+	}
+
+	// A requeued order.created and a duplicate order.cancelled change nothing
+	if _, err := repo.Allocate(ctx, orderID, items, allocation.DefaultStrategy, nil); !errors.Is(err, ErrOrderCancelled) {
+		t.Fatalf("Allocate after release error = %v, want ErrOrderCancelled", err)
+	}
+	released, err = repo.Release(ctx, orderID)
+	if err != nil || len(released) != 0 {
+		t.Fatalf("second Release = %v, %v; want nothing released", released, err)
+	}
+	if got := stockOf(t, database, item.ProductID); got != 5 {
+		t.Errorf("stock = %d, want 5", got)
+	}
+}
+
+func TestAllocationEventQueued(t *testing.T) {
+	database := testDB(t)
+	repo := NewWarehouseRepository(database)
+	ctx := context.Background()
+	item, orderID := stockedOrder(t, database, 5)
+	items := []models.OrderItemEvent{item}
+
+	if _, err := repo.Allocate(ctx, orderID, items, allocation.DefaultStrategy, nil); err != nil {
+		t.Fatal(err)
+	}
+	// A redelivered order.created queues nothing more
+	if _, err := repo.Allocate(ctx, orderID, items, allocation.DefaultStrategy, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Other tests share the table, so only this order's event is counted
+	publishOrder := func(fail bool) int {
+		sent := 0
+		_, err := repo.PublishAllocations(ctx, 1000, func(e models.InventoryAllocatedEvent) error {
+			if e.OrderID != orderID {
+				return nil
+			}
+			if fail {
+				return errors.New("broker down")
+			}
+			if len(e.Allocations) != 1 || e.Allocations[0].Quantity != 1 || e.Strategy != string(allocation.DefaultStrategy) {
+				t.Errorf("event = %+v, want one unit by %s", e, allocation.DefaultStrategy)
+			}
+			sent++
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sent
+	}
+
+	if sent := publishOrder(true); sent != 0 {
+		t.Fatalf("sent %d events while the broker was down", sent)
+	}
+	if sent := publishOrder(false); sent != 1 {
+		t.Fatalf("sent %d events after the broker recovered, want 1", sent)
+	}
+	if sent := publishOrder(false); sent != 0 {
+		t.Errorf("sent %d events again, want 0", sent)
+	}
+}
@@ -2,7 +2,7 @@ package discovery
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"net"
 	"strconv"
 
@@ -20,6 +20,19 @@ type ServiceConfig struct {
 	Tags []string
 }
 
+// ServiceInstance is a single registered instance of a service
+type ServiceInstance struct {
+	ID      string
+	Address string
+	Port    int
+	Healthy bool
+}
+
+// URL returns the base HTTP URL of the instance
+func (i ServiceInstance) URL() string {
+	return fmt.Sprintf("http://%s:%d", i.Address, i.Port)
+}
+
 func NewConsulClient(host string, port int) (*ConsulClient, error) {
 	config := api.DefaultConfig()
 	config.Address = fmt.Sprintf("%s:%d", host, port)
@@ -35,7 +48,7 @@ func NewConsulClient(host string, port int) (*ConsulClient, error) {
 		return nil, fmt.Errorf("failed to connect to Consul: %w", err)
 	}
 
-	log.Println("✅ Connected to Consul")
+	slog.Info("connected to Consul", "addr", config.Address)
 
 	return &ConsulClient{client: client}, nil
 }
@@ -75,7 +88,7 @@ func (c *ConsulClient) Register(cfg ServiceConfig) error {
 		return fmt.Errorf("failed to register service: %w", err)
 	}
 
-	log.Printf("✅ Registered service: %s (ID: %s) at %s:%d", cfg.Name, cfg.ID, hostIP, cfg.Port)
+	slog.Info("registered service", "name", cfg.Name, "id", cfg.ID, "address", hostIP, "port", cfg.Port)
 	return nil
 }
 
@@ -86,7 +99,7 @@ func (c *ConsulClient) Deregister(serviceID string) error {
 		return fmt.Errorf("failed to deregister service: %w", err)
 	}
 
-	log.Printf("✅ Deregistered service: %s", serviceID)
+	slog.Info("deregistered service", "id", serviceID)
 	return nil
 }
 
@@ -111,6 +124,32 @@ func (c *ConsulClient) GetService(serviceName string) (string, int, error) {
 	return address, service.Port, nil
 }
 
+// GetServiceInstances returns every registered instance of a service,
+// including the ones Consul currently considers unhealthy
+func (c *ConsulClient) GetServiceInstances(serviceName string) ([]ServiceInstance, error) {
+	entries, _, err := c.client.Health().Service(serviceName, "", false, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service instances: %w", err)
+	}
+
+	instances := make([]ServiceInstance, 0, len(entries))
+	for _, entry := range entries {
+		address := entry.Service.Address
+		if address == "" {
+			address = "localhost"
+		}
+
+		instances = append(instances, ServiceInstance{
+			ID:      entry.Service.ID,
+			Address: address,
+			Port:    entry.Service.Port,
+			Healthy: entry.Checks.AggregatedStatus() == api.HealthPassing,
+		})
+	}
+
+	return instances, nil
+}
+
 // GetServiceURL returns the full URL for a service
 func (c *ConsulClient) GetServiceURL(serviceName string) (string, error) {
 	address, port, err := c.GetService(serviceName)
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/db"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/models"
+)
+
+type AlertHandler struct {
+	repo *db.AlertRepository
+}
+
+func NewAlertHandler(repo *db.AlertRepository) *AlertHandler {
+	return &AlertHandler{repo: repo}
+}
+
+// ListAlerts returns one page of stock alerts, newest first by default.
+// status is open (the default), resolved or all; kind and product_id
+// narrow the listing further.
+func (h *AlertHandler) ListAlerts(c *gin.Context) {
+	page, err := parsePageRequest(c, "-id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var filter models.AlertFilter
+	switch c.DefaultQuery("status", "open") {
+	case "open":
+		open := true
+		filter.Open = &open
+	case "resolved":
+		open := false
+		filter.Open = &open
+	case "all":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be open, resolved or all"})
+		return
+	}
+
+	switch kind := c.Query("kind"); kind {
+	case "", models.AlertLowStock, models.AlertOutOfStock:
+		filter.Kind = kind
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kind must be low_stock or out_of_stock"})
+		return
+	}
+
+	if raw := c.Query("product_id"); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil || id <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID"})
+			return
+		}
+		filter.ProductID = id
+	}
+
+	alerts, next, err := h.repo.List(c.Request.Context(), filter, page)
+	if errors.Is(err, db.ErrInvalidPage) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Page[models.StockAlert]{
+		Data:       alerts,
+		Pagination: pageInfo(c, page, next),
+	})
+}
+
+// GetReorderPolicy returns a product's reorder threshold
+func (h *AlertHandler) GetReorderPolicy(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID"})
+		return
+	}
+
+	policy, err := h.repo.GetPolicy(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if policy == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "reorder policy not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// SetReorderPolicy sets when a product counts as low on stock and how
+// much a purchase order draft should restock
+func (h *AlertHandler) SetReorderPolicy(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID"})
+		return
+	}
+
+	var req models.SetReorderPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if *req.Threshold < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "threshold must not be negative"})
+		return
+	}
+	if req.ReorderQuantity < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reorder_quantity must not be negative"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	policy, err := h.repo.SetPolicy(ctx, id, req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if policy == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+		return
+	}
+
+	slog.InfoContext(ctx, "reorder policy set", "product_id", id, "threshold", policy.Threshold, "reorder_quantity", policy.ReorderQuantity)
+	c.JSON(http.StatusOK, policy)
+}
+
+// DeleteReorderPolicy stops low-stock alerts for a product; it still
+// raises out-of-stock alerts
+func (h *AlertHandler) DeleteReorderPolicy(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID"})
+		return
+	}
+
+	found, err := h.repo.DeletePolicy(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "reorder policy not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "reorder policy deleted"})
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"net/http"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/db"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/models"
+)
+
+var attributeName = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+type AttributeHandler struct {
+	repo *db.AttributeRepository
+}
+
+func NewAttributeHandler(repo *db.AttributeRepository) *AttributeHandler {
+	return &AttributeHandler{repo: repo}
+}
+
+// ListAttributes returns the attribute schema
+func (h *AttributeHandler) ListAttributes(c *gin.Context) {
+	definitions, err := h.repo.GetAll(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, definitions)
+}
+
+// PutAttribute creates or replaces the definition named in the path
+func (h *AttributeHandler) PutAttribute(c *gin.Context) {
+	var definition models.AttributeDefinition
+	if err := c.ShouldBindJSON(&definition); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	definition.Name = c.Param("name")
+	if !attributeName.MatchString(definition.Name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "attribute names are lower-case letters, digits and underscores"})
+		return
+	}
+	if definition.Type == models.AttributeEnum && len(definition.AllowedValues) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "enum attributes need allowed_values"})
+		return
+	}
+	if definition.Type != models.AttributeEnum {
+		definition.AllowedValues = nil
+	}
+
+	if err := h.repo.Upsert(c.Request.Context(), definition); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, definition)
+}
+
+// DeleteAttribute removes a definition; existing product values are kept
+func (h *AttributeHandler) DeleteAttribute(c *gin.Context) {
+	if err := h.repo.Delete(c.Request.Context(), c.Param("name")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "attribute deleted"})
+}
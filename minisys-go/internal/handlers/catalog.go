@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/catalog"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/models"
+)
+
+const (
+	// importBatchSize is how many rows one import transaction applies
+	importBatchSize = 500
+	// maxImportErrors caps the row errors an import response lists; the
+	// failed count still covers every row
+	maxImportErrors = 1000
+	// exportFlushRows is how many rows an export buffers between flushes
+	exportFlushRows = 500
+)
+
+// ImportProducts creates or replaces products from a CSV or NDJSON upload,
+// matching existing products by the SKU of their default variant. The
+// format comes from ?format= or the Content-Type. Rows are applied in
+// batches of importBatchSize, each in its own transaction; invalid rows
+// are reported and skipped. ?dry_run=true validates and counts without
+// committing anything.
+func (h *ProductHandler) ImportProducts(c *gin.Context) {
+	format, err := importFormat(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "dry_run must be true or false"})
+		return
+	}
+
+	reader, err := catalog.NewReader(c.Request.Body, format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := stockContext(c, models.MovementAdjustment)
+	definitions, err := h.attributes.GetAll(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := models.ImportResult{DryRun: dryRun, Errors: []models.ImportError{}}
+	fail := func(line int, sku string, err error) {
+		result.Failed++
+		if len(result.Errors) < maxImportErrors {
+			result.Errors = append(result.Errors, models.ImportError{Line: line, SKU: sku, Error: err.Error()})
+		}
+	}
+
+	var batch []models.CatalogRow
+	var lines []int
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		outcomes, err := h.repo.Import(ctx, batch, dryRun)
+		if err != nil {
+			return err
+		}
+		for i, outcome := range outcomes {
+			switch {
+			case outcome.Err != nil:
+				fail(lines[i], batch[i].SKU, outcome.Err)
+			case outcome.Created:
+				result.Created++
+			default:
+				result.Updated++
+				if !dryRun {
+					h.publishImported(ctx, &outcome.Product, batch[i])
+				}
+			}
+		}
+		batch, lines = batch[:0], lines[:0]
+		return nil
+	}
+
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		var rowErr *catalog.RowError
+		if errors.As(err, &rowErr) {
+			result.Rows++
+			fail(rowErr.Line, "", rowErr.Err)
+			continue
+		}
+		if err != nil {
+			// Batches already applied stay applied
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("failed to read upload: %v", err), "result": result})
+			return
+		}
+
+		result.Rows++
+		if err := validateCatalogRow(&row, definitions); err != nil {
+			fail(reader.Line(), row.SKU, err)
+			continue
+		}
+		batch = append(batch, row)
+		lines = append(lines, reader.Line())
+
+		if len(batch) == importBatchSize {
+			if err := flush(); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "result": result})
+				return
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "result": result})
+		return
+	}
+
+	slog.InfoContext(ctx, "catalog imported", "format", format, "dry_run", dryRun,
+		"rows", result.Rows, "created", result.Created, "updated", result.Updated, "failed", result.Failed)
+	c.JSON(http.StatusOK, result)
+}
+
+// publishImported announces a product an import row replaced, naming the
+// fields an import always rewrites
+func (h *ProductHandler) publishImported(ctx context.Context, product *models.Product, row models.CatalogRow) {
+	changed := []string{"name", "price", "category_id", "tags", "attributes"}
+	if row.Quantity != nil {
+		changed = append(changed, "quantity")
+	}
+
+	if err := h.publisher.PublishProductUpdated(ctx, product, changed); err != nil {
+		slog.ErrorContext(ctx, "failed to publish product.updated event", "product_id", product.ID, "error", err)
+		// Don't fail the import, the batch is already committed
+	}
+}
+
+// importFormat reads the upload format from ?format=, falling back to the
+// Content-Type
+func importFormat(c *gin.Context) (catalog.Format, error) {
+	if raw := c.Query("format"); raw != "" {
+		return catalog.ParseFormat(raw)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	switch mediaType {
+	case "text/csv":
+		return catalog.CSV, nil
+	case "application/x-ndjson", "application/jsonl":
+		return catalog.NDJSON, nil
+	default:
+		return "", fmt.Errorf("format is required: pass ?format=csv or ?format=ndjson, or a text/csv or application/x-ndjson body")
+	}
+}
+
+// validateCatalogRow applies the checks CreateProduct makes to one import
+// row, normalizing it in place
+func validateCatalogRow(row *models.CatalogRow, definitions map[string]models.AttributeDefinition) error {
+	sku, err := normalizeSKU(row.SKU)
+	if err != nil {
+		return err
+	}
+	row.SKU = sku
+
+	row.Name = strings.TrimSpace(row.Name)
+	if row.Name == "" {
+		return fmt.Errorf("name must not be empty")
+	}
+	if row.Price.Amount <= 0 {
+		return fmt.Errorf("price must be positive")
+	}
+	if row.Quantity != nil && *row.Quantity < 0 {
+		return fmt.Errorf("quantity must not be negative")
+	}
+	if row.CategoryID != nil && *row.CategoryID <= 0 {
+		return fmt.Errorf("invalid category ID")
+	}
+
+	if row.Tags, err = normalizeTags(row.Tags); err != nil {
+		return err
+	}
+	return checkAttributeValues(definitions, row.Attributes)
+}
+
+// ExportProducts streams every product that is not deleted as CSV (the
+// default) or NDJSON, in the format ImportProducts accepts
+func (h *ProductHandler) ExportProducts(c *gin.Context) {
+	format, err := catalog.ParseFormat(c.DefaultQuery("format", string(catalog.CSV)))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	c.Header("Content-Type", format.ContentType())
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="products.%s"`, format))
+
+	writer := catalog.NewWriter(c.Writer, format)
+	rows := 0
+	err = h.repo.Export(ctx, func(row models.CatalogRow) error {
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+		rows++
+		if rows%exportFlushRows == 0 {
+			if err := writer.Flush(); err != nil {
+				return err
+			}
+			c.Writer.Flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "catalog export failed", "format", format, "rows", rows, "error", err)
+		if !c.Writer.Written() {
+			c.Header("Content-Type", "application/json; charset=utf-8")
+			c.Header("Content-Disposition", "")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		// Otherwise the status is already sent and the client sees a
+		// truncated file
+		return
+	}
+
+	slog.InfoContext(ctx, "catalog exported", "format", format, "rows", rows)
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/db"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/models"
+)
+
+type CategoryHandler struct {
+	repo     *db.CategoryRepository
+	products *db.CachedProductRepository
+}
+
+func NewCategoryHandler(repo *db.CategoryRepository, products *db.CachedProductRepository) *CategoryHandler {
+	return &CategoryHandler{repo: repo, products: products}
+}
+
+// ListCategories returns the category tree, or a flat list with ?flat=true
+func (h *CategoryHandler) ListCategories(c *gin.Context) {
+	categories, err := h.repo.GetAll(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if flat, _ := strconv.ParseBool(c.Query("flat")); flat {
+		if categories == nil {
+			categories = []models.Category{}
+		}
+		c.JSON(http.StatusOK, categories)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.BuildCategoryTree(categories))
+}
+
+// GetCategory returns a single category
+func (h *CategoryHandler) GetCategory(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category ID"})
+		return
+	}
+
+	category, err := h.repo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if category == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "category not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
+// CreateCategory adds a category, deriving the slug from the name if needed
+func (h *CategoryHandler) CreateCategory(c *gin.Context) {
+	var req models.CreateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Slug == "" {
+		req.Slug = req.Name
+	}
+	req.Slug = slugify(req.Slug)
+	if req.Name == "" || req.Slug == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name must contain letters or digits"})
+		return
+	}
+
+	category, err := h.repo.Create(c.Request.Context(), req)
+	if errors.Is(err, db.ErrCategoryConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, category)
+}
+
+// UpdateCategory renames or moves a category
+func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category ID"})
+		return
+	}
+
+	var req models.UpdateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Slug != nil {
+		slug := slugify(*req.Slug)
+		req.Slug = &slug
+	}
+	if (req.Name != nil && strings.TrimSpace(*req.Name) == "") || (req.Slug != nil && *req.Slug == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name and slug must not be empty"})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	// A move changes which products sit under the old ancestors
+	var before []int
+	if req.ParentID != nil {
+		if before, err = h.repo.Ancestors(ctx, id); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	category, err := h.repo.Update(ctx, id, req)
+	switch {
+	case errors.Is(err, db.ErrCategoryCycle):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, db.ErrCategoryConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	case category == nil:
+		c.JSON(http.StatusNotFound, gin.H{"error": "category not found"})
+		return
+	}
+
+	if req.ParentID != nil {
+		h.invalidateMove(ctx, id, before)
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
+func (h *CategoryHandler) invalidateMove(ctx context.Context, id int, before []int) {
+	after, err := h.repo.Ancestors(ctx, id)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to load category ancestors", "category_id", id, "error", err)
+	}
+	h.products.InvalidateCategories(ctx, append(before, after...))
+}
+
+// DeleteCategory removes a category that has no subcategories; its
+// products become uncategorized
+func (h *CategoryHandler) DeleteCategory(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category ID"})
+		return
+	}
+
+	err = h.repo.Delete(c.Request.Context(), id)
+	if errors.Is(err, db.ErrCategoryInUse) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Products that pointed at the category changed, so every cached copy goes
+	h.products.InvalidateAll(c.Request.Context())
+
+	c.JSON(http.StatusOK, gin.H{"message": "category deleted"})
+}
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// slugify turns "Men's Shoes" into "men-s-shoes"
+func slugify(s string) string {
+	return strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(s), "-"), "-")
+}
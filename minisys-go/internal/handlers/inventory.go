@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/auth"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/db"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/inventory"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/models"
+)
+
+// maxReferenceLength caps the free-form reference of a manual adjustment
+const maxReferenceLength = 64
+
+type InventoryHandler struct {
+	repo       *db.InventoryRepository
+	warehouses *db.WarehouseRepository
+	products   *db.CachedProductRepository
+	reconciler *inventory.Reconciler
+}
+
+func NewInventoryHandler(repo *db.InventoryRepository, warehouses *db.WarehouseRepository, products *db.CachedProductRepository, reconciler *inventory.Reconciler) *InventoryHandler {
+	return &InventoryHandler{repo: repo, warehouses: warehouses, products: products, reconciler: reconciler}
+}
+
+// GetHistory returns one page of a product's stock movements, newest first
+// by default. variant_id, warehouse_id and reason narrow the listing.
+func (h *InventoryHandler) GetHistory(c *gin.Context) {
+	productID, ok := h.checkProduct(c)
+	if !ok {
+		return
+	}
+
+	page, err := parsePageRequest(c, "-id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var filter models.MovementFilter
+	for name, dest := range map[string]*int{"variant_id": &filter.VariantID, "warehouse_id": &filter.WarehouseID} {
+		if raw := c.Query(name); raw != "" {
+			id, err := strconv.Atoi(raw)
+			if err != nil || id <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + name})
+				return
+			}
+			*dest = id
+		}
+	}
+	filter.Reason = c.Query("reason")
+
+	movements, next, err := h.repo.History(c.Request.Context(), productID, filter, page)
+	if errors.Is(err, db.ErrInvalidPage) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Page[models.InventoryMovement]{
+		Data:       movements,
+		Pagination: pageInfo(c, page, next),
+	})
+}
+
+// AdjustStock records a manual stock change, such as a delivery (restock)
+// or a stocktake correction (adjustment)
+func (h *InventoryHandler) AdjustStock(c *gin.Context) {
+	productID, ok := h.checkProduct(c)
+	if !ok {
+		return
+	}
+
+	var req models.AdjustStockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Reason != models.MovementRestock && req.Reason != models.MovementAdjustment {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reason must be restock or adjustment"})
+		return
+	}
+	if req.Reason == models.MovementRestock && req.QuantityChange < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "a restock must add stock"})
+		return
+	}
+	req.ReferenceID = strings.TrimSpace(req.ReferenceID)
+	if len(req.ReferenceID) > maxReferenceLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reference_id is too long"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if req.WarehouseID != 0 {
+		warehouse, err := h.warehouses.GetByID(ctx, req.WarehouseID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if warehouse == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown warehouse"})
+			return
+		}
+	}
+
+	ctx = db.WithMovement(ctx, models.MovementSource{
+		Reason:      req.Reason,
+		ReferenceID: req.ReferenceID,
+		Actor:       actor(c),
+	})
+	found, err := h.repo.Adjust(ctx, productID, req.VariantID, req.WarehouseID, req.QuantityChange)
+	if errors.Is(err, db.ErrInsufficientStock) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if found == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "variant not found"})
+		return
+	}
+
+	h.products.Invalidate(ctx, productID)
+	slog.InfoContext(ctx, "stock adjusted", "product_id", productID, "variant_id", req.VariantID,
+		"warehouse_id", req.WarehouseID, "quantity_change", req.QuantityChange, "reason", req.Reason)
+	c.JSON(http.StatusOK, gin.H{"message": "stock adjusted"})
+}
+
+// GetDrift reconciles the ledger against stock levels right away and
+// returns whatever disagrees
+func (h *InventoryHandler) GetDrift(c *gin.Context) {
+	drift, err := h.reconciler.Reconcile(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"drift": drift, "count": len(drift)})
+}
+
+// checkProduct resolves the :id path parameter, writing the error response
+// and returning false if the product does not exist
+func (h *InventoryHandler) checkProduct(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID"})
+		return 0, false
+	}
+
+	product, err := h.products.GetByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return 0, false
+	}
+	if product == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+		return 0, false
+	}
+
+	return id, true
+}
+
+// actor names the caller in the inventory ledger
+func actor(c *gin.Context) string {
+	if identity := auth.FromContext(c); identity != nil {
+		return identity.Subject
+	}
+	return ""
+}
+
+// stockContext attributes any stock change made while handling the request
+// to the caller, with reason
+func stockContext(c *gin.Context, reason string) context.Context {
+	return db.WithMovement(c.Request.Context(), models.MovementSource{Reason: reason, Actor: actor(c)})
+}
@@ -1,17 +1,28 @@
 package handlers
 
 import (
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/allocation"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/auth"
 	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/client"
 	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/db"
 	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/models"
 	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/publisher"
 )
 
+// priceHoldPeriod is how long a price shown to a customer is honored
+// after it changes
+const priceHoldPeriod = 30 * time.Minute
+
 type OrderHandler struct {
 	repo          *db.OrderRepository
 	productClient *client.ProductClient
@@ -31,15 +42,59 @@ func (h *OrderHandler) HealthCheck(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "healthy", "service": "order-service"})
 }
 
-// ListOrders returns all orders
+// ListOrders returns a page of orders; customers only ever see their own
 func (h *OrderHandler) ListOrders(c *gin.Context) {
-	orders, err := h.repo.GetAll()
+	page, err := parsePageRequest(c, "-created_at")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter, err := parseOrderFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	identity := auth.FromContext(c)
+	if !identity.IsPrivileged() {
+		filter.CustomerID = &identity.Subject
+	}
+
+	orders, next, err := h.repo.List(c.Request.Context(), filter, page)
+	if errors.Is(err, db.ErrInvalidPage) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, orders)
+	c.JSON(http.StatusOK, models.Page[models.Order]{
+		Data:       orders,
+		Pagination: pageInfo(c, page, next),
+	})
+}
+
+// parseOrderFilter reads status, customer_id, created_after and
+// created_before from the query string
+func parseOrderFilter(c *gin.Context) (models.OrderFilter, error) {
+	filter := models.OrderFilter{Status: c.Query("status")}
+
+	if customerID, ok := c.GetQuery("customer_id"); ok {
+		filter.CustomerID = &customerID
+	}
+
+	var err error
+	if filter.CreatedAfter, err = parseTimeParam(c, "created_after"); err != nil {
+		return filter, err
+	}
+	if filter.CreatedBefore, err = parseTimeParam(c, "created_before"); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
 }
 
 // GetOrder returns a single order with items
@@ -50,13 +105,14 @@ func (h *OrderHandler) GetOrder(c *gin.Context) {
 		return
 	}
 
-	order, err := h.repo.GetByID(id)
+	order, err := h.repo.GetByID(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	if order == nil {
+	// Customers get the same answer for other people's orders as for missing ones
+	if order == nil || !canAccess(auth.FromContext(c), order) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
 		return
 	}
@@ -72,53 +128,171 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 		return
 	}
 
+	// Customers always order for themselves; staff may order on behalf of one
+	identity := auth.FromContext(c)
+	customerID := identity.Subject
+	if identity.IsPrivileged() {
+		customerID = req.CustomerID
+	}
+
+	if req.ShipTo != nil {
+		if err := req.ShipTo.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.AllocationStrategy != "" {
+		if _, err := allocation.ParseStrategy(req.AllocationStrategy); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.ReservationID < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid reservation ID"})
+		return
+	}
+
 	// Build order with product details
 	order := models.Order{
-		CustomerName: req.CustomerName,
-		Status:       "pending",
+		CustomerID:         customerID,
+		CustomerName:       req.CustomerName,
+		Status:             "pending",
+		ShipTo:             req.ShipTo,
+		AllocationStrategy: req.AllocationStrategy,
+		ReservationID:      req.ReservationID,
+	}
+
+	ctx := c.Request.Context()
+	totalAmount := models.NewMoney(0, models.DefaultCurrency)
+
+	// Resolve every item to a variant in one batch instead of one request
+	// per item. Items without a SKU use their product's default variant.
+	var skus []string
+	var productIDs []int
+	for i, item := range req.Items {
+		switch {
+		case item.SKU != "":
+			req.Items[i].SKU = strings.ToUpper(strings.TrimSpace(item.SKU))
+			skus = append(skus, req.Items[i].SKU)
+		case item.ProductID > 0:
+			productIDs = append(productIDs, item.ProductID)
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "each item needs a sku or a product_id"})
+			return
+		}
+		if item.Quantity <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "quantity must be positive"})
+			return
+		}
+	}
+	variants, err := h.productClient.GetVariants(ctx, skus, productIDs)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to fetch variants", "count", len(req.Items), "error", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
 	}
 
-	var totalAmount float64
+	bySKU := make(map[string]models.Variant, len(variants))
+	defaults := make(map[int]models.Variant, len(variants))
+	for _, v := range variants {
+		bySKU[v.SKU] = v
+		if v.IsDefault {
+			defaults[v.ProductID] = v
+		}
+	}
 
 	for _, item := range req.Items {
-		log.Printf("📞 Fetching product %d from Product Service", item.ProductID)
-		product, err := h.productClient.GetProduct(item.ProductID)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		variant, ok := bySKU[item.SKU]
+		if item.SKU == "" {
+			variant, ok = defaults[item.ProductID]
+		}
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s not found", itemRef(item))})
 			return
 		}
+		if item.SKU != "" && item.ProductID > 0 && item.ProductID != variant.ProductID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("sku %s does not belong to product %d", item.SKU, item.ProductID)})
+			return
+		}
+
+		if item.ExpectedPrice != nil && *item.ExpectedPrice != variant.Price {
+			honored, err := h.honorPrice(ctx, variant, *item.ExpectedPrice, req.PricedAt)
+			if err != nil {
+				slog.WarnContext(ctx, "failed to check shown price", "product_id", variant.ProductID, "error", err)
+				c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+				return
+			}
+			if !honored {
+				c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("price of %s changed to %s", itemRef(item), variant.Price)})
+				return
+			}
+			variant.Price = *item.ExpectedPrice
+		}
 
 		orderItem := models.OrderItem{
-			ProductID:   product.ID,
-			ProductName: product.Name,
+			ProductID:   variant.ProductID,
+			VariantID:   variant.ID,
+			SKU:         variant.SKU,
+			ProductName: variant.ProductName,
 			Quantity:    item.Quantity,
-			Price:       product.Price,
+			Price:       variant.Price,
 		}
 
-		totalAmount += product.Price * float64(item.Quantity)
+		// The first item fixes the order currency; mixing currencies is rejected
+		if len(order.Items) == 0 {
+			totalAmount = models.NewMoney(0, variant.Price.Currency)
+		}
+		lineAmount, err := variant.Price.Mul(item.Quantity)
+		if err == nil {
+			totalAmount, err = totalAmount.Add(lineAmount)
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		order.Items = append(order.Items, orderItem)
 	}
 
 	order.TotalAmount = totalAmount
 
 	// Save to database
-	if err := h.repo.Create(&order); err != nil {
+	if err := h.repo.Create(ctx, &order); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Publish order.created event
-	if err := h.publisher.PublishOrderCreated(&order); err != nil {
-		log.Printf("⚠️ Failed to publish event: %v", err)
+	if err := h.publisher.PublishOrderCreated(ctx, &order); err != nil {
+		slog.ErrorContext(ctx, "failed to publish order.created event", "order_id", order.ID, "error", err)
 		// Don't fail the request, order is already created
 	} else {
-		log.Printf("📤 Published order.created event for Order #%d", order.ID)
+		slog.DebugContext(ctx, "published order.created event", "order_id", order.ID)
 	}
 
-	log.Printf("✅ Order #%d created with total $%.2f", order.ID, order.TotalAmount)
+	slog.InfoContext(ctx, "order created", "order_id", order.ID, "total_amount", order.TotalAmount.String())
 	c.JSON(http.StatusCreated, order)
 }
 
+// honorPrice reports whether a price shown at pricedAt still holds now
+// that the variant costs something else: it must have been shown within
+// priceHoldPeriod and been the product's price at the time. Variants with
+// their own price have no history to check against.
+func (h *OrderHandler) honorPrice(ctx context.Context, variant models.Variant, expected models.Money, pricedAt *time.Time) (bool, error) {
+	if pricedAt == nil || variant.PriceOverride != nil {
+		return false, nil
+	}
+	now := time.Now()
+	if pricedAt.After(now) || now.Sub(*pricedAt) > priceHoldPeriod {
+		return false, nil
+	}
+
+	product, err := h.productClient.GetProductAt(ctx, variant.ProductID, *pricedAt)
+	if err != nil || product == nil {
+		return false, err
+	}
+	return product.Price == expected, nil
+}
+
 // UpdateOrderStatus updates the order status
 func (h *OrderHandler) UpdateOrderStatus(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
@@ -147,10 +321,54 @@ func (h *OrderHandler) UpdateOrderStatus(c *gin.Context) {
 		return
 	}
 
-	if err := h.repo.UpdateStatus(id, req.Status); err != nil {
+	// Customers may only cancel their own orders before they ship
+	identity := auth.FromContext(c)
+	if !identity.IsPrivileged() {
+		if req.Status != "cancelled" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "customers may only cancel orders"})
+			return
+		}
+
+		order, err := h.repo.GetByID(c.Request.Context(), id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if order == nil || !canAccess(identity, order) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
+			return
+		}
+		if order.Status != "pending" && order.Status != "confirmed" {
+			c.JSON(http.StatusConflict, gin.H{"error": "order can no longer be cancelled"})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	if err := h.repo.UpdateStatus(ctx, id, req.Status); err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
 
+	// Releasing stock is idempotent, so repeated cancellations are harmless
+	if req.Status == "cancelled" {
+		if err := h.publisher.PublishOrderCancelled(ctx, id); err != nil {
+			slog.ErrorContext(ctx, "failed to publish order.cancelled event", "order_id", id, "error", err)
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "order status updated"})
 }
+
+// canAccess reports whether identity may see or act on order
+func canAccess(identity *auth.Identity, order *models.Order) bool {
+	return identity.IsPrivileged() || (order.CustomerID != "" && order.CustomerID == identity.Subject)
+}
+
+// itemRef names an order item in error messages
+func itemRef(item models.CreateOrderItemRequest) string {
+	if item.SKU != "" {
+		return "sku " + item.SKU
+	}
+	return fmt.Sprintf("product %d", item.ProductID)
+}
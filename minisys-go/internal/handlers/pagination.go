@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/models"
+)
+
+// parsePageRequest reads limit, cursor and sort from the query string
+func parsePageRequest(c *gin.Context, defaultSort string) (models.PageRequest, error) {
+	page := models.PageRequest{
+		Limit:  models.DefaultPageLimit,
+		Cursor: c.Query("cursor"),
+		Sort:   c.DefaultQuery("sort", defaultSort),
+	}
+
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > models.MaxPageLimit {
+			return page, fmt.Errorf("limit must be between 1 and %d", models.MaxPageLimit)
+		}
+		page.Limit = limit
+	}
+
+	return page, nil
+}
+
+// parseTimeParam reads an optional RFC 3339 timestamp or YYYY-MM-DD date
+func parseTimeParam(c *gin.Context, name string) (*time.Time, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, nil
+	}
+
+	for _, layout := range []string{time.RFC3339Nano, time.DateOnly} {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("%s must be an RFC 3339 timestamp or a YYYY-MM-DD date", name)
+}
+
+// pageInfo builds the pagination metadata, linking to the next page with
+// the same query string and the new cursor
+func pageInfo(c *gin.Context, page models.PageRequest, nextCursor string) models.PageInfo {
+	info := models.PageInfo{
+		Limit:      page.Limit,
+		Sort:       page.Sort,
+		HasMore:    nextCursor != "",
+		NextCursor: nextCursor,
+	}
+
+	if nextCursor != "" {
+		query := c.Request.URL.Query()
+		query.Set("cursor", nextCursor)
+		info.Next = c.Request.URL.Path + "?" + query.Encode()
+	}
+
+	return info
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/db"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/models"
+)
+
+type PriceHandler struct {
+	repo     *db.PriceRepository
+	products *db.CachedProductRepository
+}
+
+func NewPriceHandler(repo *db.PriceRepository, products *db.CachedProductRepository) *PriceHandler {
+	return &PriceHandler{repo: repo, products: products}
+}
+
+// ListPrices returns one page of a product's price history, scheduled
+// prices included, newest first by default
+func (h *PriceHandler) ListPrices(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID"})
+		return
+	}
+
+	page, err := parsePageRequest(c, "-effective_from")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	product, err := h.products.GetByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if product == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+		return
+	}
+
+	prices, next, err := h.repo.History(c.Request.Context(), id, page)
+	if errors.Is(err, db.ErrInvalidPage) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Page[models.ProductPrice]{
+		Data:       prices,
+		Pagination: pageInfo(c, page, next),
+	})
+}
+
+// SchedulePrice sets a product's price from effective_from, or from now,
+// until effective_to. A price that starts now is applied straight away;
+// later ones are applied by the price scheduler.
+func (h *PriceHandler) SchedulePrice(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID"})
+		return
+	}
+
+	var req models.SchedulePriceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Price.Amount <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "price must be positive"})
+		return
+	}
+	if req.EffectiveTo != nil {
+		from := time.Now()
+		if req.EffectiveFrom != nil && req.EffectiveFrom.After(from) {
+			from = *req.EffectiveFrom
+		}
+		if !req.EffectiveTo.After(from) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": db.ErrInvalidPriceRange.Error()})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	price, err := h.repo.Schedule(ctx, id, req, actor(c))
+	if errors.Is(err, db.ErrInvalidPriceRange) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if price == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+		return
+	}
+
+	if price.ActivatedAt != nil {
+		h.products.Invalidate(ctx, id)
+	}
+
+	slog.InfoContext(ctx, "price scheduled", "product_id", id, "price", price.Price.Decimal(),
+		"currency", price.Price.Currency, "effective_from", price.EffectiveFrom, "effective_to", price.EffectiveTo)
+	c.JSON(http.StatusCreated, price)
+}
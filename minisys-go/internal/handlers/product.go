@@ -1,20 +1,40 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/db"
 	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/models"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/publisher"
+)
+
+const (
+	// maxBatchIDs caps how many products one ?ids= lookup may ask for
+	maxBatchIDs = 100
+
+	maxSearchLength = 200
+	maxSearchLimit  = 50
+
+	maxTags      = 20
+	maxTagLength = 64
 )
 
 type ProductHandler struct {
-	repo *db.CachedProductRepository
+	repo       *db.CachedProductRepository
+	attributes *db.AttributeRepository
+	publisher  *publisher.ProductPublisher
 }
 
-func NewProductHandler(repo *db.CachedProductRepository) *ProductHandler {
-	return &ProductHandler{repo: repo}
+func NewProductHandler(repo *db.CachedProductRepository, attributes *db.AttributeRepository, pub *publisher.ProductPublisher) *ProductHandler {
+	return &ProductHandler{repo: repo, attributes: attributes, publisher: pub}
 }
 
 // HealthCheck returns server status
@@ -22,26 +42,212 @@ func (h *ProductHandler) HealthCheck(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "healthy"})
 }
 
-// ListProducts returns all products
+// ListProducts returns all products, or only those named by ?ids=1,2,3
 func (h *ProductHandler) ListProducts(c *gin.Context) {
-	products, err := h.repo.GetAll(c.Request.Context())
+	if raw, ok := c.GetQuery("ids"); ok {
+		h.getProductsByIDs(c, raw)
+		return
+	}
+
+	page, err := parsePageRequest(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter, err := parseProductFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	products, next, err := h.repo.List(c.Request.Context(), filter, page)
+	if errors.Is(err, db.ErrInvalidPage) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, products)
+	c.JSON(http.StatusOK, models.Page[models.Product]{
+		Data:       products,
+		Pagination: pageInfo(c, page, next),
+	})
 }
 
-// GetProduct returns a single product
+// parseProductFilter reads min_price, max_price, in_stock, created_after
+// and created_before from the query string
+func parseProductFilter(c *gin.Context) (models.ProductFilter, error) {
+	var filter models.ProductFilter
+	currency := c.DefaultQuery("currency", models.DefaultCurrency)
+
+	for name, dest := range map[string]**models.Money{"min_price": &filter.MinPrice, "max_price": &filter.MaxPrice} {
+		if raw := c.Query(name); raw != "" {
+			price, err := models.ParseMoney(raw, currency)
+			if err != nil {
+				return filter, fmt.Errorf("%s: %w", name, err)
+			}
+			*dest = &price
+		}
+	}
+
+	if raw := c.Query("in_stock"); raw != "" {
+		inStock, err := strconv.ParseBool(raw)
+		if err != nil {
+			return filter, fmt.Errorf("in_stock must be true or false")
+		}
+		filter.InStock = inStock
+	}
+
+	var err error
+	if filter.CreatedAfter, err = parseTimeParam(c, "created_after"); err != nil {
+		return filter, err
+	}
+	if filter.CreatedBefore, err = parseTimeParam(c, "created_before"); err != nil {
+		return filter, err
+	}
+
+	if raw := c.Query("category_id"); raw != "" {
+		categoryID, err := strconv.Atoi(raw)
+		if err != nil || categoryID <= 0 {
+			return filter, fmt.Errorf("invalid category ID")
+		}
+		filter.CategoryID = &categoryID
+	}
+
+	// Tags may repeat (?tag=a&tag=b) or be comma-separated
+	var tags []string
+	for _, raw := range c.QueryArray("tag") {
+		tags = append(tags, strings.Split(raw, ",")...)
+	}
+	if filter.Tags, err = normalizeTags(tags); err != nil {
+		return filter, err
+	}
+
+	for key, values := range c.Request.URL.Query() {
+		if name, ok := strings.CutPrefix(key, "attr."); ok && name != "" {
+			if filter.Attributes == nil {
+				filter.Attributes = make(map[string]string)
+			}
+			filter.Attributes[name] = values[0]
+		}
+	}
+
+	return filter, nil
+}
+
+// getProductsByIDs returns the named products that exist, in the order
+// asked for, as a single page
+func (h *ProductHandler) getProductsByIDs(c *gin.Context, raw string) {
+	ids, err := parseIDs(raw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	products, err := h.repo.GetByIDs(c.Request.Context(), ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Page[models.Product]{
+		Data:       products,
+		Pagination: models.PageInfo{Limit: len(ids)},
+	})
+}
+
+// parseIDs reads a comma-separated list of product IDs, dropping duplicates
+func parseIDs(raw string) ([]int, error) {
+	seen := make(map[int]bool)
+	var ids []int
+
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		id, err := strconv.Atoi(part)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("invalid product ID %q", part)
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("ids must list at least one product ID")
+	}
+	if len(ids) > maxBatchIDs {
+		return nil, fmt.Errorf("at most %d product IDs may be requested at once", maxBatchIDs)
+	}
+
+	return ids, nil
+}
+
+// SearchProducts ranks products by how well their name matches ?q=
+func (h *ProductHandler) SearchProducts(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+	if len(q) > maxSearchLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("q must be at most %d characters", maxSearchLength)})
+		return
+	}
+
+	limit := models.DefaultPageLimit
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxSearchLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", maxSearchLimit)})
+			return
+		}
+		limit = n
+	}
+
+	results, err := h.repo.Search(c.Request.Context(), q, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if results == nil {
+		results = []models.ProductSearchResult{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"query": q, "data": results})
+}
+
+// GetProduct returns a single product. With ?at= it is priced as it was
+// at that time, so a price shown earlier can be checked.
 func (h *ProductHandler) GetProduct(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID"})
 		return
 	}
+	at, err := parseTimeParam(c, "at")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	product, err := h.repo.GetByID(c.Request.Context(), id)
+	var product *models.Product
+	if at != nil {
+		product, err = h.repo.GetByIDAt(c.Request.Context(), id, *at)
+	} else {
+		product, err = h.repo.GetByID(c.Request.Context(), id)
+	}
+	if errors.Is(err, db.ErrNoPriceAt) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -52,6 +258,10 @@ func (h *ProductHandler) GetProduct(c *gin.Context) {
 		return
 	}
 
+	// A past price is not the version the ETag describes
+	if at == nil {
+		setETag(c, product)
+	}
 	c.JSON(http.StatusOK, product)
 }
 
@@ -62,17 +272,275 @@ func (h *ProductHandler) CreateProduct(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if req.Price.Amount <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "price must be positive"})
+		return
+	}
+	if req.Quantity < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "quantity must not be negative"})
+		return
+	}
+	if req.SKU != "" {
+		sku, err := normalizeSKU(req.SKU)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		req.SKU = sku
+	}
+
+	ctx := stockContext(c, models.MovementInitial)
+	tags, err := normalizeTags(req.Tags)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.Tags = tags
+	if err := h.checkAttributes(ctx, req.Attributes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	product, err := h.repo.Create(c.Request.Context(), req)
+	product, err := h.repo.Create(ctx, req)
+	if errors.Is(err, db.ErrUnknownCategory) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, db.ErrDuplicateSKU) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	setETag(c, product)
 	c.JSON(http.StatusCreated, product)
 }
 
-// DeleteProduct removes a product
+// UpdateProduct replaces every editable field of a product
+func (h *ProductHandler) UpdateProduct(c *gin.Context) {
+	var req models.UpdateProductRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// A full replacement clears whatever the body leaves out
+	categoryID := 0
+	if req.CategoryID != nil {
+		categoryID = *req.CategoryID
+	}
+	if req.Tags == nil {
+		req.Tags = []string{}
+	}
+	if req.Attributes == nil {
+		req.Attributes = models.Attributes{}
+	}
+
+	h.applyPatch(c, models.PatchProductRequest{
+		Name:       &req.Name,
+		Price:      &req.Price,
+		Quantity:   req.Quantity,
+		CategoryID: &categoryID,
+		Tags:       &req.Tags,
+		Attributes: &req.Attributes,
+	})
+}
+
+// PatchProduct changes only the fields present in the request body
+func (h *ProductHandler) PatchProduct(c *gin.Context) {
+	var req models.PatchProductRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Empty() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no fields to update"})
+		return
+	}
+
+	h.applyPatch(c, req)
+}
+
+func (h *ProductHandler) applyPatch(c *gin.Context, patch models.PatchProductRequest) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID"})
+		return
+	}
+
+	ctx := stockContext(c, models.MovementAdjustment)
+	changed, err := validatePatch(&patch)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if patch.Attributes != nil {
+		if err := h.checkAttributes(ctx, *patch.Attributes); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	expectedVersion, ok := ifMatchVersion(c.GetHeader("If-Match"))
+	if !ok {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "If-Match does not name a product version"})
+		return
+	}
+
+	product, err := h.repo.Update(ctx, id, patch, expectedVersion)
+	if errors.Is(err, db.ErrVersionConflict) {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, db.ErrUnknownCategory) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, db.ErrProductDeleted) || errors.Is(err, db.ErrHasVariants) || errors.Is(err, db.ErrInsufficientStock) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if product == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+		return
+	}
+
+	if err := h.publisher.PublishProductUpdated(ctx, product, changed); err != nil {
+		slog.ErrorContext(ctx, "failed to publish product.updated event", "product_id", product.ID, "error", err)
+		// Don't fail the request, the product is already updated
+	}
+
+	slog.InfoContext(ctx, "product updated", "product_id", product.ID, "version", product.Version, "changed", changed)
+	setETag(c, product)
+	c.JSON(http.StatusOK, product)
+}
+
+// validatePatch checks the fields present in patch and returns their names
+func validatePatch(patch *models.PatchProductRequest) ([]string, error) {
+	var changed []string
+
+	if patch.Name != nil {
+		name := strings.TrimSpace(*patch.Name)
+		if name == "" {
+			return nil, fmt.Errorf("name must not be empty")
+		}
+		patch.Name = &name
+		changed = append(changed, "name")
+	}
+	if patch.Price != nil {
+		if patch.Price.Amount <= 0 {
+			return nil, fmt.Errorf("price must be positive")
+		}
+		changed = append(changed, "price")
+	}
+	if patch.Quantity != nil {
+		if *patch.Quantity < 0 {
+			return nil, fmt.Errorf("quantity must not be negative")
+		}
+		changed = append(changed, "quantity")
+	}
+	if patch.CategoryID != nil {
+		if *patch.CategoryID < 0 {
+			return nil, fmt.Errorf("invalid category ID")
+		}
+		changed = append(changed, "category_id")
+	}
+	if patch.Tags != nil {
+		tags, err := normalizeTags(*patch.Tags)
+		if err != nil {
+			return nil, err
+		}
+		patch.Tags = &tags
+		changed = append(changed, "tags")
+	}
+	if patch.Attributes != nil {
+		changed = append(changed, "attributes")
+	}
+
+	return changed, nil
+}
+
+// normalizeTags lower-cases, trims, de-duplicates and sorts tags
+func normalizeTags(tags []string) ([]string, error) {
+	seen := make(map[string]bool)
+	normalized := []string{}
+
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len(tag) > maxTagLength {
+			return nil, fmt.Errorf("tags must be at most %d characters", maxTagLength)
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+
+	if len(normalized) > maxTags {
+		return nil, fmt.Errorf("a product may have at most %d tags", maxTags)
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+// checkAttributes validates attribute values against the attribute schema
+func (h *ProductHandler) checkAttributes(ctx context.Context, attrs models.Attributes) error {
+	if len(attrs) == 0 {
+		return nil
+	}
+
+	definitions, err := h.attributes.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	return checkAttributeValues(definitions, attrs)
+}
+
+// checkAttributeValues validates attribute values against already loaded
+// definitions
+func checkAttributeValues(definitions map[string]models.AttributeDefinition, attrs models.Attributes) error {
+	for name, value := range attrs {
+		definition, ok := definitions[name]
+		if !ok {
+			return fmt.Errorf("unknown attribute %q", name)
+		}
+		if err := definition.Check(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setETag exposes the product version so clients can send it back in If-Match
+func setETag(c *gin.Context, product *models.Product) {
+	c.Header("ETag", fmt.Sprintf("%q", strconv.Itoa(product.Version)))
+}
+
+// ifMatchVersion turns an If-Match header into the version an update must
+// apply to. An absent header or "*" means any version (0).
+func ifMatchVersion(header string) (int, bool) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return 0, true
+	}
+
+	version, err := strconv.Atoi(strings.Trim(header, `"`))
+	if err != nil || version <= 0 {
+		return 0, false
+	}
+	return version, true
+}
+
+// DeleteProduct archives a product. It disappears from listings and can
+// no longer be ordered, but stays readable by ID for existing orders.
 func (h *ProductHandler) DeleteProduct(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -80,11 +548,80 @@ func (h *ProductHandler) DeleteProduct(c *gin.Context) {
 		return
 	}
 
-	err = h.repo.Delete(c.Request.Context(), id)
+	ctx := c.Request.Context()
+	product, err := h.repo.Delete(ctx, id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if product == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
 		return
 	}
 
+	if err := h.publisher.PublishProductDeleted(ctx, product); err != nil {
+		slog.ErrorContext(ctx, "failed to publish product.deleted event", "product_id", id, "error", err)
+		// Don't fail the request, the product is already deleted
+	}
+
+	slog.InfoContext(ctx, "product deleted", "product_id", id)
 	c.JSON(http.StatusOK, gin.H{"message": "product deleted"})
 }
+
+// RestoreProduct brings a deleted product back
+func (h *ProductHandler) RestoreProduct(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	product, restored, err := h.repo.Restore(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if product == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+		return
+	}
+
+	if restored {
+		if err := h.publisher.PublishProductRestored(ctx, product); err != nil {
+			slog.ErrorContext(ctx, "failed to publish product.restored event", "product_id", id, "error", err)
+			// Don't fail the request, the product is already restored
+		}
+		slog.InfoContext(ctx, "product restored", "product_id", id)
+	}
+	setETag(c, product)
+	c.JSON(http.StatusOK, product)
+}
+
+// PurgeProduct removes a deleted product for good, once no open order or
+// active reservation refers to it
+func (h *ProductHandler) PurgeProduct(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	found, err := h.repo.Purge(ctx, id)
+	if errors.Is(err, db.ErrNotDeleted) || errors.Is(err, db.ErrProductInUse) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+		return
+	}
+
+	slog.InfoContext(ctx, "product purged", "product_id", id)
+	c.JSON(http.StatusOK, gin.H{"message": "product purged"})
+}
@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/allocation"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/auth"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/db"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/models"
+)
+
+const (
+	// maxReservationTTL caps how long a checkout may hold stock
+	maxReservationTTL = time.Hour
+	// maxReservationItems caps the lines of one reservation
+	maxReservationItems = 100
+)
+
+type ReservationHandler struct {
+	repo      *db.ReservationRepository
+	products  *db.CachedProductRepository
+	orders    *db.OrderRepository
+	ttl       time.Duration
+	maxActive int
+	strategy  allocation.Strategy
+}
+
+// NewReservationHandler holds stock for ttl unless a request asks for less
+// or more, and lets each caller hold at most maxActive reservations at
+// once. Reservations are confirmed by the order that cites them, when
+// order-service publishes it, or by staff for an order that was stuck;
+// orders that name no strategy are allocated with strategy.
+func NewReservationHandler(repo *db.ReservationRepository, products *db.CachedProductRepository, orders *db.OrderRepository, ttl time.Duration, maxActive int, strategy allocation.Strategy) *ReservationHandler {
+	return &ReservationHandler{
+		repo:      repo,
+		products:  products,
+		orders:    orders,
+		ttl:       min(ttl, maxReservationTTL),
+		maxActive: maxActive,
+		strategy:  strategy,
+	}
+}
+
+// CreateReservation holds stock for a checkout
+func (h *ReservationHandler) CreateReservation(c *gin.Context) {
+	var req models.CreateReservationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	req.Reference = strings.TrimSpace(req.Reference)
+	if req.Reference == "" || len(req.Reference) > maxReferenceLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("reference must be 1-%d characters", maxReferenceLength)})
+		return
+	}
+	if len(req.Items) == 0 || len(req.Items) > maxReservationItems {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("a reservation holds 1-%d items", maxReservationItems)})
+		return
+	}
+	for i, item := range req.Items {
+		if item.Quantity <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "quantity must be positive"})
+			return
+		}
+		if item.SKU == "" && item.ProductID <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "each item needs a sku or a product_id"})
+			return
+		}
+		if item.SKU != "" {
+			sku, err := normalizeSKU(item.SKU)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			req.Items[i].SKU = sku
+		}
+	}
+
+	ttl := h.ttl
+	if req.TTLSeconds != 0 {
+		ttl = time.Duration(req.TTLSeconds) * time.Second
+		if ttl <= 0 || ttl > maxReservationTTL {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("ttl_seconds must be between 1 and %d", int(maxReservationTTL.Seconds()))})
+			return
+		}
+	}
+
+	ctx := stockContext(c, models.MovementOrder)
+	reservation, err := h.repo.Create(ctx, req, ttl, h.maxActive)
+	if errors.Is(err, db.ErrInsufficientStock) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, db.ErrTooManyReservations) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": fmt.Sprintf("at most %d reservations may be active at once", h.maxActive)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.invalidate(c, reservation)
+	slog.InfoContext(ctx, "reservation created", "reservation_id", reservation.ID, "reference", reservation.Reference, "expires_at", reservation.ExpiresAt)
+	c.JSON(http.StatusCreated, reservation)
+}
+
+// GetReservation returns a reservation and what it holds
+func (h *ReservationHandler) GetReservation(c *gin.Context) {
+	reservation := h.loadReservation(c)
+	if reservation == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, reservation)
+}
+
+// ConfirmReservation allocates the held stock to the order that cites the
+// reservation, as order.created would. Staff use it when that event was
+// lost; the order must exist, cite this reservation and belong to whoever
+// made it, and its stored items are what get allocated.
+func (h *ReservationHandler) ConfirmReservation(c *gin.Context) {
+	current := h.loadReservation(c)
+	if current == nil {
+		return
+	}
+
+	var req models.ConfirmReservationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := stockContext(c, models.MovementOrder)
+	order, err := h.orders.GetByID(ctx, req.OrderID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if order == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
+		return
+	}
+	if order.ReservationID != current.ID {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("order %d does not cite reservation %d", order.ID, current.ID)})
+		return
+	}
+
+	strategy := h.strategy
+	if order.AllocationStrategy != "" {
+		if strategy, err = allocation.ParseStrategy(order.AllocationStrategy); err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	items := make([]models.OrderItemEvent, 0, len(order.Items))
+	for _, item := range order.Items {
+		items = append(items, models.OrderItemEvent{ProductID: item.ProductID, VariantID: item.VariantID, SKU: item.SKU, Quantity: item.Quantity})
+	}
+
+	reservation, allocations, err := h.repo.Confirm(ctx, current.ID, order.ID, order.CustomerID, items, strategy, order.ShipTo)
+	if errors.Is(err, db.ErrReservationClosed) || errors.Is(err, db.ErrReservationNotOwned) || errors.Is(err, db.ErrOrderCancelled) ||
+		errors.Is(err, db.ErrInsufficientStock) || errors.Is(err, allocation.ErrUnfulfillable) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if reservation == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "reservation not found"})
+		return
+	}
+
+	h.invalidate(c, reservation)
+	slog.InfoContext(ctx, "reservation confirmed", "reservation_id", reservation.ID, "order_id", order.ID, "allocations", len(allocations))
+	c.JSON(http.StatusOK, gin.H{"reservation": reservation, "allocations": allocations})
+}
+
+// ReleaseReservation gives the held stock back
+func (h *ReservationHandler) ReleaseReservation(c *gin.Context) {
+	current := h.loadReservation(c)
+	if current == nil {
+		return
+	}
+
+	ctx := c.Request.Context()
+	reservation, err := h.repo.Release(ctx, current.ID)
+	if errors.Is(err, db.ErrReservationClosed) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if reservation == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "reservation not found"})
+		return
+	}
+
+	h.invalidate(c, reservation)
+	slog.InfoContext(ctx, "reservation released", "reservation_id", reservation.ID)
+	c.JSON(http.StatusOK, reservation)
+}
+
+// loadReservation resolves the :id path parameter, writing the error
+// response and returning nil if the reservation does not exist or belongs
+// to another customer
+func (h *ReservationHandler) loadReservation(c *gin.Context) *models.Reservation {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid reservation ID"})
+		return nil
+	}
+
+	reservation, err := h.repo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil
+	}
+	identity := auth.FromContext(c)
+	if reservation == nil || (identity != nil && !identity.IsPrivileged() && reservation.Actor != identity.Subject) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "reservation not found"})
+		return nil
+	}
+
+	return reservation
+}
+
+// invalidate drops the cached products whose availability changed
+func (h *ReservationHandler) invalidate(c *gin.Context, reservation *models.Reservation) {
+	seen := make(map[int]bool)
+	for _, item := range reservation.Items {
+		if !seen[item.ProductID] {
+			seen[item.ProductID] = true
+			h.products.Invalidate(c.Request.Context(), item.ProductID)
+		}
+	}
+}
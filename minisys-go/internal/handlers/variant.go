@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/db"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/models"
+)
+
+// maxVariantLookup caps how many SKUs and product IDs one lookup may name
+const maxVariantLookup = 100
+
+var skuPattern = regexp.MustCompile(`^[A-Z0-9][A-Z0-9._-]{0,63}$`)
+
+type VariantHandler struct {
+	repo     *db.VariantRepository
+	lookups  *db.CachedVariantRepository
+	products *db.CachedProductRepository
+}
+
+func NewVariantHandler(repo *db.VariantRepository, lookups *db.CachedVariantRepository, products *db.CachedProductRepository) *VariantHandler {
+	return &VariantHandler{repo: repo, lookups: lookups, products: products}
+}
+
+// ListVariants returns every variant of a product
+func (h *VariantHandler) ListVariants(c *gin.Context) {
+	product := h.loadProduct(c)
+	if product == nil {
+		return
+	}
+
+	variants, err := h.repo.ListByProduct(c.Request.Context(), product.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, variants)
+}
+
+// LookupVariants resolves ?skus= and ?product_ids= (both comma-separated)
+// to variants; a product ID stands for that product's default variant
+func (h *VariantHandler) LookupVariants(c *gin.Context) {
+	var skus []string
+	for _, raw := range strings.Split(c.Query("skus"), ",") {
+		if raw = strings.TrimSpace(raw); raw != "" {
+			skus = append(skus, strings.ToUpper(raw))
+		}
+	}
+
+	var productIDs []int
+	if raw := c.Query("product_ids"); raw != "" {
+		ids, err := parseIDs(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		productIDs = ids
+	}
+
+	if len(skus) == 0 && len(productIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "skus or product_ids is required"})
+		return
+	}
+	if len(skus)+len(productIDs) > maxVariantLookup {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d skus and product IDs per request", maxVariantLookup)})
+		return
+	}
+
+	variants, err := h.lookups.Lookup(c.Request.Context(), skus, productIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, variants)
+}
+
+// CreateVariant adds a SKU to a product
+func (h *VariantHandler) CreateVariant(c *gin.Context) {
+	product := h.loadProduct(c)
+	if product == nil {
+		return
+	}
+
+	var req models.CreateVariantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sku, err := normalizeSKU(req.SKU)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.SKU = sku
+	if err := checkVariant(product, req.Options, req.PriceOverride, &req.Quantity); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := stockContext(c, models.MovementInitial)
+	variant, err := h.repo.Create(ctx, product.ID, req)
+	if errors.Is(err, db.ErrDuplicateSKU) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if variant == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+		return
+	}
+
+	h.products.Invalidate(ctx, product.ID)
+	slog.InfoContext(ctx, "variant created", "product_id", product.ID, "variant_id", variant.ID, "sku", variant.SKU)
+	c.JSON(http.StatusCreated, variant)
+}
+
+// UpdateVariant changes the fields present in the request body
+func (h *VariantHandler) UpdateVariant(c *gin.Context) {
+	product := h.loadProduct(c)
+	if product == nil {
+		return
+	}
+	id, err := strconv.Atoi(c.Param("variantId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid variant ID"})
+		return
+	}
+
+	var req models.PatchVariantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.SKU != nil {
+		sku, err := normalizeSKU(*req.SKU)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		req.SKU = &sku
+	}
+	var options models.Attributes
+	if req.Options != nil {
+		options = *req.Options
+	}
+	if req.ClearPriceOverride && req.PriceOverride != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "price_override and clear_price_override are mutually exclusive"})
+		return
+	}
+	if err := checkVariant(product, options, req.PriceOverride, req.Quantity); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := stockContext(c, models.MovementAdjustment)
+	variant, err := h.repo.Update(ctx, product.ID, id, req)
+	if errors.Is(err, db.ErrDuplicateSKU) || errors.Is(err, db.ErrInsufficientStock) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if variant == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "variant not found"})
+		return
+	}
+
+	h.products.Invalidate(ctx, product.ID)
+	c.JSON(http.StatusOK, variant)
+}
+
+// DeleteVariant removes a variant other than the product's default one
+func (h *VariantHandler) DeleteVariant(c *gin.Context) {
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID"})
+		return
+	}
+	id, err := strconv.Atoi(c.Param("variantId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid variant ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	found, err := h.repo.Delete(ctx, productID, id)
+	if errors.Is(err, db.ErrDefaultVariant) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "variant not found"})
+		return
+	}
+
+	h.products.Invalidate(ctx, productID)
+	c.JSON(http.StatusOK, gin.H{"message": "variant deleted"})
+}
+
+// loadProduct resolves the :id path parameter, writing the error response
+// and returning nil if the product does not exist
+func (h *VariantHandler) loadProduct(c *gin.Context) *models.Product {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID"})
+		return nil
+	}
+
+	product, err := h.products.GetByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil
+	}
+	if product == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+		return nil
+	}
+
+	return product
+}
+
+// checkVariant validates variant fields against the owning product.
+// Options describe what tells variants apart (size, color) and must be strings.
+func checkVariant(product *models.Product, options models.Attributes, price *models.Money, quantity *int) error {
+	for name, value := range options {
+		if _, ok := value.(string); !ok || name == "" {
+			return fmt.Errorf("option %q must be a string", name)
+		}
+	}
+	if price != nil {
+		if price.Amount <= 0 {
+			return fmt.Errorf("price_override must be positive")
+		}
+		if price.Currency != product.Price.Currency {
+			return fmt.Errorf("price_override must be in %s, the product's currency", product.Price.Currency)
+		}
+	}
+	if quantity != nil && *quantity < 0 {
+		return fmt.Errorf("quantity must not be negative")
+	}
+	return nil
+}
+
+// normalizeSKU upper-cases a SKU and checks its format
+func normalizeSKU(sku string) (string, error) {
+	sku = strings.ToUpper(strings.TrimSpace(sku))
+	if !skuPattern.MatchString(sku) {
+		return "", fmt.Errorf("sku must be 1-64 letters, digits, dots, dashes or underscores")
+	}
+	return sku, nil
+}
@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/db"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/models"
+)
+
+var warehouseCodePattern = regexp.MustCompile(`^[A-Z0-9][A-Z0-9_-]{0,31}$`)
+
+type WarehouseHandler struct {
+	repo     *db.WarehouseRepository
+	products *db.CachedProductRepository
+}
+
+func NewWarehouseHandler(repo *db.WarehouseRepository, products *db.CachedProductRepository) *WarehouseHandler {
+	return &WarehouseHandler{repo: repo, products: products}
+}
+
+// ListWarehouses returns every warehouse
+func (h *WarehouseHandler) ListWarehouses(c *gin.Context) {
+	warehouses, err := h.repo.GetAll(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, warehouses)
+}
+
+// GetWarehouse returns a single warehouse
+func (h *WarehouseHandler) GetWarehouse(c *gin.Context) {
+	warehouse := h.loadWarehouse(c)
+	if warehouse == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, warehouse)
+}
+
+// CreateWarehouse adds a warehouse
+func (h *WarehouseHandler) CreateWarehouse(c *gin.Context) {
+	var req models.CreateWarehouseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	req.Code = strings.ToUpper(strings.TrimSpace(req.Code))
+	req.Name = strings.TrimSpace(req.Name)
+	if !warehouseCodePattern.MatchString(req.Code) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code must be 1-32 letters, digits, dashes or underscores"})
+		return
+	}
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name must not be empty"})
+		return
+	}
+	if req.Location != nil {
+		if err := req.Location.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	warehouse, err := h.repo.Create(ctx, req)
+	if errors.Is(err, db.ErrWarehouseConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	slog.InfoContext(ctx, "warehouse created", "warehouse_id", warehouse.ID, "code", warehouse.Code)
+	c.JSON(http.StatusCreated, warehouse)
+}
+
+// UpdateWarehouse changes the fields present in the request body
+func (h *WarehouseHandler) UpdateWarehouse(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse ID"})
+		return
+	}
+
+	var req models.UpdateWarehouseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "name must not be empty"})
+			return
+		}
+		req.Name = &name
+	}
+	if req.Location != nil {
+		if err := req.Location.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	warehouse, err := h.repo.Update(c.Request.Context(), id, req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if warehouse == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "warehouse not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, warehouse)
+}
+
+// ListWarehouseStock returns what a warehouse holds, by SKU
+func (h *WarehouseHandler) ListWarehouseStock(c *gin.Context) {
+	warehouse := h.loadWarehouse(c)
+	if warehouse == nil {
+		return
+	}
+
+	stock, err := h.repo.Stock(c.Request.Context(), warehouse.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stock)
+}
+
+// SetWarehouseStock sets how many units of a variant a warehouse holds
+func (h *WarehouseHandler) SetWarehouseStock(c *gin.Context) {
+	warehouse := h.loadWarehouse(c)
+	if warehouse == nil {
+		return
+	}
+	variantID, err := strconv.Atoi(c.Param("variantId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid variant ID"})
+		return
+	}
+
+	var req models.SetStockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if *req.Quantity < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "quantity must not be negative"})
+		return
+	}
+
+	ctx := stockContext(c, models.MovementAdjustment)
+	productID, err := h.repo.SetStock(ctx, warehouse.ID, variantID, *req.Quantity)
+	if errors.Is(err, db.ErrInsufficientStock) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if productID == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "variant not found"})
+		return
+	}
+
+	h.products.Invalidate(ctx, productID)
+	slog.InfoContext(ctx, "warehouse stock set", "warehouse", warehouse.Code, "variant_id", variantID, "quantity", *req.Quantity)
+	c.JSON(http.StatusOK, gin.H{"message": "stock updated"})
+}
+
+// GetProductStock returns a product's stock by variant and warehouse
+func (h *WarehouseHandler) GetProductStock(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	product, err := h.products.GetByID(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if product == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+		return
+	}
+
+	stock, err := h.repo.ProductStock(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stock)
+}
+
+// loadWarehouse resolves the :id path parameter, writing the error
+// response and returning nil if the warehouse does not exist
+func (h *WarehouseHandler) loadWarehouse(c *gin.Context) *models.Warehouse {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse ID"})
+		return nil
+	}
+
+	warehouse, err := h.repo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil
+	}
+	if warehouse == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "warehouse not found"})
+		return nil
+	}
+
+	return warehouse
+}
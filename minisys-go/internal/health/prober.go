@@ -0,0 +1,264 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	StatusHealthy   = "healthy"
+	StatusDegraded  = "degraded"
+	StatusUnhealthy = "unhealthy"
+	StatusUnknown   = "unknown"
+)
+
+// Target is a single upstream instance to probe
+type Target struct {
+	InstanceID string
+	URL        string
+}
+
+// TargetSource returns the instances to probe, keyed by service name
+type TargetSource func() map[string][]Target
+
+// InstanceStatus is the last known health of one upstream instance
+type InstanceStatus struct {
+	InstanceID          string     `json:"instance_id"`
+	URL                 string     `json:"url"`
+	Status              string     `json:"status"`
+	LatencyMs           float64    `json:"latency_ms"`
+	LastChecked         time.Time  `json:"last_checked"`
+	LastSuccess         *time.Time `json:"last_success,omitempty"`
+	LastFailure         *time.Time `json:"last_failure,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+}
+
+// ServiceStatus aggregates the instances of one upstream service
+type ServiceStatus struct {
+	Status    string           `json:"status"`
+	Healthy   int              `json:"healthy"`
+	Total     int              `json:"total"`
+	Instances []InstanceStatus `json:"instances"`
+}
+
+// Report is a point-in-time view of every probed upstream
+type Report struct {
+	Status    string                   `json:"status"`
+	CheckedAt *time.Time               `json:"checked_at,omitempty"`
+	Services  map[string]ServiceStatus `json:"services"`
+}
+
+// HTTPStatus maps the overall status to the code /health should answer with
+func (r Report) HTTPStatus() int {
+	if r.Status == StatusHealthy {
+		return http.StatusOK
+	}
+	return http.StatusServiceUnavailable
+}
+
+// Prober periodically checks every upstream instance in the background
+// and keeps the latest results so health endpoints never block on I/O
+type Prober struct {
+	targets  TargetSource
+	client   *http.Client
+	interval time.Duration
+
+	mutex     sync.RWMutex
+	results   map[string]map[string]*InstanceStatus
+	checkedAt time.Time
+}
+
+func NewProber(targets TargetSource, interval, timeout time.Duration) *Prober {
+	return &Prober{
+		targets:  targets,
+		client:   &http.Client{Timeout: timeout},
+		interval: interval,
+		results:  make(map[string]map[string]*InstanceStatus),
+	}
+}
+
+// Start probes immediately and then on every interval until ctx is done
+func (p *Prober) Start(ctx context.Context) {
+	go func() {
+		p.ProbeAll(ctx)
+
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				p.ProbeAll(ctx)
+			}
+		}
+	}()
+}
+
+type probeResult struct {
+	service string
+	target  Target
+	latency time.Duration
+	err     error
+}
+
+// ProbeAll checks every instance of every service concurrently
+func (p *Prober) ProbeAll(ctx context.Context) {
+	targets := p.targets()
+
+	var wg sync.WaitGroup
+	results := make(chan probeResult)
+
+	for service, instances := range targets {
+		for _, target := range instances {
+			wg.Add(1)
+			go func(service string, target Target) {
+				defer wg.Done()
+				latency, err := p.probe(ctx, target.URL)
+				results <- probeResult{service: service, target: target, latency: latency, err: err}
+			}(service, target)
+		}
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	collected := make([]probeResult, 0)
+	for result := range results {
+		collected = append(collected, result)
+	}
+
+	p.record(targets, collected)
+}
+
+func (p *Prober) probe(ctx context.Context, baseURL string) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/health", nil)
+	if err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+	resp, err := p.client.Do(req)
+	latency := time.Since(start)
+	if err != nil {
+		return latency, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return latency, fmt.Errorf("health endpoint returned status %d", resp.StatusCode)
+	}
+
+	return latency, nil
+}
+
+func (p *Prober) record(targets map[string][]Target, collected []probeResult) {
+	now := time.Now()
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	// Keep history only for instances that are still registered
+	next := make(map[string]map[string]*InstanceStatus, len(targets))
+	for service, instances := range targets {
+		next[service] = make(map[string]*InstanceStatus, len(instances))
+		for _, target := range instances {
+			status, ok := p.results[service][target.InstanceID]
+			if !ok {
+				status = &InstanceStatus{
+					InstanceID: target.InstanceID,
+					Status:     StatusUnknown,
+				}
+			}
+			status.URL = target.URL
+			next[service][target.InstanceID] = status
+		}
+	}
+
+	for _, result := range collected {
+		status := next[result.service][result.target.InstanceID]
+		status.LastChecked = now
+		status.LatencyMs = float64(result.latency.Microseconds()) / 1000
+
+		if result.err != nil {
+			checked := now
+			status.Status = StatusUnhealthy
+			status.LastFailure = &checked
+			status.LastError = result.err.Error()
+			status.ConsecutiveFailures++
+			slog.Warn("health probe failed", "upstream", result.service, "instance", result.target.InstanceID, "url", result.target.URL, "error", result.err)
+			continue
+		}
+
+		checked := now
+		status.Status = StatusHealthy
+		status.LastSuccess = &checked
+		status.ConsecutiveFailures = 0
+	}
+
+	p.results = next
+	p.checkedAt = now
+}
+
+// Snapshot returns the cached results without probing
+func (p *Prober) Snapshot() Report {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	report := Report{
+		Status:   StatusHealthy,
+		Services: make(map[string]ServiceStatus, len(p.results)),
+	}
+
+	if p.checkedAt.IsZero() {
+		report.Status = StatusUnknown
+		return report
+	}
+	checkedAt := p.checkedAt
+	report.CheckedAt = &checkedAt
+
+	for service, instances := range p.results {
+		svc := ServiceStatus{Instances: make([]InstanceStatus, 0, len(instances))}
+		for _, instance := range instances {
+			svc.Instances = append(svc.Instances, *instance)
+			if instance.Status == StatusHealthy {
+				svc.Healthy++
+			}
+		}
+		sort.Slice(svc.Instances, func(i, j int) bool {
+			return svc.Instances[i].InstanceID < svc.Instances[j].InstanceID
+		})
+
+		svc.Total = len(svc.Instances)
+		switch {
+		case svc.Total > 0 && svc.Healthy == svc.Total:
+			svc.Status = StatusHealthy
+		case svc.Healthy > 0:
+			svc.Status = StatusDegraded
+		default:
+			svc.Status = StatusUnhealthy
+		}
+
+		report.Services[service] = svc
+		report.Status = worst(report.Status, svc.Status)
+	}
+
+	return report
+}
+
+func worst(a, b string) string {
+	rank := map[string]int{StatusHealthy: 0, StatusDegraded: 1, StatusUnhealthy: 2}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
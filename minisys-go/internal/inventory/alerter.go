@@ -0,0 +1,86 @@
+package inventory
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/db"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/models"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/publisher"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/requestid"
+)
+
+// alertBatch caps how many alerts one transaction publishes
+const alertBatch = 100
+
+// Alerter publishes the stock alerts raised by stock movements. Alerts are
+// written in the same transaction as the movement and published here, so
+// a broker outage delays them instead of losing them.
+type Alerter struct {
+	repo      *db.AlertRepository
+	publisher *publisher.InventoryPublisher
+	interval  time.Duration
+}
+
+func NewAlerter(repo *db.AlertRepository, pub *publisher.InventoryPublisher, interval time.Duration) *Alerter {
+	return &Alerter{repo: repo, publisher: pub, interval: interval}
+}
+
+// Start publishes pending alerts on every interval until ctx is done. A
+// zero interval disables publishing; alerts are still raised and listed.
+func (a *Alerter) Start(ctx context.Context) {
+	if a.interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(a.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				a.Publish(requestid.NewContext(ctx, requestid.New()))
+			}
+		}
+	}()
+}
+
+// Publish sends every pending alert, in batches
+func (a *Alerter) Publish(ctx context.Context) {
+	for {
+		n, err := a.repo.PublishPending(ctx, alertBatch, func(alert models.StockAlert) error {
+			return a.publish(ctx, alert)
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to publish stock alerts", "error", err)
+			return
+		}
+		if n < alertBatch {
+			return
+		}
+	}
+}
+
+func (a *Alerter) publish(ctx context.Context, alert models.StockAlert) error {
+	event := models.StockAlertEvent{
+		AlertID:       alert.ID,
+		ProductID:     alert.ProductID,
+		ProductName:   alert.ProductName,
+		Kind:          alert.Kind,
+		Quantity:      alert.Quantity,
+		Threshold:     alert.Threshold,
+		PurchaseOrder: alert.PurchaseOrder,
+		RaisedAt:      alert.CreatedAt,
+	}
+	if err := a.publisher.PublishStockAlert(ctx, event); err != nil {
+		slog.WarnContext(ctx, "failed to publish stock alert, will retry", "alert_id", alert.ID, "error", err)
+		return err
+	}
+
+	slog.InfoContext(ctx, "stock alert raised", "alert_id", alert.ID, "product_id", alert.ProductID, "kind", alert.Kind, "quantity", alert.Quantity)
+	return nil
+}
@@ -0,0 +1,76 @@
+package inventory
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/db"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/models"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/publisher"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/requestid"
+)
+
+// allocationBatch caps how many allocations one transaction publishes
+const allocationBatch = 100
+
+// AllocationRelay publishes inventory.allocated events. They are queued in
+// the transaction that takes an order's stock and published here, so a
+// broker outage delays an order's shipping plan instead of losing it.
+type AllocationRelay struct {
+	repo      *db.WarehouseRepository
+	publisher *publisher.InventoryPublisher
+	interval  time.Duration
+}
+
+func NewAllocationRelay(repo *db.WarehouseRepository, pub *publisher.InventoryPublisher, interval time.Duration) *AllocationRelay {
+	return &AllocationRelay{repo: repo, publisher: pub, interval: interval}
+}
+
+// Start publishes pending allocations on every interval until ctx is
+// done. A zero interval leaves them to explicit Publish calls.
+func (r *AllocationRelay) Start(ctx context.Context) {
+	if r.interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.Publish(requestid.NewContext(ctx, requestid.New()))
+			}
+		}
+	}()
+}
+
+// Publish sends every pending allocation, in batches
+func (r *AllocationRelay) Publish(ctx context.Context) {
+	for {
+		n, err := r.repo.PublishAllocations(ctx, allocationBatch, func(event models.InventoryAllocatedEvent) error {
+			return r.publish(ctx, event)
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to publish allocations", "error", err)
+			return
+		}
+		if n < allocationBatch {
+			return
+		}
+	}
+}
+
+func (r *AllocationRelay) publish(ctx context.Context, event models.InventoryAllocatedEvent) error {
+	if err := r.publisher.PublishAllocated(ctx, event); err != nil {
+		slog.WarnContext(ctx, "failed to publish inventory.allocated event, will retry", "order_id", event.OrderID, "error", err)
+		return err
+	}
+
+	slog.DebugContext(ctx, "published inventory.allocated event", "order_id", event.OrderID, "allocations", len(event.Allocations))
+	return nil
+}
@@ -0,0 +1,68 @@
+package inventory
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/db"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/metrics"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/models"
+)
+
+// Reconciler periodically replays the inventory ledger against the stored
+// stock levels. Stock is a projection of the ledger, so any difference
+// means something changed stock without recording why.
+type Reconciler struct {
+	repo     *db.InventoryRepository
+	interval time.Duration
+}
+
+func NewReconciler(repo *db.InventoryRepository, interval time.Duration) *Reconciler {
+	return &Reconciler{repo: repo, interval: interval}
+}
+
+// Start reconciles on every interval until ctx is done. A zero interval
+// disables the background job; Reconcile can still be called on demand.
+func (r *Reconciler) Start(ctx context.Context) {
+	if r.interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.Reconcile(ctx)
+			}
+		}
+	}()
+}
+
+// Reconcile returns every stock level that disagrees with its ledger,
+// logging each one and exporting the count
+func (r *Reconciler) Reconcile(ctx context.Context) ([]models.StockDrift, error) {
+	drift, err := r.repo.Drift(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to reconcile inventory", "error", err)
+		return nil, err
+	}
+
+	metrics.SetStockDrift(len(drift))
+	for _, d := range drift {
+		slog.WarnContext(ctx, "inventory drift detected",
+			"product_id", d.ProductID, "sku", d.SKU, "warehouse", d.WarehouseCode,
+			"recorded", d.Recorded, "ledger", d.Ledger,
+		)
+	}
+	if len(drift) == 0 {
+		slog.DebugContext(ctx, "inventory reconciled")
+	}
+
+	return drift, nil
+}
@@ -0,0 +1,93 @@
+package inventory
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/cache"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/db"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/models"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/publisher"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/requestid"
+)
+
+// sweepBatch caps how many reservations one transaction expires
+const sweepBatch = 100
+
+// Sweeper releases reservations whose time has run out and announces
+// each one on inventory.reservation_expired
+type Sweeper struct {
+	repo      *db.ReservationRepository
+	publisher *publisher.InventoryPublisher
+	cache     *cache.RedisCache
+	interval  time.Duration
+}
+
+func NewSweeper(repo *db.ReservationRepository, pub *publisher.InventoryPublisher, cache *cache.RedisCache, interval time.Duration) *Sweeper {
+	return &Sweeper{repo: repo, publisher: pub, cache: cache, interval: interval}
+}
+
+// Start sweeps on every interval until ctx is done. A zero interval
+// disables sweeping, leaving expired holds in place until it is re-enabled.
+func (s *Sweeper) Start(ctx context.Context) {
+	if s.interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.Sweep(requestid.NewContext(ctx, requestid.New()))
+			}
+		}
+	}()
+}
+
+// Sweep expires overdue reservations in batches until none are left
+func (s *Sweeper) Sweep(ctx context.Context) {
+	for {
+		expired, err := s.repo.Expire(ctx, sweepBatch)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to sweep reservations", "error", err)
+			return
+		}
+
+		for _, res := range expired {
+			s.expired(ctx, res)
+		}
+		if len(expired) < sweepBatch {
+			return
+		}
+	}
+}
+
+// expired invalidates the products a reservation held and publishes the event
+func (s *Sweeper) expired(ctx context.Context, res models.Reservation) {
+	seen := make(map[int]bool)
+	for _, item := range res.Items {
+		if !seen[item.ProductID] {
+			seen[item.ProductID] = true
+			db.InvalidateProduct(ctx, s.cache, item.ProductID)
+		}
+	}
+
+	event := models.ReservationExpiredEvent{
+		ReservationID: res.ID,
+		Reference:     res.Reference,
+		Items:         res.Items,
+		ExpiredAt:     time.Now().UTC(),
+	}
+	if err := s.publisher.PublishReservationExpired(ctx, event); err != nil {
+		// The hold is already gone; only the notification is lost
+		slog.ErrorContext(ctx, "failed to publish inventory.reservation_expired event", "reservation_id", res.ID, "error", err)
+		return
+	}
+	slog.InfoContext(ctx, "reservation expired", "reservation_id", res.ID, "reference", res.Reference, "items", len(res.Items))
+}
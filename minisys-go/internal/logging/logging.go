@@ -0,0 +1,135 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/requestid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// level is shared by every logger so it can be changed while running
+var level = new(slog.LevelVar)
+
+// contextHandler adds request and trace IDs carried by the context to
+// every record logged with one of the *Context functions
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := requestid.FromContext(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// Init installs a JSON logger tagged with service and instance as the
+// slog default. Anything still written through the log package is routed
+// through it as well.
+func Init(service, instance, initialLevel string) {
+	InitWriter(os.Stdout, service, instance, initialLevel)
+}
+
+// InitWriter is Init logging to w, for tools whose stdout carries output
+func InitWriter(w io.Writer, service, instance, initialLevel string) {
+	if err := SetLevel(initialLevel); err != nil {
+		level.Set(slog.LevelInfo)
+	}
+
+	handler := slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level})
+	logger := slog.New(contextHandler{handler}).With(
+		slog.String("service", service),
+		slog.String("instance", instance),
+	)
+
+	slog.SetDefault(logger)
+}
+
+// SetLevel changes the level of every logger (debug, info, warn or error)
+func SetLevel(name string) error {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(strings.TrimSpace(name))); err != nil {
+		return fmt.Errorf("invalid log level %q", name)
+	}
+	level.Set(l)
+	return nil
+}
+
+// Fatal logs at error level and exits, replacing log.Fatalf
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// LevelHandler reports the current level on GET and changes it on PUT
+// with a body of {"level": "debug"}
+func LevelHandler(c *gin.Context) {
+	if c.Request.Method == http.MethodPut {
+		var req struct {
+			Level string `json:"level" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := SetLevel(req.Level); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		slog.InfoContext(c.Request.Context(), "log level changed", "level", level.Level().String())
+	}
+
+	c.JSON(http.StatusOK, gin.H{"level": strings.ToLower(level.Level().String())})
+}
+
+// Middleware replaces gin's access logger with one structured line per
+// request. Server errors log at error level and client errors at warn.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		lvl := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			lvl = slog.LevelError
+		case status >= http.StatusBadRequest:
+			lvl = slog.LevelWarn
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.String("route", c.FullPath()),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("client_ip", c.ClientIP()),
+			slog.Int("bytes", c.Writer.Size()),
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("errors", c.Errors.String()))
+		}
+
+		slog.LogAttrs(c.Request.Context(), lvl, "request handled", attrs...)
+	}
+}
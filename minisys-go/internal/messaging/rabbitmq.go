@@ -1,9 +1,12 @@
 package messaging
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
 
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/metrics"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/tracing"
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
@@ -26,7 +29,7 @@ func NewRabbitMQ(host string, port int, user, password string) (*RabbitMQ, error
 		return nil, fmt.Errorf("failed to open channel: %w", err)
 	}
 
-	log.Println("✅ Connected to RabbitMQ")
+	slog.Info("connected to RabbitMQ", "host", host, "port", port)
 
 	return &RabbitMQ{
 		conn:    conn,
@@ -48,27 +51,39 @@ func (r *RabbitMQ) DeclareQueue(name string) error {
 		return fmt.Errorf("failed to declare queue: %w", err)
 	}
 
-	log.Printf("✅ Queue declared: %s", name)
+	slog.Debug("queue declared", "queue", name)
 	return nil
 }
 
-// Publish sends a message to a queue
-func (r *RabbitMQ) Publish(queue string, message []byte) error {
-	err := r.channel.Publish(
+// Publish sends a message to a queue. The current trace context is added
+// to headers so consumers can continue the trace.
+func (r *RabbitMQ) Publish(ctx context.Context, queue string, message []byte, headers amqp.Table) error {
+	if headers == nil {
+		headers = amqp.Table{}
+	}
+
+	ctx, span := tracing.StartPublish(ctx, queue, headers)
+	defer span.End()
+
+	err := r.channel.PublishWithContext(
+		ctx,
 		"",    // exchange
 		queue, // routing key (queue name)
 		false, // mandatory
 		false, // immediate
 		amqp.Publishing{
 			ContentType: "application/json",
+			Headers:     headers,
 			Body:        message,
 		},
 	)
+	metrics.RecordPublished(queue, err)
 	if err != nil {
+		tracing.RecordError(span, err)
 		return fmt.Errorf("failed to publish message: %w", err)
 	}
 
-	log.Printf("📤 Message published to queue: %s", queue)
+	slog.DebugContext(ctx, "message published", "queue", queue)
 	return nil
 }
 
@@ -87,7 +102,7 @@ func (r *RabbitMQ) Consume(queue string) (<-chan amqp.Delivery, error) {
 		return nil, fmt.Errorf("failed to consume messages: %w", err)
 	}
 
-	log.Printf("👂 Listening on queue: %s", queue)
+	slog.Info("listening on queue", "queue", queue)
 	return messages, nil
 }
 
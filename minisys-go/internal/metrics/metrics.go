@@ -0,0 +1,168 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "minisys"
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests handled, by route and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency, by route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	upstreamRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_requests_total",
+		Help:      "Requests proxied by the gateway, by upstream and status code.",
+	}, []string{"upstream", "method", "status"})
+
+	upstreamDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upstream_request_duration_seconds",
+		Help:      "Latency of requests proxied by the gateway, by upstream.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"upstream", "method"})
+
+	cacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_lookups_total",
+		Help:      "Cache lookups, by key type and result (hit, miss, error).",
+	}, []string{"cache", "result"})
+
+	consumerMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "consumer_messages_total",
+		Help:      "Messages processed by queue consumers, by outcome.",
+	}, []string{"queue", "outcome"})
+
+	consumerDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "consumer_processing_duration_seconds",
+		Help:      "Time spent processing a message, by queue and outcome.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"queue", "outcome"})
+
+	publishedMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "published_messages_total",
+		Help:      "Messages published, by queue and outcome (success, failure).",
+	}, []string{"queue", "outcome"})
+
+	stockDrift = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "inventory_stock_drift",
+		Help:      "Warehouse stock levels that disagree with the inventory ledger at the last reconciliation.",
+	})
+)
+
+// Cache lookup results
+const (
+	CacheHit   = "hit"
+	CacheMiss  = "miss"
+	CacheError = "error"
+)
+
+// Consumer outcomes
+const (
+	OutcomeAcked    = "acked"
+	OutcomeRequeued = "requeued"
+	OutcomeRejected = "rejected"
+)
+
+// Handler serves every registered metric in the Prometheus text format
+func Handler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.Handler())
+}
+
+// Middleware records request count and latency per matched route.
+// Unmatched paths share one label so scanners cannot blow up cardinality.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		httpRequests.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		httpDuration.WithLabelValues(c.Request.Method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// RegisterDBStats exports connection pool statistics for db
+func RegisterDBStats(db *sql.DB, name string) {
+	prometheus.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// RecordCacheLookup counts a cache hit, miss or error for a key type
+func RecordCacheLookup(cache, result string) {
+	cacheLookups.WithLabelValues(cache, result).Inc()
+}
+
+// RecordConsumed records how a consumed message was handled
+func RecordConsumed(queue, outcome string, started time.Time) {
+	consumerMessages.WithLabelValues(queue, outcome).Inc()
+	consumerDuration.WithLabelValues(queue, outcome).Observe(time.Since(started).Seconds())
+}
+
+// RecordPublished counts a publish attempt
+func RecordPublished(queue string, err error) {
+	outcome := "success"
+	if err != nil {
+		outcome = "failure"
+	}
+	publishedMessages.WithLabelValues(queue, outcome).Inc()
+}
+
+// SetStockDrift records how many stock levels the last reconciliation
+// found out of step with the ledger
+func SetStockDrift(n int) {
+	stockDrift.Set(float64(n))
+}
+
+// upstreamTransport records RED metrics for each proxied request
+type upstreamTransport struct {
+	upstream string
+	next     http.RoundTripper
+}
+
+// InstrumentTransport wraps next so every request to upstream is measured
+func InstrumentTransport(upstream string, next http.RoundTripper) http.RoundTripper {
+	return &upstreamTransport{upstream: upstream, next: next}
+}
+
+func (t *upstreamTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+
+	upstreamRequests.WithLabelValues(t.upstream, req.Method, status).Inc()
+	upstreamDuration.WithLabelValues(t.upstream, req.Method).Observe(time.Since(start).Seconds())
+
+	return resp, err
+}
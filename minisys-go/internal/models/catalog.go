@@ -0,0 +1,51 @@
+package models
+
+import "encoding/json"
+
+// CatalogRow is one product in a bulk import or export, keyed by the SKU
+// of its default variant. On import an existing product is replaced as a
+// whole: an omitted category, tags or attributes are cleared, while an
+// omitted quantity leaves stock as it is.
+type CatalogRow struct {
+	SKU        string     `json:"sku"`
+	Name       string     `json:"name"`
+	Price      Money      `json:"price"`
+	Quantity   *int       `json:"quantity,omitempty"`
+	CategoryID *int       `json:"category_id,omitempty"`
+	Tags       []string   `json:"tags,omitempty"`
+	Attributes Attributes `json:"attributes,omitempty"`
+}
+
+// MarshalJSON adds the currency the row is priced in
+func (r CatalogRow) MarshalJSON() ([]byte, error) {
+	type plain CatalogRow
+	return withCurrency(plain(r), r.Price.Currency)
+}
+
+// UnmarshalJSON reads a bare price in the row's currency, if it names one
+func (r *CatalogRow) UnmarshalJSON(data []byte) error {
+	type plain CatalogRow
+	if err := json.Unmarshal(data, (*plain)(r)); err != nil {
+		return err
+	}
+	return readAmountsIn(data, &r.Price)
+}
+
+// ImportError is a row an import could not apply. Line is the row's line
+// in the uploaded file.
+type ImportError struct {
+	Line  int    `json:"line"`
+	SKU   string `json:"sku,omitempty"`
+	Error string `json:"error"`
+}
+
+// ImportResult summarizes a bulk import. In a dry run nothing is
+// committed, but the counts are what the import would have done.
+type ImportResult struct {
+	DryRun  bool          `json:"dry_run"`
+	Rows    int           `json:"rows"`
+	Created int           `json:"created"`
+	Updated int           `json:"updated"`
+	Failed  int           `json:"failed"`
+	Errors  []ImportError `json:"errors"`
+}
@@ -0,0 +1,135 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type Category struct {
+	ID        int        `json:"id"`
+	ParentID  *int       `json:"parent_id"`
+	Name      string     `json:"name"`
+	Slug      string     `json:"slug"`
+	CreatedAt time.Time  `json:"created_at"`
+	Children  []Category `json:"children,omitempty"`
+}
+
+type CreateCategoryRequest struct {
+	Name     string `json:"name" binding:"required"`
+	Slug     string `json:"slug"`
+	ParentID *int   `json:"parent_id"`
+}
+
+// UpdateCategoryRequest renames or moves a category. A parent_id of 0
+// moves it to the root.
+type UpdateCategoryRequest struct {
+	Name     *string `json:"name"`
+	Slug     *string `json:"slug"`
+	ParentID *int    `json:"parent_id"`
+}
+
+// BuildCategoryTree nests a flat category list under its roots
+func BuildCategoryTree(categories []Category) []Category {
+	children := make(map[int][]Category)
+	roots := []Category{}
+	for _, category := range categories {
+		if category.ParentID == nil {
+			roots = append(roots, category)
+		} else {
+			children[*category.ParentID] = append(children[*category.ParentID], category)
+		}
+	}
+
+	var attach func(nodes []Category) []Category
+	attach = func(nodes []Category) []Category {
+		for i := range nodes {
+			nodes[i].Children = attach(children[nodes[i].ID])
+		}
+		return nodes
+	}
+
+	return attach(roots)
+}
+
+const (
+	AttributeString  = "string"
+	AttributeNumber  = "number"
+	AttributeBoolean = "boolean"
+	AttributeEnum    = "enum"
+)
+
+// AttributeDefinition declares a typed product attribute such as color
+type AttributeDefinition struct {
+	Name          string   `json:"name"`
+	Type          string   `json:"type" binding:"required,oneof=string number boolean enum"`
+	AllowedValues []string `json:"allowed_values,omitempty"`
+}
+
+// Check reports whether value is acceptable for this attribute
+func (d AttributeDefinition) Check(value any) error {
+	switch d.Type {
+	case AttributeString:
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("attribute %q must be a string", d.Name)
+		}
+	case AttributeNumber:
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("attribute %q must be a number", d.Name)
+		}
+	case AttributeBoolean:
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("attribute %q must be true or false", d.Name)
+		}
+	case AttributeEnum:
+		s, ok := value.(string)
+		if ok {
+			for _, allowed := range d.AllowedValues {
+				if s == allowed {
+					return nil
+				}
+			}
+		}
+		return fmt.Errorf("attribute %q must be one of %v", d.Name, d.AllowedValues)
+	default:
+		return fmt.Errorf("attribute %q has unknown type %q", d.Name, d.Type)
+	}
+	return nil
+}
+
+// Attributes holds a product's typed attribute values, stored as JSONB
+type Attributes map[string]any
+
+func (a Attributes) Value() (driver.Value, error) {
+	if a == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (a *Attributes) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	case nil:
+		*a = Attributes{}
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into Attributes", src)
+	}
+
+	attrs := Attributes{}
+	if err := json.Unmarshal(data, &attrs); err != nil {
+		return err
+	}
+	*a = attrs
+	return nil
+}
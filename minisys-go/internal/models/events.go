@@ -1,16 +1,92 @@
 package models
 
+import (
+	"encoding/json"
+	"time"
+)
+
 // OrderCreatedEvent is published when a new order is created
 type OrderCreatedEvent struct {
 	OrderID      int              `json:"order_id"`
+	CustomerID   string           `json:"customer_id,omitempty"`
 	CustomerName string           `json:"customer_name"`
-	TotalAmount  float64          `json:"total_amount"`
+	TotalAmount  Money            `json:"total_amount"`
 	Items        []OrderItemEvent `json:"items"`
+	// ShipTo and AllocationStrategy steer which warehouses fulfil the order
+	ShipTo             *Location `json:"ship_to,omitempty"`
+	AllocationStrategy string    `json:"allocation_strategy,omitempty"`
+	// ReservationID names stock held for this order at checkout
+	ReservationID int `json:"reservation_id,omitempty"`
+}
+
+// MarshalJSON adds the currency of the order total
+func (e OrderCreatedEvent) MarshalJSON() ([]byte, error) {
+	type plain OrderCreatedEvent
+	return withCurrency(plain(e), e.TotalAmount.Currency)
+}
+
+func (e *OrderCreatedEvent) UnmarshalJSON(data []byte) error {
+	type plain OrderCreatedEvent
+	if err := json.Unmarshal(data, (*plain)(e)); err != nil {
+		return err
+	}
+	return readAmountsIn(data, &e.TotalAmount)
 }
 
+// OrderItemEvent names the SKU to take stock from. Events without a
+// variant ID fall back to the product's default variant.
 type OrderItemEvent struct {
-	ProductID int `json:"product_id"`
-	Quantity  int `json:"quantity"`
+	ProductID int    `json:"product_id"`
+	VariantID int    `json:"variant_id,omitempty"`
+	SKU       string `json:"sku,omitempty"`
+	Quantity  int    `json:"quantity"`
+}
+
+// OrderCancelledEvent is published when an order is cancelled so its
+// stock can go back on the shelf
+type OrderCancelledEvent struct {
+	OrderID     int       `json:"order_id"`
+	CancelledAt time.Time `json:"cancelled_at"`
+}
+
+// InventoryAllocatedEvent is published once stock for an order has been
+// taken from warehouses, so the order knows where each item ships from
+type InventoryAllocatedEvent struct {
+	OrderID     int          `json:"order_id"`
+	Strategy    string       `json:"strategy"`
+	Allocations []Allocation `json:"allocations"`
+	AllocatedAt time.Time    `json:"allocated_at"`
+}
+
+// OrderAllocationFailedEvent is published when an order's stock cannot be
+// allocated, so order-service can fail the order instead of leaving it
+// pending
+type OrderAllocationFailedEvent struct {
+	OrderID  int       `json:"order_id"`
+	Reason   string    `json:"reason"`
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// ReservationExpiredEvent is published when a reservation lapses without
+// being confirmed and its stock is available again
+type ReservationExpiredEvent struct {
+	ReservationID int               `json:"reservation_id"`
+	Reference     string            `json:"reference"`
+	Items         []ReservationItem `json:"items"`
+	ExpiredAt     time.Time         `json:"expired_at"`
+}
+
+// StockAlertEvent is published on inventory.low_stock or
+// inventory.out_of_stock when a product's stock crosses into that state
+type StockAlertEvent struct {
+	AlertID       int                 `json:"alert_id"`
+	ProductID     int                 `json:"product_id"`
+	ProductName   string              `json:"product_name"`
+	Kind          string              `json:"kind"`
+	Quantity      int                 `json:"quantity"`
+	Threshold     int                 `json:"threshold"`
+	PurchaseOrder *PurchaseOrderDraft `json:"purchase_order,omitempty"`
+	RaisedAt      time.Time           `json:"raised_at"`
 }
 
 // InventoryUpdateEvent is for updating product inventory
@@ -18,3 +94,60 @@ type InventoryUpdateEvent struct {
 	ProductID int `json:"product_id"`
 	Quantity  int `json:"quantity"` // negative = reduce, positive = add
 }
+
+// ProductUpdatedEvent is published whenever a product is changed through the API
+type ProductUpdatedEvent struct {
+	ProductID int       `json:"product_id"`
+	Name      string    `json:"name"`
+	Price     Money     `json:"price"`
+	Quantity  int       `json:"quantity"`
+	Version   int       `json:"version"`
+	Changed   []string  `json:"changed"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// MarshalJSON adds the currency the product is priced in
+func (e ProductUpdatedEvent) MarshalJSON() ([]byte, error) {
+	type plain ProductUpdatedEvent
+	return withCurrency(plain(e), e.Price.Currency)
+}
+
+// ProductDeletedEvent is published when a product is archived
+type ProductDeletedEvent struct {
+	ProductID int       `json:"product_id"`
+	Version   int       `json:"version"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// ProductRestoredEvent is published when an archived product is brought back
+type ProductRestoredEvent struct {
+	ProductID  int       `json:"product_id"`
+	Name       string    `json:"name"`
+	Price      Money     `json:"price"`
+	Quantity   int       `json:"quantity"`
+	Version    int       `json:"version"`
+	RestoredAt time.Time `json:"restored_at"`
+}
+
+// MarshalJSON adds the currency the product is priced in
+func (e ProductRestoredEvent) MarshalJSON() ([]byte, error) {
+	type plain ProductRestoredEvent
+	return withCurrency(plain(e), e.Price.Currency)
+}
+
+// ProductPriceChangedEvent is published once a price takes effect, whether
+// it was set directly or scheduled
+type ProductPriceChangedEvent struct {
+	ProductID     int        `json:"product_id"`
+	Price         Money      `json:"price"`
+	PreviousPrice *Money     `json:"previous_price,omitempty"`
+	EffectiveFrom time.Time  `json:"effective_from"`
+	EffectiveTo   *time.Time `json:"effective_to,omitempty"`
+	ChangedAt     time.Time  `json:"changed_at"`
+}
+
+// MarshalJSON adds the currency of both prices
+func (e ProductPriceChangedEvent) MarshalJSON() ([]byte, error) {
+	type plain ProductPriceChangedEvent
+	return withCurrency(plain(e), e.Price.Currency)
+}
@@ -0,0 +1,113 @@
+package models
+
+import "time"
+
+// Reasons a stock level changes
+const (
+	MovementInitial      = "initial" // opening balance when the ledger started
+	MovementOrder        = "order"
+	MovementCancellation = "cancellation"
+	MovementAdjustment   = "adjustment"
+	MovementRestock      = "restock"
+)
+
+// MovementSource says why stock changes and who changes it
+type MovementSource struct {
+	Reason      string
+	ReferenceID string
+	Actor       string
+}
+
+// InventoryMovement is one entry in the stock ledger
+type InventoryMovement struct {
+	ID             int       `json:"id"`
+	ProductID      int       `json:"product_id"`
+	VariantID      int       `json:"variant_id"`
+	SKU            string    `json:"sku"`
+	WarehouseID    int       `json:"warehouse_id"`
+	WarehouseCode  string    `json:"warehouse_code"`
+	QuantityChange int       `json:"quantity_change"`
+	QuantityAfter  int       `json:"quantity_after"`
+	Reason         string    `json:"reason"`
+	ReferenceID    string    `json:"reference_id,omitempty"`
+	Actor          string    `json:"actor"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// MovementFilter narrows a product's inventory history
+type MovementFilter struct {
+	VariantID   int
+	WarehouseID int
+	Reason      string
+}
+
+// AdjustStockRequest records a stock change by hand. Reason is restock or
+// adjustment; the default warehouse is used when none is given.
+type AdjustStockRequest struct {
+	VariantID      int    `json:"variant_id" binding:"required"`
+	WarehouseID    int    `json:"warehouse_id"`
+	QuantityChange int    `json:"quantity_change" binding:"required"`
+	Reason         string `json:"reason" binding:"required"`
+	ReferenceID    string `json:"reference_id"`
+}
+
+// StockDrift is a warehouse stock level that disagrees with its ledger
+type StockDrift struct {
+	WarehouseID   int    `json:"warehouse_id"`
+	WarehouseCode string `json:"warehouse_code"`
+	ProductID     int    `json:"product_id"`
+	VariantID     int    `json:"variant_id"`
+	SKU           string `json:"sku"`
+	Recorded      int    `json:"recorded"`
+	Ledger        int    `json:"ledger"`
+}
+
+// Kinds of stock alert
+const (
+	AlertLowStock   = "low_stock"
+	AlertOutOfStock = "out_of_stock"
+)
+
+// ReorderPolicy says when a product counts as low on stock and how much
+// to reorder then; a zero ReorderQuantity drafts no purchase order
+type ReorderPolicy struct {
+	ProductID       int       `json:"product_id"`
+	Threshold       int       `json:"threshold"`
+	ReorderQuantity int       `json:"reorder_quantity"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+type SetReorderPolicyRequest struct {
+	Threshold       *int `json:"threshold" binding:"required"`
+	ReorderQuantity int  `json:"reorder_quantity"`
+}
+
+// StockAlert is raised when a stock movement takes a product to its
+// reorder threshold or to zero, and resolved once stock is back above it
+type StockAlert struct {
+	ID            int                 `json:"id"`
+	ProductID     int                 `json:"product_id"`
+	ProductName   string              `json:"product_name"`
+	Kind          string              `json:"kind"`
+	Quantity      int                 `json:"quantity"`
+	Threshold     int                 `json:"threshold"`
+	PurchaseOrder *PurchaseOrderDraft `json:"purchase_order,omitempty"`
+	CreatedAt     time.Time           `json:"created_at"`
+	ResolvedAt    *time.Time          `json:"resolved_at,omitempty"`
+}
+
+// PurchaseOrderDraft is a suggested restock, left for a buyer to place
+type PurchaseOrderDraft struct {
+	ID        int       `json:"id"`
+	ProductID int       `json:"product_id"`
+	Quantity  int       `json:"quantity"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AlertFilter narrows the alert listing. Open selects unresolved alerts,
+// or resolved ones when false; nil selects both.
+type AlertFilter struct {
+	Open      *bool
+	Kind      string
+	ProductID int
+}
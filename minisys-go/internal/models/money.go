@@ -0,0 +1,347 @@
+package models
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is assumed when a client sends a bare amount
+const DefaultCurrency = "USD"
+
+// ErrMoneyOverflow is returned when arithmetic would not fit in an int64
+// of minor units
+var ErrMoneyOverflow = errors.New("amount is out of range")
+
+// zeroDecimalCurrencies have no minor unit (ISO 4217 exponent 0); every
+// other supported currency uses cents
+var zeroDecimalCurrencies = map[string]bool{
+	"BIF": true, "CLP": true, "DJF": true, "GNF": true, "ISK": true,
+	"JPY": true, "KMF": true, "KRW": true, "PYG": true, "RWF": true,
+	"UGX": true, "VND": true, "VUV": true, "XAF": true, "XOF": true,
+	"XPF": true,
+}
+
+// Money is an exact amount in the currency's minor units (cents for USD)
+//
+// It is encoded in JSON as a bare number such as 999.99, the shape prices
+// had before currencies existed; the types holding it add a "currency"
+// member alongside. On input {"amount":"999.99","currency":"USD"} and
+// strings are accepted too, and bare amounts are taken in the sibling
+// currency, or DefaultCurrency without one. In SQL the amount is stored as
+// a DECIMAL and the currency in its own column.
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// ParseMoney parses a decimal string such as "999.99" in currency
+func ParseMoney(amount, currency string) (Money, error) {
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	if !validCurrency(currency) {
+		return Money{}, fmt.Errorf("invalid currency %q", currency)
+	}
+
+	minor, err := parseMinorUnits(amount, exponent(currency))
+	if err != nil {
+		return Money{}, err
+	}
+
+	return Money{Amount: minor, Currency: currency}, nil
+}
+
+func validCurrency(currency string) bool {
+	if len(currency) != 3 {
+		return false
+	}
+	for _, r := range currency {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+func exponent(currency string) int {
+	if zeroDecimalCurrencies[currency] {
+		return 0
+	}
+	return 2
+}
+
+func parseMinorUnits(s string, exp int) (int64, error) {
+	s = strings.TrimSpace(s)
+
+	negative := strings.HasPrefix(s, "-")
+	digits := strings.TrimPrefix(s, "-")
+
+	whole, frac, _ := strings.Cut(digits, ".")
+	if whole == "" && frac == "" {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	if len(frac) > exp {
+		// Extra trailing zeros from DECIMAL columns are harmless
+		if strings.Trim(frac[exp:], "0") != "" {
+			return 0, fmt.Errorf("amount %q has more than %d decimal places", s, exp)
+		}
+		frac = frac[:exp]
+	}
+	frac += strings.Repeat("0", exp-len(frac))
+
+	for _, r := range whole + frac {
+		if r < '0' || r > '9' {
+			return 0, fmt.Errorf("invalid amount %q", s)
+		}
+	}
+
+	minor, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q: %w", s, err)
+	}
+	if negative {
+		minor = -minor
+	}
+
+	return minor, nil
+}
+
+func (m Money) currency() string {
+	if m.Currency == "" {
+		return DefaultCurrency
+	}
+	return m.Currency
+}
+
+// Decimal formats the amount without its currency, e.g. "999.99"
+func (m Money) Decimal() string {
+	exp := exponent(m.currency())
+
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	s := strconv.FormatInt(amount, 10)
+	if exp == 0 {
+		return sign + s
+	}
+	if len(s) <= exp {
+		s = strings.Repeat("0", exp-len(s)+1) + s
+	}
+
+	return sign + s[:len(s)-exp] + "." + s[len(s)-exp:]
+}
+
+func (m Money) String() string {
+	return m.Decimal() + " " + m.currency()
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// Add returns m + other; both must be in the same currency
+func (m Money) Add(other Money) (Money, error) {
+	if m.currency() != other.currency() {
+		return Money{}, fmt.Errorf("cannot add %s to %s", other.currency(), m.currency())
+	}
+
+	sum := m.Amount + other.Amount
+	if (other.Amount > 0 && sum < m.Amount) || (other.Amount < 0 && sum > m.Amount) {
+		return Money{}, ErrMoneyOverflow
+	}
+	return Money{Amount: sum, Currency: m.currency()}, nil
+}
+
+// Mul returns m multiplied by a quantity
+func (m Money) Mul(quantity int) (Money, error) {
+	q := int64(quantity)
+	product := m.Amount * q
+	if q != 0 && (product/q != m.Amount || (m.Amount == math.MinInt64 && q == -1)) {
+		return Money{}, ErrMoneyOverflow
+	}
+	return Money{Amount: product, Currency: m.currency()}, nil
+}
+
+type moneyJSON struct {
+	Amount   json.RawMessage `json:"amount"`
+	Currency string          `json:"currency"`
+}
+
+// MarshalJSON writes the amount as an exact JSON number
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.Decimal()), nil
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	currency := DefaultCurrency
+	amount := data
+	if len(data) > 0 && data[0] == '{' {
+		var obj moneyJSON
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+		if obj.Currency != "" {
+			currency = strings.ToUpper(obj.Currency)
+		}
+		amount = obj.Amount
+	}
+
+	text, err := amountText(amount)
+	if err != nil {
+		return err
+	}
+
+	parsed, err := ParseMoney(text, currency)
+	if err != nil {
+		return err
+	}
+
+	*m = parsed
+	return nil
+}
+
+// inCurrency re-reads an amount decoded without a currency of its own,
+// and so taken as DefaultCurrency, in the currency its holder names
+func (m *Money) inCurrency(currency string) error {
+	if currency == "" || m.currency() != DefaultCurrency {
+		return nil
+	}
+
+	parsed, err := ParseMoney(m.Decimal(), currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// withCurrency encodes v, a struct, with a "currency" member added next
+// to its amounts
+func withCurrency(v any, currency string) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+
+	member := `"currency":` + strconv.Quote(currency)
+	if bytes.Equal(data, []byte("{}")) {
+		return []byte("{" + member + "}"), nil
+	}
+	return append(data[:len(data)-1], []byte(","+member+"}")...), nil
+}
+
+// readCurrency returns the "currency" member of a JSON object, or "" if it
+// has none
+func readCurrency(data []byte) (string, error) {
+	var obj struct {
+		Currency string `json:"currency"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return "", err
+	}
+
+	currency := strings.ToUpper(obj.Currency)
+	if currency != "" && !validCurrency(currency) {
+		return "", fmt.Errorf("invalid currency %q", obj.Currency)
+	}
+	return currency, nil
+}
+
+// readAmountsIn re-reads amounts, skipping nil ones, in the currency named
+// by the JSON object data they were decoded from
+func readAmountsIn(data []byte, amounts ...*Money) error {
+	currency, err := readCurrency(data)
+	if err != nil {
+		return err
+	}
+	for _, m := range amounts {
+		if m == nil {
+			continue
+		}
+		if err := m.inCurrency(currency); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// amountText accepts a JSON string or number without going through float64
+func amountText(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 {
+		return "", fmt.Errorf("money amount is missing")
+	}
+	if raw[0] == '"' {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return "", err
+		}
+		return s, nil
+	}
+
+	var n json.Number
+	if err := json.Unmarshal(raw, &n); err != nil {
+		return "", fmt.Errorf("invalid money amount %s", raw)
+	}
+	return n.String(), nil
+}
+
+// Value stores the amount as a decimal string for a DECIMAL column
+func (m Money) Value() (driver.Value, error) {
+	return m.Decimal(), nil
+}
+
+// Scan reads a DECIMAL column. The currency decides how many minor units
+// the amount has, so scan the currency column before the amount.
+func (m *Money) Scan(src any) error {
+	var text string
+	switch v := src.(type) {
+	case []byte:
+		text = string(v)
+	case string:
+		text = v
+	case int64:
+		text = strconv.FormatInt(v, 10)
+	case float64:
+		text = strconv.FormatFloat(v, 'f', -1, 64)
+	case nil:
+		m.Amount = 0
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into Money", src)
+	}
+
+	minor, err := parseMinorUnits(text, exponent(m.currency()))
+	if err != nil {
+		return err
+	}
+
+	m.Amount = minor
+	m.Currency = m.currency()
+	return nil
+}
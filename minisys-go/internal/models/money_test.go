@@ -0,0 +1,395 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParseMinorUnits(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		exp     int
+		want    int64
+		wantErr bool
+	}{
+		{name: "whole", in: "12", exp: 2, want: 1200},
+		{name: "cents", in: "999.99", exp: 2, want: 99999},
+		{name: "one decimal", in: "0.5", exp: 2, want: 50},
+		{name: "leading dot", in: ".05", exp: 2, want: 5},
+		{name: "trailing dot", in: "3.", exp: 2, want: 300},
+		{name: "decimal column zeros", in: "10.5000", exp: 2, want: 1050},
+		{name: "surrounding space", in: " 1.25 ", exp: 2, want: 125},
+		{name: "negative", in: "-1.25", exp: 2, want: -125},
+		{name: "negative fraction", in: "-0.01", exp: 2, want: -1},
+		{name: "zero decimal currency", in: "1000", exp: 0, want: 1000},
+		{name: "zero decimal with zeros", in: "1000.00", exp: 0, want: 1000},
+		{name: "too precise", in: "1.005", exp: 2, wantErr: true},
+		{name: "fraction of yen", in: "1000.5", exp: 0, wantErr: true},
+		{name: "empty", in: "", exp: 2, wantErr: true},
+		{name: "sign only", in: "-", exp: 2, wantErr: true},
+		{name: "dot only", in: ".", exp: 2, wantErr: true},
+		{name: "double sign", in: "--1", exp: 2, wantErr: true},
+		{name: "plus sign", in: "+1", exp: 2, wantErr: true},
+		{name: "two dots", in: "1.2.3", exp: 2, wantErr: true},
+		{name: "exponent", in: "1e3", exp: 2, wantErr: true},
+		{name: "letters", in: "abc", exp: 2, wantErr: true},
+		{name: "max", in: "92233720368547758.07", exp: 2, want: math.MaxInt64},
+		{name: "out of range", in: "92233720368547758.08", exp: 2, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseMinorUnits(tt.in, tt.exp)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseMinorUnits(%q, %d) error = %v, wantErr %v", tt.in, tt.exp, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("parseMinorUnits(%q, %d) = %d, want %d", tt.in, tt.exp, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    Money
+		wantErr bool
+	}{
+		{name: "bare number", in: `999.99`, want: Money{99999, "USD"}},
+		{name: "bare string", in: `"999.99"`, want: Money{99999, "USD"}},
+		{name: "number keeps precision", in: `0.1`, want: Money{10, "USD"}},
+		{name: "large number exact", in: `12345678901234.56`, want: Money{1234567890123456, "USD"}},
+		{name: "negative", in: `-5.25`, want: Money{-525, "USD"}},
+		{name: "object", in: `{"amount":"10.50","currency":"EUR"}`, want: Money{1050, "EUR"}},
+		{name: "object numeric amount", in: `{"amount":10.5,"currency":"EUR"}`, want: Money{1050, "EUR"}},
+		{name: "object lower-case currency", in: `{"amount":"1","currency":"gbp"}`, want: Money{100, "GBP"}},
+		{name: "object without currency", in: `{"amount":"1"}`, want: Money{100, "USD"}},
+		{name: "zero decimal currency", in: `{"amount":"1000","currency":"JPY"}`, want: Money{1000, "JPY"}},
+		{name: "null leaves zero", in: `null`, want: Money{}},
+		{name: "too precise", in: `1.001`, wantErr: true},
+		{name: "yen with cents", in: `{"amount":"1.50","currency":"JPY"}`, wantErr: true},
+		{name: "bad currency", in: `{"amount":"1","currency":"US"}`, wantErr: true},
+		{name: "currency with digits", in: `{"amount":"1","currency":"U5D"}`, wantErr: true},
+		{name: "missing amount", in: `{"currency":"USD"}`, wantErr: true},
+		{name: "boolean", in: `true`, wantErr: true},
+		{name: "exponent number", in: `1e2`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Money
+			err := json.Unmarshal([]byte(tt.in), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal(%s) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("Unmarshal(%s) = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyMarshalJSON(t *testing.T) {
+	tests := []struct {
+		in   Money
+		want string
+	}{
+		{Money{99999, "USD"}, `999.99`},
+		{Money{5, "USD"}, `0.05`},
+		{Money{-525, "EUR"}, `-5.25`},
+		{Money{1000, "JPY"}, `1000`},
+		{Money{0, ""}, `0.00`},
+	}
+
+	for _, tt := range tests {
+		got, err := json.Marshal(tt.in)
+		if err != nil {
+			t.Fatalf("Marshal(%+v) error = %v", tt.in, err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("Marshal(%+v) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	tests := []struct {
+		name    string
+		op      func() (Money, error)
+		want    Money
+		wantErr error
+	}{
+		{name: "add", op: func() (Money, error) { return Money{150, "USD"}.Add(Money{250, "USD"}) }, want: Money{400, "USD"}},
+		{name: "add defaults currency", op: func() (Money, error) { return Money{1, ""}.Add(Money{2, "USD"}) }, want: Money{3, "USD"}},
+		{name: "add negative", op: func() (Money, error) { return Money{100, "USD"}.Add(Money{-250, "USD"}) }, want: Money{-150, "USD"}},
+		{name: "add overflow", op: func() (Money, error) { return Money{math.MaxInt64, "USD"}.Add(Money{1, "USD"}) }, wantErr: ErrMoneyOverflow},
+		{name: "add underflow", op: func() (Money, error) { return Money{math.MinInt64, "USD"}.Add(Money{-1, "USD"}) }, wantErr: ErrMoneyOverflow},
+		{name: "mul", op: func() (Money, error) { return Money{1999, "EUR"}.Mul(3) }, want: Money{5997, "EUR"}},
+		{name: "mul zero", op: func() (Money, error) { return Money{1999, "EUR"}.Mul(0) }, want: Money{0, "EUR"}},
+		{name: "mul overflow", op: func() (Money, error) { return Money{math.MaxInt64 / 2, "USD"}.Mul(3) }, wantErr: ErrMoneyOverflow},
+		{name: "mul min by minus one", op: func() (Money, error) { return Money{math.MinInt64, "USD"}.Mul(-1) }, wantErr: ErrMoneyOverflow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.op()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := (Money{1, "USD"}).Add(Money{1, "EUR"}); err == nil {
+		t.Error("adding EUR to USD succeeded")
+	}
+}
+
+func TestProductJSONCurrency(t *testing.T) {
+	tests := []struct {
+		name  string
+		price Money
+		json  string
+	}{
+		{name: "dollars", price: Money{99999, "USD"}, json: `"price":999.99`},
+		{name: "yen", price: Money{1000, "JPY"}, json: `"price":1000`},
+		{name: "euros", price: Money{1050, "EUR"}, json: `"price":10.50`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(Product{ID: 1, Name: "widget", Price: tt.price})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var shape map[string]json.RawMessage
+			if err := json.Unmarshal(data, &shape); err != nil {
+				t.Fatal(err)
+			}
+			if got := `"price":` + string(shape["price"]); got != tt.json {
+				t.Errorf("encoded %s, want %s", got, tt.json)
+			}
+			if got := string(shape["currency"]); got != `"`+tt.price.Currency+`"` {
+				t.Errorf("currency = %s, want %q", got, tt.price.Currency)
+			}
+
+			var back Product
+			if err := json.Unmarshal(data, &back); err != nil {
+				t.Fatal(err)
+			}
+			if back.Price != tt.price {
+				t.Errorf("round trip price = %+v, want %+v", back.Price, tt.price)
+			}
+		})
+	}
+}
+
+func TestVariantJSONCurrency(t *testing.T) {
+	override := Money{1500, "JPY"}
+	in := Variant{ID: 7, Price: override, PriceOverride: &override}
+
+	data, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back Variant
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back.Price != in.Price || back.PriceOverride == nil || *back.PriceOverride != override {
+		t.Errorf("round trip = %+v (override %+v), want %+v", back.Price, back.PriceOverride, override)
+	}
+}
+
+func TestOrderJSONCurrency(t *testing.T) {
+	in := Order{
+		ID:          3,
+		TotalAmount: Money{3000, "JPY"},
+		Items:       []OrderItem{{ProductID: 1, Quantity: 3, Price: Money{1000, "JPY"}}},
+	}
+
+	data, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back Order
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back.TotalAmount != in.TotalAmount || back.Items[0].Price != in.Items[0].Price {
+		t.Errorf("round trip = %+v / %+v, want %+v / %+v", back.TotalAmount, back.Items[0].Price, in.TotalAmount, in.Items[0].Price)
+	}
+}
+
+func TestRequestJSONCurrency(t *testing.T) {
+	decode := func(in string, v any) error { return json.Unmarshal([]byte(in), v) }
+
+	tests := []struct {
+		name    string
+		in      string
+		price   func(in string) (*Money, error)
+		want    *Money
+		wantErr bool
+	}{
+		{
+			name: "create product in euros",
+			in:   `{"name":"widget","price":10.5,"currency":"eur"}`,
+			price: func(in string) (*Money, error) {
+				var r CreateProductRequest
+				return &r.Price, decode(in, &r)
+			},
+			want: &Money{1050, "EUR"},
+		},
+		{
+			name: "create product without currency",
+			in:   `{"name":"widget","price":10.5}`,
+			price: func(in string) (*Money, error) {
+				var r CreateProductRequest
+				return &r.Price, decode(in, &r)
+			},
+			want: &Money{1050, "USD"},
+		},
+		{
+			name: "create product with cents in yen",
+			in:   `{"name":"widget","price":10.5,"currency":"JPY"}`,
+			price: func(in string) (*Money, error) {
+				var r CreateProductRequest
+				return &r.Price, decode(in, &r)
+			},
+			wantErr: true,
+		},
+		{
+			name: "create product with unknown currency",
+			in:   `{"name":"widget","price":10,"currency":"euro"}`,
+			price: func(in string) (*Money, error) {
+				var r CreateProductRequest
+				return &r.Price, decode(in, &r)
+			},
+			wantErr: true,
+		},
+		{
+			name: "object price keeps its own currency",
+			in:   `{"name":"widget","price":{"amount":"10.50","currency":"GBP"},"currency":"EUR"}`,
+			price: func(in string) (*Money, error) {
+				var r CreateProductRequest
+				return &r.Price, decode(in, &r)
+			},
+			want: &Money{1050, "GBP"},
+		},
+		{
+			name: "update product in yen",
+			in:   `{"name":"widget","price":1000,"quantity":1,"currency":"JPY"}`,
+			price: func(in string) (*Money, error) {
+				var r UpdateProductRequest
+				return &r.Price, decode(in, &r)
+			},
+			want: &Money{1000, "JPY"},
+		},
+		{
+			name: "patch product price in pounds",
+			in:   `{"price":"4.99","currency":"GBP"}`,
+			price: func(in string) (*Money, error) {
+				var r PatchProductRequest
+				err := decode(in, &r)
+				return r.Price, err
+			},
+			want: &Money{499, "GBP"},
+		},
+		{
+			name: "patch product without price",
+			in:   `{"name":"gadget","currency":"GBP"}`,
+			price: func(in string) (*Money, error) {
+				var r PatchProductRequest
+				err := decode(in, &r)
+				return r.Price, err
+			},
+		},
+		{
+			name: "order item expected price in yen",
+			in:   `{"sku":"W-1","quantity":2,"expected_price":1000,"currency":"JPY"}`,
+			price: func(in string) (*Money, error) {
+				var r CreateOrderItemRequest
+				err := decode(in, &r)
+				return r.ExpectedPrice, err
+			},
+			want: &Money{1000, "JPY"},
+		},
+		{
+			name: "scheduled price in euros",
+			in:   `{"price":8,"currency":"EUR","effective_from":"2026-11-01T00:00:00Z"}`,
+			price: func(in string) (*Money, error) {
+				var r SchedulePriceRequest
+				return &r.Price, decode(in, &r)
+			},
+			want: &Money{800, "EUR"},
+		},
+		{
+			name: "variant price override in yen",
+			in:   `{"sku":"W-1-L","price_override":1500,"currency":"JPY"}`,
+			price: func(in string) (*Money, error) {
+				var r CreateVariantRequest
+				err := decode(in, &r)
+				return r.PriceOverride, err
+			},
+			want: &Money{1500, "JPY"},
+		},
+		{
+			name: "variant patch override in euros",
+			in:   `{"price_override":12.25,"currency":"EUR"}`,
+			price: func(in string) (*Money, error) {
+				var r PatchVariantRequest
+				err := decode(in, &r)
+				return r.PriceOverride, err
+			},
+			want: &Money{1225, "EUR"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.price(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal(%s) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if tt.want == nil {
+				if got != nil {
+					t.Errorf("price = %+v, want none", *got)
+				}
+				return
+			}
+			if got == nil || *got != *tt.want {
+				t.Errorf("price = %v, want %+v", got, *tt.want)
+			}
+		})
+	}
+}
+
+// A product read from the API can be sent back unchanged as a PUT body
+func TestProductRoundTripsAsUpdate(t *testing.T) {
+	quantity := 3
+	for _, price := range []Money{{1000, "JPY"}, {1050, "EUR"}, {99999, "USD"}} {
+		data, err := json.Marshal(Product{ID: 1, Name: "widget", Price: price, Quantity: quantity})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var update UpdateProductRequest
+		if err := json.Unmarshal(data, &update); err != nil {
+			t.Fatal(err)
+		}
+		if update.Price != price {
+			t.Errorf("PUT of %s read price %+v, want %+v", data, update.Price, price)
+		}
+	}
+}
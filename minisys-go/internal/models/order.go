@@ -1,31 +1,89 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type Order struct {
-	ID           int         `json:"id"`
-	CustomerName string      `json:"customer_name"`
-	TotalAmount  float64     `json:"total_amount"`
-	Status       string      `json:"status"`
-	Items        []OrderItem `json:"items"`
-	CreatedAt    time.Time   `json:"created_at"`
+	ID           int       `json:"id"`
+	CustomerID   string    `json:"customer_id,omitempty"`
+	CustomerName string    `json:"customer_name"`
+	TotalAmount  Money     `json:"total_amount"`
+	Status       string    `json:"status"`
+	ShipTo       *Location `json:"ship_to,omitempty"`
+	// AllocationStrategy is how warehouses are picked; empty means the
+	// product-service default
+	AllocationStrategy string `json:"allocation_strategy,omitempty"`
+	// ReservationID is the checkout reservation the order draws on, if any
+	ReservationID int          `json:"reservation_id,omitempty"`
+	Items         []OrderItem  `json:"items"`
+	Allocations   []Allocation `json:"allocations,omitempty"`
+	CreatedAt     time.Time    `json:"created_at"`
+}
+
+// MarshalJSON adds the currency of the order's total and items
+func (o Order) MarshalJSON() ([]byte, error) {
+	type plain Order
+	return withCurrency(plain(o), o.TotalAmount.Currency)
+}
+
+func (o *Order) UnmarshalJSON(data []byte) error {
+	type plain Order
+	if err := json.Unmarshal(data, (*plain)(o)); err != nil {
+		return err
+	}
+	amounts := []*Money{&o.TotalAmount}
+	for i := range o.Items {
+		amounts = append(amounts, &o.Items[i].Price)
+	}
+	return readAmountsIn(data, amounts...)
 }
 
 type OrderItem struct {
-	ID          int     `json:"id"`
-	OrderID     int     `json:"order_id"`
-	ProductID   int     `json:"product_id"`
-	ProductName string  `json:"product_name"`
-	Quantity    int     `json:"quantity"`
-	Price       float64 `json:"price"`
+	ID          int    `json:"id"`
+	OrderID     int    `json:"order_id"`
+	ProductID   int    `json:"product_id"`
+	VariantID   int    `json:"variant_id,omitempty"`
+	SKU         string `json:"sku,omitempty"`
+	ProductName string `json:"product_name"`
+	Quantity    int    `json:"quantity"`
+	Price       Money  `json:"price"`
 }
 
 type CreateOrderRequest struct {
+	CustomerID   string                   `json:"customer_id"`
 	CustomerName string                   `json:"customer_name" binding:"required"`
+	ShipTo       *Location                `json:"ship_to"`
 	Items        []CreateOrderItemRequest `json:"items" binding:"required"`
+	// AllocationStrategy is nearest, most_stock or split
+	AllocationStrategy string `json:"allocation_strategy"`
+	// ReservationID names stock the customer held at checkout. Someone
+	// else's reservation is ignored and the order competes for stock.
+	ReservationID int `json:"reservation_id"`
+	// PricedAt is when the items' expected prices were shown to the
+	// customer; see CreateOrderItemRequest.ExpectedPrice
+	PricedAt *time.Time `json:"priced_at,omitempty"`
 }
 
+// CreateOrderItemRequest names either a SKU or, for clients that predate
+// variants, a product ID meaning that product's default variant
 type CreateOrderItemRequest struct {
-	ProductID int `json:"product_id" binding:"required"`
-	Quantity  int `json:"quantity" binding:"required"`
+	ProductID int    `json:"product_id"`
+	SKU       string `json:"sku"`
+	Quantity  int    `json:"quantity" binding:"required"`
+	// ExpectedPrice is the unit price the customer was shown. If the price
+	// has changed since, the order is rejected, unless the shown price was
+	// in effect at the order's PricedAt, recently enough to be honored.
+	ExpectedPrice *Money `json:"expected_price,omitempty"`
+}
+
+// UnmarshalJSON reads a bare expected price in the item's currency, if it
+// names one
+func (r *CreateOrderItemRequest) UnmarshalJSON(data []byte) error {
+	type plain CreateOrderItemRequest
+	if err := json.Unmarshal(data, (*plain)(r)); err != nil {
+		return err
+	}
+	return readAmountsIn(data, r.ExpectedPrice)
 }
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// PageRequest selects one page of a keyset-paginated listing. Sort is a
+// whitelisted field name, prefixed with "-" for descending order.
+type PageRequest struct {
+	Limit  int
+	Cursor string
+	Sort   string
+}
+
+// PageInfo is returned alongside every page
+type PageInfo struct {
+	Limit      int    `json:"limit"`
+	Sort       string `json:"sort"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Next       string `json:"next,omitempty"`
+}
+
+// Page is the response envelope for paginated listings
+type Page[T any] struct {
+	Data       []T      `json:"data"`
+	Pagination PageInfo `json:"pagination"`
+}
+
+// ProductFilter narrows a product listing; zero values are ignored
+type ProductFilter struct {
+	MinPrice      *Money
+	MaxPrice      *Money
+	InStock       bool
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+
+	// CategoryID also matches products in every descendant category
+	CategoryID *int
+	// Tags must all be present on a product
+	Tags []string
+	// Attributes are compared by their text form, e.g. {"size": "42"}
+	Attributes map[string]string
+}
+
+// OrderFilter narrows an order listing; zero values are ignored.
+// CustomerID is a pointer so that an empty ID still filters.
+type OrderFilter struct {
+	Status        string
+	CustomerID    *string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ProductPrice is one range of a product's price history. EffectiveTo is
+// nil while the price holds until further notice; ActivatedAt is nil while
+// the price is still scheduled.
+type ProductPrice struct {
+	ID            int        `json:"id"`
+	ProductID     int        `json:"product_id"`
+	Price         Money      `json:"price"`
+	EffectiveFrom time.Time  `json:"effective_from"`
+	EffectiveTo   *time.Time `json:"effective_to,omitempty"`
+	Actor         string     `json:"actor"`
+	CreatedAt     time.Time  `json:"created_at"`
+	ActivatedAt   *time.Time `json:"activated_at,omitempty"`
+}
+
+// MarshalJSON adds the currency of the price
+func (p ProductPrice) MarshalJSON() ([]byte, error) {
+	type plain ProductPrice
+	return withCurrency(plain(p), p.Price.Currency)
+}
+
+// SchedulePriceRequest sets a product's price from EffectiveFrom (now when
+// omitted) until EffectiveTo, after which the price it interrupted
+// resumes. Without EffectiveTo the price holds until the next scheduled
+// change.
+type SchedulePriceRequest struct {
+	Price         Money      `json:"price"`
+	EffectiveFrom *time.Time `json:"effective_from"`
+	EffectiveTo   *time.Time `json:"effective_to"`
+}
+
+// UnmarshalJSON reads a bare price in the request's currency, if it names one
+func (r *SchedulePriceRequest) UnmarshalJSON(data []byte) error {
+	type plain SchedulePriceRequest
+	if err := json.Unmarshal(data, (*plain)(r)); err != nil {
+		return err
+	}
+	return readAmountsIn(data, &r.Price)
+}
@@ -1,17 +1,108 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type Product struct {
-	ID        int       `json:"id"`
-	Name      string    `json:"name"`
-	Price     float64   `json:"price"`
-	Quantity  int       `json:"quantity"`
-	CreatedAt time.Time `json:"created_at"`
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Price      Money      `json:"price"`
+	Quantity   int        `json:"quantity"`
+	Available  int        `json:"available"` // quantity less active reservations
+	CategoryID *int       `json:"category_id"`
+	Tags       []string   `json:"tags"`
+	Attributes Attributes `json:"attributes"`
+	Version    int        `json:"version"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"` // set while the product is archived
+}
+
+// MarshalJSON adds the currency the product is priced in
+func (p Product) MarshalJSON() ([]byte, error) {
+	type plain Product
+	return withCurrency(plain(p), p.Price.Currency)
+}
+
+func (p *Product) UnmarshalJSON(data []byte) error {
+	type plain Product
+	if err := json.Unmarshal(data, (*plain)(p)); err != nil {
+		return err
+	}
+	return readAmountsIn(data, &p.Price)
 }
 
 type CreateProductRequest struct {
-	Name     string  `json:"name" binding:"required"`
-	Price    float64 `json:"price" binding:"required"`
-	Quantity int     `json:"Quantity"`
+	Name       string     `json:"name" binding:"required"`
+	Price      Money      `json:"price"`
+	Quantity   int        `json:"Quantity"`
+	CategoryID *int       `json:"category_id"`
+	Tags       []string   `json:"tags"`
+	Attributes Attributes `json:"attributes"`
+	SKU        string     `json:"sku"` // of the default variant; generated when empty
+}
+
+// UnmarshalJSON reads a bare price in the request's currency, if it names one
+func (r *CreateProductRequest) UnmarshalJSON(data []byte) error {
+	type plain CreateProductRequest
+	if err := json.Unmarshal(data, (*plain)(r)); err != nil {
+		return err
+	}
+	return readAmountsIn(data, &r.Price)
+}
+
+// UpdateProductRequest replaces every editable field (PUT); omitted
+// category, tags and attributes are cleared
+type UpdateProductRequest struct {
+	Name       string     `json:"name" binding:"required"`
+	Price      Money      `json:"price"`
+	Quantity   *int       `json:"quantity" binding:"required"`
+	CategoryID *int       `json:"category_id"`
+	Tags       []string   `json:"tags"`
+	Attributes Attributes `json:"attributes"`
+}
+
+// UnmarshalJSON reads a bare price in the request's currency, if it names one
+func (r *UpdateProductRequest) UnmarshalJSON(data []byte) error {
+	type plain UpdateProductRequest
+	if err := json.Unmarshal(data, (*plain)(r)); err != nil {
+		return err
+	}
+	return readAmountsIn(data, &r.Price)
+}
+
+// PatchProductRequest changes only the fields that are present (PATCH).
+// A category_id of 0 removes the product from its category; tags and
+// attributes are replaced as a whole.
+type PatchProductRequest struct {
+	Name       *string     `json:"name"`
+	Price      *Money      `json:"price"`
+	Quantity   *int        `json:"quantity"`
+	CategoryID *int        `json:"category_id"`
+	Tags       *[]string   `json:"tags"`
+	Attributes *Attributes `json:"attributes"`
+}
+
+// UnmarshalJSON reads a bare price in the request's currency, if it names one
+func (p *PatchProductRequest) UnmarshalJSON(data []byte) error {
+	type plain PatchProductRequest
+	if err := json.Unmarshal(data, (*plain)(p)); err != nil {
+		return err
+	}
+	return readAmountsIn(data, p.Price)
+}
+
+// Empty reports whether the patch changes nothing
+func (p PatchProductRequest) Empty() bool {
+	return p.Name == nil && p.Price == nil && p.Quantity == nil &&
+		p.CategoryID == nil && p.Tags == nil && p.Attributes == nil
+}
+
+// ProductSearchResult is one ranked match from a product search
+type ProductSearchResult struct {
+	Product   Product `json:"product"`
+	Rank      float64 `json:"rank"`
+	Highlight string  `json:"highlight"` // HTML-escaped name, matches in <mark> tags
 }
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// Reservation statuses. Only active reservations hold stock.
+const (
+	ReservationActive    = "active"
+	ReservationConfirmed = "confirmed"
+	ReservationReleased  = "released"
+	ReservationExpired   = "expired"
+)
+
+// Reservation holds stock for a checkout until it is confirmed as an
+// order, released, or expires
+type Reservation struct {
+	ID        int               `json:"id"`
+	Reference string            `json:"reference"`
+	Status    string            `json:"status"`
+	OrderID   int               `json:"order_id,omitempty"`
+	Actor     string            `json:"actor"`
+	Items     []ReservationItem `json:"items"`
+	ExpiresAt time.Time         `json:"expires_at"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+type ReservationItem struct {
+	ProductID int    `json:"product_id"`
+	VariantID int    `json:"variant_id"`
+	SKU       string `json:"sku"`
+	Quantity  int    `json:"quantity"`
+}
+
+// CreateReservationRequest holds stock for the items for TTLSeconds, or
+// the service default when zero
+type CreateReservationRequest struct {
+	Reference  string                   `json:"reference" binding:"required"`
+	Items      []CreateOrderItemRequest `json:"items" binding:"required"`
+	TTLSeconds int                      `json:"ttl_seconds"`
+}
+
+// ConfirmReservationRequest names the order a reservation is confirmed
+// for. The items, ship-to and strategy are the order's own.
+type ConfirmReservationRequest struct {
+	OrderID int `json:"order_id" binding:"required"`
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Variant is one sellable SKU of a product, e.g. size M in red. Price is
+// the effective price: the variant's override, or else the product price.
+type Variant struct {
+	ID            int        `json:"id"`
+	ProductID     int        `json:"product_id"`
+	ProductName   string     `json:"product_name"`
+	SKU           string     `json:"sku"`
+	Options       Attributes `json:"options"`
+	Price         Money      `json:"price"`
+	PriceOverride *Money     `json:"price_override"`
+	Quantity      int        `json:"quantity"`
+	Available     int        `json:"available"` // quantity less active reservations
+	IsDefault     bool       `json:"is_default"`
+	Version       int        `json:"version"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// MarshalJSON adds the currency the variant is priced in
+func (v Variant) MarshalJSON() ([]byte, error) {
+	type plain Variant
+	return withCurrency(plain(v), v.Price.Currency)
+}
+
+func (v *Variant) UnmarshalJSON(data []byte) error {
+	type plain Variant
+	if err := json.Unmarshal(data, (*plain)(v)); err != nil {
+		return err
+	}
+	return readAmountsIn(data, &v.Price, v.PriceOverride)
+}
+
+type CreateVariantRequest struct {
+	SKU           string     `json:"sku" binding:"required"`
+	Options       Attributes `json:"options"`
+	PriceOverride *Money     `json:"price_override"`
+	Quantity      int        `json:"quantity"`
+}
+
+// UnmarshalJSON reads a bare price override in the request's currency, if
+// it names one
+func (r *CreateVariantRequest) UnmarshalJSON(data []byte) error {
+	type plain CreateVariantRequest
+	if err := json.Unmarshal(data, (*plain)(r)); err != nil {
+		return err
+	}
+	return readAmountsIn(data, r.PriceOverride)
+}
+
+// PatchVariantRequest changes only the fields that are present. Setting
+// clear_price_override drops the override so the product price applies.
+type PatchVariantRequest struct {
+	SKU                *string     `json:"sku"`
+	Options            *Attributes `json:"options"`
+	PriceOverride      *Money      `json:"price_override"`
+	ClearPriceOverride bool        `json:"clear_price_override"`
+	Quantity           *int        `json:"quantity"`
+}
+
+// UnmarshalJSON reads a bare price override in the request's currency, if
+// it names one
+func (r *PatchVariantRequest) UnmarshalJSON(data []byte) error {
+	type plain PatchVariantRequest
+	if err := json.Unmarshal(data, (*plain)(r)); err != nil {
+		return err
+	}
+	return readAmountsIn(data, r.PriceOverride)
+}
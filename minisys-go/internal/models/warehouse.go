@@ -0,0 +1,73 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Location is a point on the map in decimal degrees
+type Location struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Validate checks the coordinates are within range
+func (l Location) Validate() error {
+	if l.Latitude < -90 || l.Latitude > 90 || l.Longitude < -180 || l.Longitude > 180 {
+		return fmt.Errorf("location must have latitude in [-90, 90] and longitude in [-180, 180]")
+	}
+	return nil
+}
+
+// Warehouse holds stock. The default warehouse receives stock changes that
+// do not name a warehouse; inactive warehouses are skipped by allocation.
+type Warehouse struct {
+	ID        int       `json:"id"`
+	Code      string    `json:"code"`
+	Name      string    `json:"name"`
+	Location  *Location `json:"location"`
+	Priority  int       `json:"priority"`
+	IsDefault bool      `json:"is_default"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateWarehouseRequest struct {
+	Code     string    `json:"code" binding:"required"`
+	Name     string    `json:"name" binding:"required"`
+	Location *Location `json:"location"`
+	Priority int       `json:"priority"`
+}
+
+// UpdateWarehouseRequest changes only the fields that are present
+type UpdateWarehouseRequest struct {
+	Name     *string   `json:"name"`
+	Location *Location `json:"location"`
+	Priority *int      `json:"priority"`
+	Active   *bool     `json:"active"`
+}
+
+// WarehouseStock is the stock of one variant in one warehouse
+type WarehouseStock struct {
+	WarehouseID   int       `json:"warehouse_id"`
+	WarehouseCode string    `json:"warehouse_code"`
+	ProductID     int       `json:"product_id"`
+	VariantID     int       `json:"variant_id"`
+	SKU           string    `json:"sku"`
+	Quantity      int       `json:"quantity"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+type SetStockRequest struct {
+	Quantity *int `json:"quantity" binding:"required"`
+}
+
+// Allocation records that a warehouse ships part of an order item
+type Allocation struct {
+	ProductID     int    `json:"product_id"`
+	VariantID     int    `json:"variant_id"`
+	SKU           string `json:"sku"`
+	WarehouseID   int    `json:"warehouse_id"`
+	WarehouseCode string `json:"warehouse_code"`
+	Quantity      int    `json:"quantity"`
+}
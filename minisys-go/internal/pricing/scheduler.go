@@ -0,0 +1,98 @@
+package pricing
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/cache"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/db"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/models"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/publisher"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/requestid"
+)
+
+// priceBatch caps how many prices one transaction activates or publishes
+const priceBatch = 100
+
+// Scheduler applies scheduled prices once they come due and publishes
+// product.price_changed for every price that takes effect, scheduled or
+// set directly. Changes are recorded in the database first, so a broker
+// outage delays events instead of losing them.
+type Scheduler struct {
+	repo      *db.PriceRepository
+	publisher *publisher.ProductPublisher
+	cache     *cache.RedisCache
+	interval  time.Duration
+}
+
+func NewScheduler(repo *db.PriceRepository, pub *publisher.ProductPublisher, c *cache.RedisCache, interval time.Duration) *Scheduler {
+	return &Scheduler{repo: repo, publisher: pub, cache: c, interval: interval}
+}
+
+// Start runs the scheduler on every interval until ctx is done. A zero
+// interval disables it; scheduled prices then never take effect.
+func (s *Scheduler) Start(ctx context.Context) {
+	if s.interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.Run(requestid.NewContext(ctx, requestid.New()))
+			}
+		}
+	}()
+}
+
+// Run activates every due price, then publishes every pending change
+func (s *Scheduler) Run(ctx context.Context) {
+	s.activate(ctx)
+	s.publish(ctx)
+}
+
+func (s *Scheduler) activate(ctx context.Context) {
+	for {
+		changed, err := s.repo.Activate(ctx, priceBatch)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to activate scheduled prices", "error", err)
+			return
+		}
+		if len(changed) > 0 {
+			db.InvalidateProducts(ctx, s.cache, changed)
+			slog.InfoContext(ctx, "scheduled prices activated", "products", len(changed))
+		}
+		// Anything still due, e.g. prices locked by a writer, waits for the
+		// next run
+		if len(changed) < priceBatch {
+			return
+		}
+	}
+}
+
+func (s *Scheduler) publish(ctx context.Context) {
+	for {
+		n, err := s.repo.PublishPending(ctx, priceBatch, func(event models.ProductPriceChangedEvent) error {
+			if err := s.publisher.PublishPriceChanged(ctx, event); err != nil {
+				slog.WarnContext(ctx, "failed to publish price change, will retry", "product_id", event.ProductID, "error", err)
+				return err
+			}
+			slog.InfoContext(ctx, "price changed", "product_id", event.ProductID, "price", event.Price.Decimal(), "currency", event.Price.Currency)
+			return nil
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to publish price changes", "error", err)
+			return
+		}
+		if n < priceBatch {
+			return
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/messaging"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/models"
+)
+
+const (
+	InventoryAllocatedQueue          = "inventory.allocated"
+	OrderAllocationFailedQueue       = "order.allocation_failed"
+	InventoryReservationExpiredQueue = "inventory.reservation_expired"
+	InventoryLowStockQueue           = "inventory.low_stock"
+	InventoryOutOfStockQueue         = "inventory.out_of_stock"
+)
+
+type InventoryPublisher struct {
+	mq *messaging.RabbitMQ
+}
+
+func NewInventoryPublisher(mq *messaging.RabbitMQ) (*InventoryPublisher, error) {
+	// Declare the queues
+	for _, queue := range []string{InventoryAllocatedQueue, OrderAllocationFailedQueue, InventoryReservationExpiredQueue, InventoryLowStockQueue, InventoryOutOfStockQueue} {
+		if err := mq.DeclareQueue(queue); err != nil {
+			return nil, err
+		}
+	}
+
+	return &InventoryPublisher{mq: mq}, nil
+}
+
+// PublishAllocated publishes an inventory.allocated event
+func (p *InventoryPublisher) PublishAllocated(ctx context.Context, event models.InventoryAllocatedEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	return p.mq.Publish(ctx, InventoryAllocatedQueue, data, headers(ctx))
+}
+
+// PublishAllocationFailed publishes an order.allocation_failed event
+func (p *InventoryPublisher) PublishAllocationFailed(ctx context.Context, event models.OrderAllocationFailedEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	return p.mq.Publish(ctx, OrderAllocationFailedQueue, data, headers(ctx))
+}
+
+// PublishReservationExpired publishes an inventory.reservation_expired event
+func (p *InventoryPublisher) PublishReservationExpired(ctx context.Context, event models.ReservationExpiredEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	return p.mq.Publish(ctx, InventoryReservationExpiredQueue, data, headers(ctx))
+}
+
+// PublishStockAlert publishes an inventory.low_stock or
+// inventory.out_of_stock event, depending on the alert kind
+func (p *InventoryPublisher) PublishStockAlert(ctx context.Context, event models.StockAlertEvent) error {
+	queue := InventoryLowStockQueue
+	if event.Kind == models.AlertOutOfStock {
+		queue = InventoryOutOfStockQueue
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	return p.mq.Publish(ctx, queue, data, headers(ctx))
+}
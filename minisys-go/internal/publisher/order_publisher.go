@@ -1,39 +1,54 @@
 package publisher
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/messaging"
 	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/models"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/requestid"
+	amqp "github.com/rabbitmq/amqp091-go"
 )
 
-const OrderCreatedQueue = "order.created"
+const (
+	OrderCreatedQueue   = "order.created"
+	OrderCancelledQueue = "order.cancelled"
+)
 
 type OrderPublisher struct {
 	mq *messaging.RabbitMQ
 }
 
 func NewOrderPublisher(mq *messaging.RabbitMQ) (*OrderPublisher, error) {
-	// Declare the queue
-	if err := mq.DeclareQueue(OrderCreatedQueue); err != nil {
-		return nil, err
+	// Declare the queues
+	for _, queue := range []string{OrderCreatedQueue, OrderCancelledQueue} {
+		if err := mq.DeclareQueue(queue); err != nil {
+			return nil, err
+		}
 	}
 
 	return &OrderPublisher{mq: mq}, nil
 }
 
 // PublishOrderCreated publishes an order.created event
-func (p *OrderPublisher) PublishOrderCreated(order *models.Order) error {
+func (p *OrderPublisher) PublishOrderCreated(ctx context.Context, order *models.Order) error {
 	event := models.OrderCreatedEvent{
-		OrderID:      order.ID,
-		CustomerName: order.CustomerName,
-		TotalAmount:  order.TotalAmount,
+		OrderID:            order.ID,
+		CustomerID:         order.CustomerID,
+		CustomerName:       order.CustomerName,
+		TotalAmount:        order.TotalAmount,
+		ShipTo:             order.ShipTo,
+		AllocationStrategy: order.AllocationStrategy,
+		ReservationID:      order.ReservationID,
 	}
 
 	for _, item := range order.Items {
 		event.Items = append(event.Items, models.OrderItemEvent{
 			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			SKU:       item.SKU,
 			Quantity:  item.Quantity,
 		})
 	}
@@ -43,5 +58,29 @@ func (p *OrderPublisher) PublishOrderCreated(order *models.Order) error {
 		return fmt.Errorf("failed to marshal event: %w", err)
 	}
 
-	return p.mq.Publish(OrderCreatedQueue, data)
+	return p.mq.Publish(ctx, OrderCreatedQueue, data, headers(ctx))
+}
+
+// PublishOrderCancelled publishes an order.cancelled event
+func (p *OrderPublisher) PublishOrderCancelled(ctx context.Context, orderID int) error {
+	event := models.OrderCancelledEvent{
+		OrderID:     orderID,
+		CancelledAt: time.Now().UTC(),
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	return p.mq.Publish(ctx, OrderCancelledQueue, data, headers(ctx))
+}
+
+// headers carries the request ID across the AMQP hop
+func headers(ctx context.Context) amqp.Table {
+	id := requestid.FromContext(ctx)
+	if id == "" {
+		return nil
+	}
+	return amqp.Table{requestid.Header: id}
 }
@@ -0,0 +1,97 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/messaging"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/models"
+)
+
+const (
+	ProductUpdatedQueue      = "product.updated"
+	ProductDeletedQueue      = "product.deleted"
+	ProductRestoredQueue     = "product.restored"
+	ProductPriceChangedQueue = "product.price_changed"
+)
+
+type ProductPublisher struct {
+	mq *messaging.RabbitMQ
+}
+
+func NewProductPublisher(mq *messaging.RabbitMQ) (*ProductPublisher, error) {
+	// Declare the queues
+	for _, queue := range []string{ProductUpdatedQueue, ProductDeletedQueue, ProductRestoredQueue, ProductPriceChangedQueue} {
+		if err := mq.DeclareQueue(queue); err != nil {
+			return nil, err
+		}
+	}
+
+	return &ProductPublisher{mq: mq}, nil
+}
+
+// PublishProductUpdated publishes a product.updated event listing the changed fields
+func (p *ProductPublisher) PublishProductUpdated(ctx context.Context, product *models.Product, changed []string) error {
+	event := models.ProductUpdatedEvent{
+		ProductID: product.ID,
+		Name:      product.Name,
+		Price:     product.Price,
+		Quantity:  product.Quantity,
+		Version:   product.Version,
+		Changed:   changed,
+		UpdatedAt: product.UpdatedAt,
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	return p.mq.Publish(ctx, ProductUpdatedQueue, data, headers(ctx))
+}
+
+// PublishProductDeleted publishes a product.deleted event
+func (p *ProductPublisher) PublishProductDeleted(ctx context.Context, product *models.Product) error {
+	event := models.ProductDeletedEvent{
+		ProductID: product.ID,
+		Version:   product.Version,
+		DeletedAt: product.UpdatedAt,
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	return p.mq.Publish(ctx, ProductDeletedQueue, data, headers(ctx))
+}
+
+// PublishProductRestored publishes a product.restored event
+func (p *ProductPublisher) PublishProductRestored(ctx context.Context, product *models.Product) error {
+	event := models.ProductRestoredEvent{
+		ProductID:  product.ID,
+		Name:       product.Name,
+		Price:      product.Price,
+		Quantity:   product.Quantity,
+		Version:    product.Version,
+		RestoredAt: product.UpdatedAt,
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	return p.mq.Publish(ctx, ProductRestoredQueue, data, headers(ctx))
+}
+
+// PublishPriceChanged publishes a product.price_changed event
+func (p *ProductPublisher) PublishPriceChanged(ctx context.Context, event models.ProductPriceChangedEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	return p.mq.Publish(ctx, ProductPriceChangedQueue, data, headers(ctx))
+}
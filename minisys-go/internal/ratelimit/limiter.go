@@ -0,0 +1,173 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Both scripts read the clock from Redis itself so every gateway replica
+// shares one notion of "now" regardless of local clock skew.
+
+// tokenBucketScript refills the bucket lazily on each call.
+// ARGV: capacity, refill rate (tokens per ms), tokens requested
+// Returns: allowed, remaining, retry after ms, reset after ms
+var tokenBucketScript = redis.NewScript(`
+local key = KEYS[1]
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local requested = tonumber(ARGV[3])
+
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local state = redis.call('HMGET', key, 'tokens', 'ts')
+local tokens = tonumber(state[1]) or capacity
+local ts = tonumber(state[2]) or now
+
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+
+local allowed = 0
+local retry = 0
+if tokens >= requested then
+	tokens = tokens - requested
+	allowed = 1
+else
+	retry = math.ceil((requested - tokens) / rate)
+end
+
+redis.call('HSET', key, 'tokens', tokens, 'ts', now)
+redis.call('PEXPIRE', key, math.ceil(capacity / rate))
+
+local reset = math.ceil((capacity - tokens) / rate)
+return {allowed, math.floor(tokens), retry, reset}
+`)
+
+// slidingWindowScript keeps a log of request timestamps in a sorted set.
+// ARGV: limit, window (ms), unique member for this request
+// Returns: allowed, remaining, retry after ms, reset after ms
+var slidingWindowScript = redis.NewScript(`
+local key = KEYS[1]
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local member = ARGV[3]
+
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+local count = redis.call('ZCARD', key)
+
+local allowed = 0
+if count < limit then
+	redis.call('ZADD', key, now, member)
+	count = count + 1
+	allowed = 1
+end
+redis.call('PEXPIRE', key, window)
+
+local reset = window
+local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+if oldest[2] then
+	reset = tonumber(oldest[2]) + window - now
+end
+
+local retry = 0
+if allowed == 0 then
+	retry = reset
+end
+
+return {allowed, limit - count, retry, reset}
+`)
+
+// Result is the outcome of a single rate limit check
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+	ResetAfter time.Duration
+}
+
+// RedisLimiter evaluates rules atomically in Redis so limits are shared
+// across every gateway replica
+type RedisLimiter struct {
+	client *redis.Client
+	seq    atomic.Uint64
+}
+
+func NewRedisLimiter(host string, port int) (*RedisLimiter, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr: fmt.Sprintf("%s:%d", host, port),
+	})
+
+	// Test connection
+	ctx := context.Background()
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+
+	slog.Info("rate limiter connected to Redis")
+
+	return &RedisLimiter{client: client}, nil
+}
+
+// Allow consumes one unit from the rule's budget for key
+func (l *RedisLimiter) Allow(ctx context.Context, rule *Rule, key string) (*Result, error) {
+	window := time.Duration(rule.Window)
+	redisKey := fmt.Sprintf("ratelimit:%s:%s", rule.Name, key)
+
+	var (
+		values []int64
+		err    error
+	)
+
+	switch rule.Algorithm {
+	case AlgorithmTokenBucket:
+		capacity := rule.Burst
+		if capacity <= 0 {
+			capacity = rule.Limit
+		}
+		rate := float64(rule.Limit) / float64(window.Milliseconds())
+		values, err = tokenBucketScript.Run(ctx, l.client, []string{redisKey},
+			capacity, strconv.FormatFloat(rate, 'f', -1, 64), 1).Int64Slice()
+
+	case AlgorithmSlidingWindow:
+		member := fmt.Sprintf("%d-%d", time.Now().UnixNano(), l.seq.Add(1))
+		values, err = slidingWindowScript.Run(ctx, l.client, []string{redisKey},
+			rule.Limit, window.Milliseconds(), member).Int64Slice()
+
+	default:
+		return nil, fmt.Errorf("unknown algorithm %q", rule.Algorithm)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate rate limit: %w", err)
+	}
+	if len(values) != 4 {
+		return nil, fmt.Errorf("unexpected rate limit script result: %v", values)
+	}
+
+	limit := rule.Limit
+	if rule.Algorithm == AlgorithmTokenBucket && rule.Burst > 0 {
+		limit = rule.Burst
+	}
+
+	return &Result{
+		Allowed:    values[0] == 1,
+		Limit:      limit,
+		Remaining:  int(max(values[1], 0)),
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+		ResetAfter: time.Duration(values[3]) * time.Millisecond,
+	}, nil
+}
+
+// Close closes the Redis connection
+func (l *RedisLimiter) Close() error {
+	return l.client.Close()
+}
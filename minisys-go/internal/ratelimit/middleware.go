@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/auth"
+)
+
+// Middleware enforces the policy on every request passing through it.
+// It must run after auth.Authenticator.Middleware so identity-keyed rules
+// count against the verified caller. If Redis is unreachable requests are
+// let through rather than rejected.
+func Middleware(limiter *RedisLimiter, policy *Policy) gin.HandlerFunc {
+	return enforce(limiter, policy.Match)
+}
+
+// PreAuthMiddleware enforces the policy's pre-auth rules. It must run
+// before auth.Authenticator.Middleware so rejected credentials still
+// count against the client address.
+func PreAuthMiddleware(limiter *RedisLimiter, policy *Policy) gin.HandlerFunc {
+	return enforce(limiter, policy.MatchPreAuth)
+}
+
+func enforce(limiter *RedisLimiter, match func(method, path string) *Rule) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rule := match(c.Request.Method, c.Request.URL.Path)
+		if rule == nil {
+			c.Next()
+			return
+		}
+
+		result, err := limiter.Allow(c.Request.Context(), rule, clientKey(c, rule))
+		if err != nil {
+			slog.WarnContext(c.Request.Context(), "rate limiter unavailable, allowing request", "error", err)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(seconds(result.ResetAfter)))
+		c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", rule.Limit, seconds(time.Duration(rule.Window))))
+
+		if !result.Allowed {
+			retryAfter := max(seconds(result.RetryAfter), 1)
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			slog.InfoContext(c.Request.Context(), "rate limit exceeded", "rule", rule.Name, "method", c.Request.Method, "path", c.Request.URL.Path)
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error":       "rate limit exceeded",
+				"retry_after": retryAfter,
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// clientKey picks the identity a rule counts against
+func clientKey(c *gin.Context, rule *Rule) string {
+	switch rule.KeyBy {
+	case KeyByIdentity, KeyByAPIKey:
+		if identity := auth.FromContext(c); identity != nil {
+			// Hash so subjects never end up in Redis key names
+			sum := sha256.Sum256([]byte(identity.Subject))
+			return "id:" + hex.EncodeToString(sum[:8])
+		}
+		// Anonymous callers fall back to a per-IP budget
+		return "ip:" + c.ClientIP()
+	case KeyByRoute:
+		// FullPath is the registered pattern, so path parameters share a bucket
+		return "route:" + c.FullPath()
+	default:
+		return "ip:" + c.ClientIP()
+	}
+}
+
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
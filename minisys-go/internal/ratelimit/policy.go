@@ -0,0 +1,226 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	AlgorithmTokenBucket   = "token_bucket"
+	AlgorithmSlidingWindow = "sliding_window"
+
+	KeyByIdentity = "identity"
+	KeyByIP       = "ip"
+	KeyByRoute    = "route"
+
+	// KeyByAPIKey is the old name for KeyByIdentity, kept so existing
+	// policy files still load
+	KeyByAPIKey = "api_key"
+)
+
+// Duration lets rule windows be written as "1m" or "30s" in JSON
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("window must be a duration string: %w", err)
+	}
+
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid window %q: %w", s, err)
+	}
+
+	*d = Duration(parsed)
+	return nil
+}
+
+// Rule is a single limit applied to the requests it matches
+type Rule struct {
+	Name      string   `json:"name"`
+	Methods   []string `json:"methods"`
+	Path      string   `json:"path"`
+	Algorithm string   `json:"algorithm"`
+	Limit     int      `json:"limit"`
+	Window    Duration `json:"window"`
+	Burst     int      `json:"burst"`
+	KeyBy     string   `json:"key_by"`
+}
+
+// Matches reports whether the rule applies to a request.
+// Paths match on whole segments, so "/products" covers "/products/1".
+func (r Rule) Matches(method, path string) bool {
+	if len(r.Methods) > 0 {
+		found := false
+		for _, m := range r.Methods {
+			if strings.EqualFold(m, method) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if r.Path == "" {
+		return true
+	}
+	return path == r.Path || strings.HasPrefix(path, strings.TrimSuffix(r.Path, "/")+"/")
+}
+
+func (r Rule) validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("rule name is required")
+	}
+	if r.Limit <= 0 {
+		return fmt.Errorf("rule %s: limit must be positive", r.Name)
+	}
+	if r.Window <= 0 {
+		return fmt.Errorf("rule %s: window must be positive", r.Name)
+	}
+	switch r.Algorithm {
+	case AlgorithmTokenBucket, AlgorithmSlidingWindow:
+	default:
+		return fmt.Errorf("rule %s: unknown algorithm %q", r.Name, r.Algorithm)
+	}
+	switch r.KeyBy {
+	case KeyByIdentity, KeyByAPIKey, KeyByIP, KeyByRoute:
+	default:
+		return fmt.Errorf("rule %s: unknown key_by %q", r.Name, r.KeyBy)
+	}
+	return nil
+}
+
+// Policy is an ordered list of rules; the first match wins and
+// Default (if set) covers everything else. PreAuth rules are checked the
+// same way before authentication, so requests with bad credentials are
+// limited too; they cannot key on identity.
+type Policy struct {
+	Rules   []Rule `json:"rules"`
+	Default *Rule  `json:"default"`
+	PreAuth []Rule `json:"pre_auth"`
+}
+
+// Match returns the rule that governs a request, or nil if it is unlimited
+func (p *Policy) Match(method, path string) *Rule {
+	for i := range p.Rules {
+		if p.Rules[i].Matches(method, path) {
+			return &p.Rules[i]
+		}
+	}
+	return p.Default
+}
+
+// MatchPreAuth returns the pre-authentication rule for a request, or nil
+func (p *Policy) MatchPreAuth(method, path string) *Rule {
+	for i := range p.PreAuth {
+		if p.PreAuth[i].Matches(method, path) {
+			return &p.PreAuth[i]
+		}
+	}
+	return nil
+}
+
+// DefaultPolicy is used when no policy file is configured
+func DefaultPolicy() *Policy {
+	return &Policy{
+		Rules: []Rule{
+			{
+				Name:      "products-write",
+				Methods:   []string{"POST", "PUT", "PATCH", "DELETE"},
+				Path:      "/products",
+				Algorithm: AlgorithmTokenBucket,
+				Limit:     30,
+				Window:    Duration(time.Minute),
+				Burst:     10,
+				KeyBy:     KeyByIdentity,
+			},
+			{
+				Name:      "reservations-write",
+				Methods:   []string{"POST", "PUT", "PATCH", "DELETE"},
+				Path:      "/reservations",
+				Algorithm: AlgorithmTokenBucket,
+				Limit:     30,
+				Window:    Duration(time.Minute),
+				Burst:     10,
+				KeyBy:     KeyByIdentity,
+			},
+			{
+				Name:      "orders",
+				Path:      "/orders",
+				Algorithm: AlgorithmSlidingWindow,
+				Limit:     60,
+				Window:    Duration(time.Minute),
+				KeyBy:     KeyByIP,
+			},
+		},
+		Default: &Rule{
+			Name:      "default",
+			Algorithm: AlgorithmTokenBucket,
+			Limit:     300,
+			Window:    Duration(time.Minute),
+			Burst:     50,
+			KeyBy:     KeyByIP,
+		},
+		PreAuth: []Rule{
+			{
+				// Caps each client address, so guessing credentials is
+				// as limited as anything else
+				Name:      "pre-auth",
+				Algorithm: AlgorithmTokenBucket,
+				Limit:     600,
+				Window:    Duration(time.Minute),
+				Burst:     100,
+				KeyBy:     KeyByIP,
+			},
+		},
+	}
+}
+
+// LoadPolicy reads a JSON policy file, or returns DefaultPolicy if path is
+// empty. A file without pre_auth keeps the default pre-auth rules; an
+// empty list disables them.
+func LoadPolicy(path string) (*Policy, error) {
+	if path == "" {
+		return DefaultPolicy(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rate limit policy: %w", err)
+	}
+
+	var policy Policy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("failed to parse rate limit policy: %w", err)
+	}
+
+	for _, rule := range policy.Rules {
+		if err := rule.validate(); err != nil {
+			return nil, err
+		}
+	}
+	if policy.Default != nil {
+		if err := policy.Default.validate(); err != nil {
+			return nil, err
+		}
+	}
+	if policy.PreAuth == nil {
+		policy.PreAuth = DefaultPolicy().PreAuth
+	}
+	for _, rule := range policy.PreAuth {
+		if err := rule.validate(); err != nil {
+			return nil, err
+		}
+		if rule.KeyBy != KeyByIP && rule.KeyBy != KeyByRoute {
+			return nil, fmt.Errorf("rule %s: pre_auth rules must key by ip or route", rule.Name)
+		}
+	}
+
+	return &policy, nil
+}
@@ -0,0 +1,276 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prudhivi99/Distributed-Systems/minisys-go/internal/auth"
+)
+
+func TestRuleMatches(t *testing.T) {
+	tests := []struct {
+		name   string
+		rule   Rule
+		method string
+		path   string
+		want   bool
+	}{
+		{"exact path", Rule{Path: "/products"}, "GET", "/products", true},
+		{"sub path", Rule{Path: "/products"}, "GET", "/products/1/variants", true},
+		{"trailing slash in rule", Rule{Path: "/products/"}, "GET", "/products/1", true},
+		{"shared prefix only", Rule{Path: "/products"}, "GET", "/productsx", false},
+		{"other path", Rule{Path: "/products"}, "GET", "/orders", false},
+		{"no path matches everything", Rule{}, "DELETE", "/anything", true},
+		{"method listed", Rule{Methods: []string{"POST", "PUT"}, Path: "/products"}, "PUT", "/products/1", true},
+		{"method case-insensitive", Rule{Methods: []string{"POST"}, Path: "/products"}, "post", "/products", true},
+		{"method not listed", Rule{Methods: []string{"POST"}, Path: "/products"}, "GET", "/products", false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.rule.Matches(tt.method, tt.path); got != tt.want {
+			t.Errorf("%s: Matches(%s, %s) = %v, want %v", tt.name, tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestDefaultPolicyMatch(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{"POST", "/products", "products-write"},
+		{"PATCH", "/products/1", "products-write"},
+		{"GET", "/products/1", "default"},
+		{"POST", "/reservations", "reservations-write"},
+		{"POST", "/reservations/4/release", "reservations-write"},
+		{"GET", "/reservations/4", "default"},
+		{"GET", "/orders", "orders"},
+		{"POST", "/orders", "orders"},
+		{"GET", "/categories", "default"},
+	}
+
+	policy := DefaultPolicy()
+	for _, tt := range tests {
+		rule := policy.Match(tt.method, tt.path)
+		if rule == nil || rule.Name != tt.want {
+			t.Errorf("Match(%s, %s) = %+v, want rule %q", tt.method, tt.path, rule, tt.want)
+		}
+	}
+
+	for _, rule := range append(append(policy.Rules, *policy.Default), policy.PreAuth...) {
+		if err := rule.validate(); err != nil {
+			t.Errorf("default rule %s is invalid: %v", rule.Name, err)
+		}
+	}
+
+	// Every request, authenticated or not, counts against its address first
+	for _, tt := range tests {
+		rule := policy.MatchPreAuth(tt.method, tt.path)
+		if rule == nil || rule.KeyBy != KeyByIP {
+			t.Errorf("MatchPreAuth(%s, %s) = %+v, want an ip-keyed rule", tt.method, tt.path, rule)
+		}
+	}
+}
+
+func TestLoadPolicyPreAuth(t *testing.T) {
+	rule := `{"name": "r", "algorithm": "sliding_window", "limit": 5, "window": "1m", "key_by": "%s"}`
+
+	tests := []struct {
+		name     string
+		body     string
+		wantErr  string
+		wantRule string
+	}{
+		{name: "omitted keeps the default", body: `{}`, wantRule: "pre-auth"},
+		{name: "empty disables", body: `{"pre_auth": []}`},
+		{name: "custom", body: `{"pre_auth": [` + fmt.Sprintf(rule, KeyByIP) + `]}`, wantRule: "r"},
+		{name: "identity is not known yet", body: `{"pre_auth": [` + fmt.Sprintf(rule, KeyByIdentity) + `]}`, wantErr: "must key by ip or route"},
+		{name: "invalid rule", body: `{"pre_auth": [{"name": "r"}]}`, wantErr: "limit"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "policy.json")
+			if err := os.WriteFile(path, []byte(tt.body), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			policy, err := LoadPolicy(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadPolicy error = %v, want one mentioning %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := ""
+			if rule := policy.MatchPreAuth("POST", "/orders"); rule != nil {
+				got = rule.Name
+			}
+			if got != tt.wantRule {
+				t.Errorf("pre-auth rule = %q, want %q", got, tt.wantRule)
+			}
+		})
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	valid := Rule{Name: "r", Algorithm: AlgorithmSlidingWindow, Limit: 1, Window: Duration(time.Second), KeyBy: KeyByIP}
+
+	tests := []struct {
+		name    string
+		rule    func(r *Rule)
+		wantErr string
+	}{
+		{name: "valid", rule: func(r *Rule) {}},
+		{name: "legacy api_key", rule: func(r *Rule) { r.KeyBy = KeyByAPIKey }},
+		{name: "identity", rule: func(r *Rule) { r.KeyBy = KeyByIdentity }},
+		{name: "route", rule: func(r *Rule) { r.KeyBy = KeyByRoute }},
+		{name: "missing name", rule: func(r *Rule) { r.Name = "" }, wantErr: "name is required"},
+		{name: "zero limit", rule: func(r *Rule) { r.Limit = 0 }, wantErr: "limit"},
+		{name: "zero window", rule: func(r *Rule) { r.Window = 0 }, wantErr: "window"},
+		{name: "unknown algorithm", rule: func(r *Rule) { r.Algorithm = "leaky_bucket" }, wantErr: "unknown algorithm"},
+		{name: "unknown key", rule: func(r *Rule) { r.KeyBy = "header" }, wantErr: "unknown key_by"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := valid
+			tt.rule(&rule)
+
+			data, err := json.Marshal(map[string]any{"rules": []any{map[string]any{
+				"name": rule.Name, "algorithm": rule.Algorithm, "limit": rule.Limit,
+				"window": time.Duration(rule.Window).String(), "key_by": rule.KeyBy,
+			}}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(t.TempDir(), "policy.json")
+			if err := os.WriteFile(path, data, 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = LoadPolicy(path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("LoadPolicy error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("LoadPolicy error = %v, want one mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClientKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		keyBy   string
+		target  string
+		headers map[string]string
+		want    string
+	}{
+		{
+			name:    "identity hashes the verified subject",
+			keyBy:   KeyByIdentity,
+			target:  "/products/1",
+			headers: map[string]string{auth.HeaderUserID: "alice"},
+			want:    "id:2bd806c97f0e00af",
+		},
+		{
+			name:    "legacy api_key keys on identity too",
+			keyBy:   KeyByAPIKey,
+			target:  "/products/1",
+			headers: map[string]string{auth.HeaderUserID: "alice"},
+			want:    "id:2bd806c97f0e00af",
+		},
+		{
+			name:    "an unverified API key header is ignored",
+			keyBy:   KeyByIdentity,
+			target:  "/products/1",
+			headers: map[string]string{"X-API-Key": "made-up"},
+			want:    "ip:192.0.2.1",
+		},
+		{
+			name:    "forwarded-for from an untrusted peer is ignored",
+			keyBy:   KeyByIP,
+			target:  "/products/1",
+			headers: map[string]string{"X-Forwarded-For": "203.0.113.9"},
+			want:    "ip:192.0.2.1",
+		},
+		{
+			name:   "route keys on the registered pattern",
+			keyBy:  KeyByRoute,
+			target: "/products/1",
+			want:   "route:/products/:id",
+		},
+		{
+			name:   "different routes get different buckets",
+			keyBy:  KeyByRoute,
+			target: "/orders",
+			want:   "route:/orders",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := &Rule{KeyBy: tt.keyBy}
+			var got string
+			capture := func(c *gin.Context) { got = clientKey(c, rule) }
+
+			router := gin.New()
+			if err := router.SetTrustedProxies(nil); err != nil {
+				t.Fatal(err)
+			}
+			router.Use(auth.ReadIdentity())
+			router.GET("/products/:id", capture)
+			router.GET("/orders", capture)
+
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			req.RemoteAddr = "192.0.2.1:4711"
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got != tt.want {
+				t.Errorf("clientKey = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSeconds(t *testing.T) {
+	tests := []struct {
+		in   time.Duration
+		want int
+	}{
+		{0, 0},
+		{time.Millisecond, 1},
+		{time.Second, 1},
+		{1500 * time.Millisecond, 2},
+		{time.Minute, 60},
+	}
+
+	for _, tt := range tests {
+		if got := seconds(tt.in); got != tt.want {
+			t.Errorf("seconds(%v) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package tracing
+
+import (
+	"context"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/prudhivi99/Distributed-Systems/minisys-go/internal/tracing"
+
+// amqpCarrier adapts AMQP message headers to the propagation API
+type amqpCarrier amqp.Table
+
+func (c amqpCarrier) Get(key string) string {
+	value, _ := c[key].(string)
+	return value
+}
+
+func (c amqpCarrier) Set(key, value string) {
+	c[key] = value
+}
+
+func (c amqpCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// StartPublish starts a producer span and injects its context into headers
+func StartPublish(ctx context.Context, queue string, headers amqp.Table) (context.Context, trace.Span) {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, queue+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "rabbitmq"),
+			attribute.String("messaging.destination.name", queue),
+			attribute.String("messaging.operation.type", "send"),
+		),
+	)
+
+	otel.GetTextMapPropagator().Inject(ctx, amqpCarrier(headers))
+	return ctx, span
+}
+
+// StartConsume extracts the producer's context from a delivery and starts
+// a consumer span as its child
+func StartConsume(ctx context.Context, queue string, msg amqp.Delivery) (context.Context, trace.Span) {
+	if msg.Headers != nil {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, amqpCarrier(msg.Headers))
+	}
+
+	return otel.Tracer(tracerName).Start(ctx, queue+" process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "rabbitmq"),
+			attribute.String("messaging.destination.name", queue),
+			attribute.String("messaging.operation.type", "process"),
+		),
+	)
+}
+
+// RecordError marks the span as failed
+func RecordError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
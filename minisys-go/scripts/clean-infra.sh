@@ -16,8 +16,8 @@ fi
 echo ""
 echo "��️  Removing MinISys Infrastructure..."
 
-docker stop minisys-consul minisys-rabbitmq minisys-redis minisys-postgres 2>/dev/null
-docker rm minisys-consul minisys-rabbitmq minisys-redis minisys-postgres 2>/dev/null
+docker stop minisys-jaeger minisys-consul minisys-rabbitmq minisys-redis minisys-postgres 2>/dev/null
+docker rm minisys-jaeger minisys-consul minisys-rabbitmq minisys-redis minisys-postgres 2>/dev/null
 
 echo ""
 echo "✅ All infrastructure removed"
@@ -83,6 +83,15 @@ start_container "minisys-consul" "consul:1.15" \
      -p 8600:8600/udp \
      agent -server -ui -node=server-1 -bootstrap-expect=1 -client=0.0.0.0"
 
+echo ""
+echo "============================================"
+echo "5. Jaeger (Distributed Tracing)"
+echo "============================================"
+start_container "minisys-jaeger" "jaegertracing/all-in-one:1.57" \
+    "-p 16686:16686 \
+     -p 4318:4318 \
+     -e COLLECTOR_OTLP_ENABLED=true"
+
 echo ""
 echo "============================================"
 echo "⏳ Waiting for services to be ready..."
@@ -91,63 +100,14 @@ sleep 10
 
 echo ""
 echo "============================================"
-echo "5. Database Migration"
-echo "============================================"
-
-# Check if tables exist
-TABLES_EXIST=$(docker exec minisys-postgres psql -U minisys -d minisys -t -c "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = 'public';" 2>/dev/null | tr -d ' ')
-
-if [ "$TABLES_EXIST" -gt "0" ] 2>/dev/null; then
-    echo -e "${GREEN}✅ Database tables already exist${NC}"
-else
-    echo -e "${YELLOW}📦 Running database migrations...${NC}"
-    
-    # Create tables
-    docker exec -i minisys-postgres psql -U minisys -d minisys << 'EOSQL'
--- Products table
-CREATE TABLE IF NOT EXISTS products (
-    id SERIAL PRIMARY KEY,
-    name VARCHAR(255) NOT NULL,
-    price DECIMAL(10,2) NOT NULL,
-    quantity INT NOT NULL DEFAULT 0,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-);
-
--- Orders table
-CREATE TABLE IF NOT EXISTS orders (
-    id SERIAL PRIMARY KEY,
-    customer_name VARCHAR(255) NOT NULL,
-    total_amount DECIMAL(10,2) NOT NULL,
-    status VARCHAR(50) NOT NULL DEFAULT 'pending',
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-);
-
--- Order items table
-CREATE TABLE IF NOT EXISTS order_items (
-    id SERIAL PRIMARY KEY,
-    order_id INT REFERENCES orders(id),
-    product_id INT NOT NULL,
-    product_name VARCHAR(255) NOT NULL,
-    quantity INT NOT NULL,
-    price DECIMAL(10,2) NOT NULL
-);
-
--- Insert sample products if empty
-INSERT INTO products (name, price, quantity)
-SELECT 'Laptop', 999.99, 10
-WHERE NOT EXISTS (SELECT 1 FROM products WHERE name = 'Laptop');
-
-INSERT INTO products (name, price, quantity)
-SELECT 'Mouse', 29.99, 50
-WHERE NOT EXISTS (SELECT 1 FROM products WHERE name = 'Mouse');
-
-INSERT INTO products (name, price, quantity)
-SELECT 'Keyboard', 79.99, 30
-WHERE NOT EXISTS (SELECT 1 FROM products WHERE name = 'Keyboard');
-EOSQL
-
-    echo -e "${GREEN}✅ Database migrations completed${NC}"
-fi
+echo "6. Database Migration"
+echo "============================================"
+
+# Schema lives in internal/db/migrations; apply whatever is pending
+SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
+echo -e "${YELLOW}📦 Running database migrations...${NC}"
+(cd "$SCRIPT_DIR/.." && go run ./cmd/migrate up)
+echo -e "${GREEN}✅ Database migrations completed${NC}"
 
 echo ""
 echo "============================================"
@@ -166,8 +126,12 @@ echo "  Redis:       localhost:6379"
 echo "  RabbitMQ:    localhost:5672 (guest/guest)"
 echo "  RabbitMQ UI: http://localhost:15672"
 echo "  Consul UI:   http://localhost:8500"
+echo "  Jaeger UI:   http://localhost:16686 (OTLP on localhost:4318)"
+echo ""
+echo "Migrations: go run ./cmd/migrate status | up | down [steps] | to <version>"
+echo "Catalog:    go run ./cmd/catalog export [-format csv|ndjson] [-o file]"
 echo ""
-echo "Next steps:"
+echo "Next steps (set TRACE_EXPORTER=otlp TRACE_OTLP_ENDPOINT=localhost:4318 to send traces to Jaeger):"
 echo "  1. Terminal 1: go run cmd/product-service/main.go"
 echo "  2. Terminal 2: go run cmd/order-service/main.go"
 echo "  3. Terminal 3: go run cmd/api-gateway/main.go"
@@ -7,7 +7,7 @@
 echo "🛑 Stopping MinISys Infrastructure..."
 echo ""
 
-docker stop minisys-consul minisys-rabbitmq minisys-redis minisys-postgres 2>/dev/null
+docker stop minisys-jaeger minisys-consul minisys-rabbitmq minisys-redis minisys-postgres 2>/dev/null
 
 echo ""
 echo "✅ All infrastructure stopped"